@@ -0,0 +1,184 @@
+package mtls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type devFiles struct {
+	cert, key, ca string
+}
+
+// issueDevCertificate makes sure dir holds a local CA and issues a fresh
+// certificate for name, usable both as server and as client. The first
+// service to start creates the CA; the others reuse it.
+func issueDevCertificate(dir, name string) (devFiles, error) {
+	if name == "" {
+		return devFiles{}, errors.New("dev certificate needs a service name")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return devFiles{}, err
+	}
+	caCert, caKey, err := loadOrCreateCA(dir)
+	if err != nil {
+		return devFiles{}, err
+	}
+	files := devFiles{
+		cert: filepath.Join(dir, name+".pem"),
+		key:  filepath.Join(dir, name+"-key.pem"),
+		ca:   filepath.Join(dir, "ca.pem"),
+	}
+	if err := writeFile(files.ca, pemBlock("CERTIFICATE", caCert.Raw)); err != nil {
+		return devFiles{}, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return devFiles{}, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serialNumber(),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name, "localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.AddDate(1, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		return devFiles{}, err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return devFiles{}, err
+	}
+	if err := writeFile(files.key, pemBlock("PRIVATE KEY", keyDER)); err != nil {
+		return devFiles{}, err
+	}
+	if err := writeFile(files.cert, pemBlock("CERTIFICATE", der)); err != nil {
+		return devFiles{}, err
+	}
+	return files, nil
+}
+
+// loadOrCreateCA keeps the CA certificate and key in one file, which is
+// linked into place so that concurrently starting services agree on it.
+func loadOrCreateCA(dir string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	path := filepath.Join(dir, "ca-bundle.pem")
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		data, err := newCA()
+		if err != nil {
+			return nil, nil, err
+		}
+		tmp, err := writeTemp(dir, data)
+		if err != nil {
+			return nil, nil, err
+		}
+		err = os.Link(tmp, path)
+		os.Remove(tmp)
+		if err != nil && !errors.Is(err, os.ErrExist) {
+			return nil, nil, err
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var cert *x509.Certificate
+	var key *ecdsa.PrivateKey
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		switch block.Type {
+		case "CERTIFICATE":
+			cert, err = x509.ParseCertificate(block.Bytes)
+		case "EC PRIVATE KEY":
+			key, err = x509.ParseECPrivateKey(block.Bytes)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if cert == nil || key == nil {
+		return nil, nil, fmt.Errorf("%s: incomplete ca bundle", path)
+	}
+	return cert, key, nil
+}
+
+func newCA() ([]byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber(),
+		Subject:               pkix.Name{CommonName: "social-network dev CA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return append(pemBlock("CERTIFICATE", der), pemBlock("EC PRIVATE KEY", keyDER)...), nil
+}
+
+func serialNumber() *big.Int {
+	n, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 127))
+	return n
+}
+
+func pemBlock(typ string, der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der})
+}
+
+func writeTemp(dir string, data []byte) (string, error) {
+	f, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// writeFile replaces path atomically, so a reload never sees half a file.
+func writeFile(path string, data []byte) error {
+	tmp, err := writeTemp(filepath.Dir(path), data)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"common/identity"
+)
+
+func internalToken(t *testing.T, secret, audience, subject string) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Issuer:    "main-service",
+		Audience:  jwt.ClaimStrings{audience},
+		Subject:   subject,
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return token
+}
+
+func TestIdentityInterceptor(t *testing.T) {
+	interceptor := identity.NewVerifier("secret", "posts-service").UnaryServerInterceptor()
+	var caller identity.Caller
+	handler := func(ctx context.Context, _ any) (any, error) {
+		caller, _ = identity.FromContext(ctx)
+		return "ok", nil
+	}
+	call := func(tokens ...string) error {
+		ctx := context.Background()
+		for _, token := range tokens {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(identity.MetadataKey, token))
+		}
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+		return err
+	}
+
+	if err := call(); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated without a token, got %v", err)
+	}
+	for name, token := range map[string]string{
+		"wrong audience": internalToken(t, "secret", "stats-service", "42"),
+		"wrong secret":   internalToken(t, "other", "posts-service", "42"),
+		"garbage":        "not-a-token",
+	} {
+		if err := call(token); status.Code(err) != codes.Unauthenticated {
+			t.Fatalf("%s: expected Unauthenticated, got %v", name, err)
+		}
+	}
+
+	if err := call(internalToken(t, "secret", "posts-service", "42")); err != nil {
+		t.Fatalf("expected valid token to pass: %v", err)
+	}
+	if caller.Service != "main-service" || caller.UserID != "42" {
+		t.Fatalf("unexpected caller: %+v", caller)
+	}
+}
@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"common/mtls"
+)
+
+func loadDevCredentials(t *testing.T, dir, name string) *mtls.Credentials {
+	t.Helper()
+	creds, err := mtls.Load(mtls.Config{DevDir: dir, Name: name})
+	if err != nil {
+		t.Fatalf("load %s credentials: %v", name, err)
+	}
+	return creds
+}
+
+func serveHealth(t *testing.T, creds *mtls.Credentials) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := grpc.NewServer(creds.ServerOption())
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	_, port, _ := net.SplitHostPort(lis.Addr().String())
+	return net.JoinHostPort("localhost", port)
+}
+
+func callHealth(t *testing.T, addr string, transport grpc.DialOption) error {
+	t.Helper()
+	conn, err := grpc.NewClient(addr, transport)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	return err
+}
+
+func TestMTLSRejectsUntrustedClients(t *testing.T) {
+	dir := t.TempDir()
+	addr := serveHealth(t, loadDevCredentials(t, dir, "stats-service"))
+
+	if err := callHealth(t, addr, loadDevCredentials(t, dir, "main-service").DialOption()); err != nil {
+		t.Fatalf("expected a client of the same CA to be accepted: %v", err)
+	}
+	if err := callHealth(t, addr, grpc.WithTransportCredentials(insecure.NewCredentials())); err == nil {
+		t.Fatal("expected a plaintext client to be rejected")
+	}
+	if err := callHealth(t, addr, loadDevCredentials(t, t.TempDir(), "main-service").DialOption()); err == nil {
+		t.Fatal("expected a client of another CA to be rejected")
+	}
+
+	// A client that trusts the server but presents a foreign certificate.
+	other := t.TempDir()
+	loadDevCredentials(t, other, "main-service")
+	cert, err := tls.LoadX509KeyPair(filepath.Join(other, "main-service.pem"), filepath.Join(other, "main-service-key.pem"))
+	if err != nil {
+		t.Fatalf("load foreign certificate: %v", err)
+	}
+	caPEM, _ := os.ReadFile(filepath.Join(dir, "ca.pem"))
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(caPEM)
+	foreign := credentials.NewTLS(&tls.Config{RootCAs: roots, Certificates: []tls.Certificate{cert}})
+	if err := callHealth(t, addr, grpc.WithTransportCredentials(foreign)); err == nil {
+		t.Fatal("expected a client certificate of another CA to be rejected")
+	}
+	noCert := credentials.NewTLS(&tls.Config{RootCAs: roots})
+	if err := callHealth(t, addr, grpc.WithTransportCredentials(noCert)); err == nil {
+		t.Fatal("expected a client without a certificate to be rejected")
+	}
+}
+
+func TestMTLSReloadsRotatedCertificates(t *testing.T) {
+	oldCA, newCA := t.TempDir(), t.TempDir()
+	loadDevCredentials(t, oldCA, "stats-service")
+	loadDevCredentials(t, newCA, "stats-service")
+
+	dir := t.TempDir()
+	install := func(from string) {
+		for src, dst := range map[string]string{"stats-service.pem": "cert.pem", "stats-service-key.pem": "key.pem", "ca.pem": "ca.pem"} {
+			data, err := os.ReadFile(filepath.Join(from, src))
+			if err != nil {
+				t.Fatalf("read %s: %v", src, err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, dst), data, 0o600); err != nil {
+				t.Fatalf("write %s: %v", dst, err)
+			}
+		}
+	}
+	install(oldCA)
+	creds, err := mtls.Load(mtls.Config{
+		CertFile: filepath.Join(dir, "cert.pem"),
+		KeyFile:  filepath.Join(dir, "key.pem"),
+		CAFile:   filepath.Join(dir, "ca.pem"),
+	})
+	if err != nil {
+		t.Fatalf("load credentials: %v", err)
+	}
+	addr := serveHealth(t, creds)
+
+	oldClient := loadDevCredentials(t, oldCA, "main-service").DialOption()
+	newClient := loadDevCredentials(t, newCA, "main-service").DialOption()
+	if err := callHealth(t, addr, oldClient); err != nil {
+		t.Fatalf("expected the old CA to be trusted before rotation: %v", err)
+	}
+
+	install(newCA)
+	if err := creds.Reload(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if err := callHealth(t, addr, newClient); err != nil {
+		t.Fatalf("expected the new CA to be trusted after rotation: %v", err)
+	}
+	if err := callHealth(t, addr, oldClient); err == nil {
+		t.Fatal("expected the old CA to be rejected after rotation")
+	}
+}
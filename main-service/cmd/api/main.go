@@ -1,9 +1,16 @@
 package main
 
 import (
+	"common/identity"
+	"common/mtls"
+	"context"
 	"database/sql"
 	"fmt"
+	"main-service/internal/auth"
+	"main-service/internal/events"
 	"main-service/internal/handlers"
+	"main-service/internal/lockout"
+	"main-service/internal/mail"
 	"net"
 	"net/http"
 	"os"
@@ -37,12 +44,54 @@ func main() {
 	}
 	fmt.Println("Connected to Postgres")
 
+	if len(os.Args) > 1 && os.Args[1] == "unlock" {
+		if err := runUnlock(context.Background(), db, os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "unlock failed: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "role" {
+		if err := runRole(context.Background(), db, os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "role failed: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	internalSecret := os.Getenv("INTERNAL_TOKEN_SECRET")
+	if internalSecret == "" {
+		panic("INTERNAL_TOKEN_SECRET not set")
+	}
+	signer := identity.NewSigner(internalSecret, "main-service")
+
+	transport := grpc.WithTransportCredentials(insecure.NewCredentials())
+	tlsCfg := mtls.Config{
+		CertFile: os.Getenv("TLS_CERT_FILE"),
+		KeyFile:  os.Getenv("TLS_KEY_FILE"),
+		CAFile:   os.Getenv("TLS_CA_FILE"),
+		DevDir:   os.Getenv("TLS_DEV_DIR"),
+		Name:     "main-service",
+	}
+	if tlsCfg.Enabled() {
+		creds, err := mtls.Load(tlsCfg)
+		if err != nil {
+			panic(fmt.Errorf("load tls certificates failed: %w", err))
+		}
+		go creds.Watch(context.Background(), time.Minute)
+		transport = creds.DialOption()
+	}
+
 	postsAddr := os.Getenv("POSTS_SERVICE_ADDR")
 	if postsAddr == "" {
 		postsAddr = "localhost:50051"
 	}
 
-	grpcConn, err := grpc.NewClient(postsAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	grpcConn, err := grpc.NewClient(postsAddr,
+		transport,
+		grpc.WithChainUnaryInterceptor(signer.UnaryClientInterceptor("posts-service")),
+		grpc.WithChainStreamInterceptor(signer.StreamClientInterceptor("posts-service")),
+	)
 	if err != nil {
 		panic(fmt.Errorf("grpc new client failed: %w", err))
 	}
@@ -55,7 +104,11 @@ func main() {
 		statsAddr = "stats-service:9090"
 	}
 
-	statsConn, err := grpc.NewClient(statsAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	statsConn, err := grpc.NewClient(statsAddr,
+		transport,
+		grpc.WithChainUnaryInterceptor(signer.UnaryClientInterceptor("stats-service")),
+		grpc.WithChainStreamInterceptor(signer.StreamClientInterceptor("stats-service")),
+	)
 	if err != nil {
 		panic(fmt.Errorf("stats grpc client failed: %w", err))
 	}
@@ -83,40 +136,148 @@ func main() {
 		likesTopic = "post_likes"
 	}
 
-	var viewsWriter, likesWriter *kafka.Writer
+	usersTopic := os.Getenv("KAFKA_USERS_TOPIC")
+	if usersTopic == "" {
+		usersTopic = "user_events"
+	}
+
+	partitions := 6
+	if v := os.Getenv("KAFKA_TOPIC_PARTITIONS"); v != "" {
+		if partitions, err = strconv.Atoi(v); err != nil || partitions < 1 {
+			panic(fmt.Errorf("invalid KAFKA_TOPIC_PARTITIONS %q", v))
+		}
+	}
+
+	var writer events.MessageWriter
 	if len(brokers) > 0 {
-		if err := ensureKafkaTopic(brokers, viewsTopic); err != nil {
+		if err := ensureKafkaTopic(brokers, viewsTopic, partitions); err != nil {
 			panic(fmt.Errorf("ensure kafka topic %q failed: %w", viewsTopic, err))
 		}
-		if err := ensureKafkaTopic(brokers, likesTopic); err != nil {
+		if err := ensureKafkaTopic(brokers, likesTopic, partitions); err != nil {
 			panic(fmt.Errorf("ensure kafka topic %q failed: %w", likesTopic, err))
 		}
+		if err := ensureKafkaTopic(brokers, usersTopic, partitions); err != nil {
+			panic(fmt.Errorf("ensure kafka topic %q failed: %w", usersTopic, err))
+		}
 
-		viewsWriter = &kafka.Writer{
+		// Messages are keyed by post ID, so all events of a post land in
+		// the same partition and are consumed in order.
+		kafkaWriter := &kafka.Writer{
 			Addr:                   kafka.TCP(brokers...),
-			Topic:                  viewsTopic,
-			Balancer:               &kafka.LeastBytes{},
+			Balancer:               &kafka.Hash{},
+			RequiredAcks:           kafka.RequireAll,
 			AllowAutoTopicCreation: true,
 		}
-		defer viewsWriter.Close()
+		defer kafkaWriter.Close()
+		writer = kafkaWriter
+	}
 
-		likesWriter = &kafka.Writer{
-			Addr:                   kafka.TCP(brokers...),
-			Topic:                  likesTopic,
-			Balancer:               &kafka.LeastBytes{},
-			AllowAutoTopicCreation: true,
+	spoolDir := os.Getenv("EVENT_SPOOL_DIR")
+	if spoolDir == "" {
+		spoolDir = "/var/lib/main-service/spool"
+	}
+	queueSize, _ := strconv.Atoi(os.Getenv("EVENT_QUEUE_SIZE"))
+	publisher, err := events.NewPublisher(events.Config{Dir: spoolDir, QueueSize: queueSize}, writer)
+	if err != nil {
+		panic(fmt.Errorf("open event spool failed: %w", err))
+	}
+	defer publisher.Close()
+	go publisher.Run(context.Background())
+
+	keys, err := auth.Load(auth.Config{
+		Dir:        os.Getenv("JWT_KEYS_DIR"),
+		SigningKID: os.Getenv("JWT_SIGNING_KID"),
+		Secret:     os.Getenv("JWT_SECRET"),
+	})
+	if err != nil {
+		panic(fmt.Errorf("load jwt keys failed: %w", err))
+	}
+	handlers.UseKeySet(keys)
+	handlers.UseSessionStore(handlers.NewDBSessionStore(db))
+	if os.Getenv("JWT_KEYS_DIR") != "" {
+		go keys.Watch(context.Background(), time.Minute)
+	}
+
+	mailer, err := newMailer()
+	if err != nil {
+		panic(fmt.Errorf("mailer init failed: %w", err))
+	}
+	resetURL := os.Getenv("PASSWORD_RESET_URL")
+	if resetURL == "" {
+		resetURL = "http://localhost:8080/reset-password"
+	}
+	resetTTL := time.Hour
+	if v := os.Getenv("PASSWORD_RESET_TTL"); v != "" {
+		if resetTTL, err = time.ParseDuration(v); err != nil || resetTTL <= 0 {
+			panic(fmt.Errorf("invalid PASSWORD_RESET_TTL %q", v))
+		}
+	}
+
+	exportTTL := 7 * 24 * time.Hour
+	if v := os.Getenv("DATA_EXPORT_TTL"); v != "" {
+		if exportTTL, err = time.ParseDuration(v); err != nil || exportTTL <= 0 {
+			panic(fmt.Errorf("invalid DATA_EXPORT_TTL %q", v))
+		}
+	}
+
+	sseHeartbeat := 15 * time.Second
+	if v := os.Getenv("SSE_HEARTBEAT"); v != "" {
+		if sseHeartbeat, err = time.ParseDuration(v); err != nil || sseHeartbeat <= 0 {
+			panic(fmt.Errorf("invalid SSE_HEARTBEAT %q", v))
 		}
-		defer likesWriter.Close()
 	}
 
+	verifyURL := os.Getenv("EMAIL_VERIFY_URL")
+	if verifyURL == "" {
+		verifyURL = "http://localhost:8080/auth/email/verify"
+	}
+
+	loginAuditRetention := 90 * 24 * time.Hour
+	if v := os.Getenv("LOGIN_AUDIT_RETENTION"); v != "" {
+		if loginAuditRetention, err = time.ParseDuration(v); err != nil || loginAuditRetention <= 0 {
+			panic(fmt.Errorf("invalid LOGIN_AUDIT_RETENTION %q", v))
+		}
+	}
+
+	var guardStore lockout.Store
+	switch backend := os.Getenv("LOGIN_GUARD_BACKEND"); backend {
+	case "", "postgres":
+		guardStore = lockout.NewPostgres(db)
+	case "memory":
+		guardStore = lockout.NewMemory()
+	default:
+		panic(fmt.Errorf("invalid LOGIN_GUARD_BACKEND %q", backend))
+	}
+	guard := lockout.NewGuard(guardStore, lockout.DefaultLoginPolicy, lockout.DefaultIPPolicy)
+	forgotLimiter := lockout.NewLimiter(guardStore, "password_forgot", 3, 20, time.Hour)
+	go guard.RunPrune(context.Background(), time.Hour, loginAuditRetention)
+
 	http.HandleFunc("/health", handlers.Health)
+	http.HandleFunc("/.well-known/jwks.json", handlers.JWKS(keys))
 	http.HandleFunc("/auth/register", handlers.AuthRegister(db))
-	http.HandleFunc("/auth/login", handlers.AuthLogin(db))
-	http.HandleFunc("/users/me", handlers.UserMe(db))
-	http.HandleFunc("/users/me/update", handlers.UserMeUpdate(db))
+	http.HandleFunc("/auth/login", handlers.AuthLogin(db, guard))
+	http.HandleFunc("/auth/login/mfa", handlers.AuthLoginMFA(db, guard))
+	http.HandleFunc("/auth/password/forgot", handlers.AuthPasswordForgot(db, mailer, forgotLimiter, resetURL, resetTTL))
+	http.HandleFunc("/auth/password/reset", handlers.AuthPasswordReset(db))
+	http.HandleFunc("/users/me", handlers.UserMe(db, postsClient, publisher.Topic(usersTopic), guard))
+	http.HandleFunc("/users/me/password", handlers.UserMePassword(db, guard))
+	http.HandleFunc("/auth/email/verify", handlers.AuthEmailVerify(db))
+	http.HandleFunc("/users/me/update", handlers.UserMeUpdate(db, mailer, verifyURL, 24*time.Hour))
+	http.HandleFunc("/users/me/stats", handlers.UserMeStats(statsClient))
+	http.HandleFunc("/users/me/export", handlers.UserMeExport(db, postsClient, statsClient, exportTTL))
+	http.HandleFunc("/users/me/export/", handlers.UserMeExportDownload(db))
+	http.HandleFunc("/users/me/2fa/enroll", handlers.UserMe2FAEnroll(db))
+	http.HandleFunc("/users/me/2fa/confirm", handlers.UserMe2FAConfirm(db))
+	http.HandleFunc("/users/me/2fa/disable", handlers.UserMe2FADisable(db, guard))
 	http.HandleFunc("/posts", handlers.Posts(postsClient))
-	http.HandleFunc("/posts/", handlers.PostsWithID(postsClient, viewsWriter, likesWriter))
+	http.HandleFunc("/posts/", handlers.PostsWithID(postsClient, statsClient, publisher.Topic(viewsTopic), publisher.Topic(likesTopic)))
+	http.HandleFunc("/metrics", handlers.EventsMetrics(publisher))
+	http.HandleFunc("/admin/users", handlers.AdminUsers(db))
+	http.HandleFunc("/admin/users/", handlers.AdminUser(db, guard))
+	http.HandleFunc("/admin/posts/", handlers.AdminPosts(postsClient))
+	http.HandleFunc("/admin/stats", handlers.AdminStats(db, postsClient, publisher))
 	http.HandleFunc("/stats/post", handlers.StatsPost(statsClient))
+	http.HandleFunc("/stats/post/stream", handlers.StatsPostStream(statsClient, sseHeartbeat))
 	http.HandleFunc("/stats/top-posts", handlers.StatsTopPosts(statsClient, postsClient, db))
 	http.HandleFunc("/stats/top-users", handlers.StatsTopUsers(statsClient, db))
 
@@ -126,7 +287,27 @@ func main() {
 	}
 }
 
-func ensureKafkaTopic(brokers []string, topic string) error {
+// newMailer sends through MAIL_SMTP_ADDR when it is set and otherwise
+// writes messages to MAIL_FILE, or stdout, for local development.
+func newMailer() (mail.Mailer, error) {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "no-reply@social-network.local"
+	}
+	if addr := os.Getenv("MAIL_SMTP_ADDR"); addr != "" {
+		return &mail.SMTP{
+			Addr:     addr,
+			From:     from,
+			Username: os.Getenv("MAIL_SMTP_USER"),
+			Password: os.Getenv("MAIL_SMTP_PASSWORD"),
+		}, nil
+	}
+	return mail.NewFile(os.Getenv("MAIL_FILE"), from)
+}
+
+// ensureKafkaTopic creates the topic with the given number of partitions,
+// or grows an existing topic that has fewer.
+func ensureKafkaTopic(brokers []string, topic string, partitions int) error {
 	if topic == "" || len(brokers) == 0 {
 		return nil
 	}
@@ -148,11 +329,14 @@ func ensureKafkaTopic(brokers []string, topic string) error {
 				} else {
 					err = controllerConn.CreateTopics(kafka.TopicConfig{
 						Topic:             topic,
-						NumPartitions:     1,
+						NumPartitions:     partitions,
 						ReplicationFactor: 1,
 					})
 					controllerConn.Close()
-					if err == nil || strings.Contains(err.Error(), "already exists") {
+					if err != nil && strings.Contains(err.Error(), "already exists") {
+						err = growKafkaTopic(conn, controllerAddr, topic, partitions)
+					}
+					if err == nil {
 						conn.Close()
 						return nil
 					}
@@ -171,3 +355,24 @@ func ensureKafkaTopic(brokers []string, topic string) error {
 	}
 	return lastErr
 }
+
+func growKafkaTopic(conn *kafka.Conn, controllerAddr, topic string, partitions int) error {
+	existing, err := conn.ReadPartitions(topic)
+	if err != nil {
+		return err
+	}
+	if len(existing) >= partitions {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client := &kafka.Client{Addr: kafka.TCP(controllerAddr)}
+	resp, err := client.CreatePartitions(ctx, &kafka.CreatePartitionsRequest{
+		Topics: []kafka.TopicPartitionsConfig{{Name: topic, Count: int32(partitions)}},
+	})
+	if err != nil {
+		return err
+	}
+	return resp.Errors[topic]
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"main-service/internal/handlers"
+)
+
+// runRole implements "server role <login> <role>", which appoints the first
+// administrator; later changes can go through PUT /admin/users/{id}/role.
+// The user's sessions end so that new tokens carry the role.
+func runRole(ctx context.Context, db *sql.DB, args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: role <login> <user|moderator|admin>")
+	}
+	login, role := args[0], args[1]
+	if !handlers.ValidRole(role) {
+		return fmt.Errorf("unknown role %q", role)
+	}
+
+	res, err := db.ExecContext(ctx,
+		`update users set role = $1, session_version = session_version + 1 where login = $2`, role, login)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("no user %q", login)
+	}
+	fmt.Printf("%s is now %s\n", login, role)
+	return nil
+}
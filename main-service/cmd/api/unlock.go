@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+
+	"main-service/internal/lockout"
+)
+
+// runUnlock implements "server unlock [-history N] <login>": it lifts
+// the lockout of login and prints its latest failed attempts.
+func runUnlock(ctx context.Context, db *sql.DB, args []string) error {
+	fs := flag.NewFlagSet("unlock", flag.ContinueOnError)
+	history := fs.Int("history", 10, "number of recent failed attempts to print")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: unlock [-history N] <login>")
+	}
+	login := fs.Arg(0)
+
+	guard := lockout.NewGuard(lockout.NewPostgres(db), lockout.DefaultLoginPolicy, lockout.DefaultIPPolicy)
+	if *history > 0 {
+		attempts, err := guard.FailedAttempts(ctx, login, *history)
+		if err != nil {
+			return err
+		}
+		for _, a := range attempts {
+			fmt.Printf("%s  %-15s  %s\n", a.At.Format("2006-01-02 15:04:05"), a.IP, a.Reason)
+		}
+	}
+	if err := guard.Unlock(ctx, login); err != nil {
+		return err
+	}
+	fmt.Printf("unlocked %s\n", login)
+	return nil
+}
@@ -0,0 +1,262 @@
+// Package auth signs and verifies the JWTs issued by main-service.
+//
+// Tokens are signed with RS256 or EdDSA keys loaded from PEM files and carry
+// the key id in the "kid" header, so other services can verify them with the
+// public keys published as a JWKS. Every key in the directory verifies; only
+// the signing key signs. Rotating means adding a new key, letting it become
+// the signing key and deleting the old file once the tokens it signed have
+// expired. A new key only verifies until the JWKS caches of other services
+// have expired, so they know it before it signs. A shared HS256 secret is
+// still accepted for tokens without kid.
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWKSMaxAge is how long other services may cache the published keys.
+const JWKSMaxAge = 5 * time.Minute
+
+var (
+	ErrNoSigningKey = errors.New("no signing key configured")
+	ErrUnknownKey   = errors.New("token signed with an unknown key")
+)
+
+type Config struct {
+	// Dir holds one PEM file per key, named <kid>.pem. A private key signs
+	// and verifies, a public key only verifies.
+	Dir string
+	// SigningKID picks the signing key; empty picks the private key with the
+	// greatest kid, so naming keys by date makes the newest one sign.
+	SigningKID string
+	// PublishDelay is how long a key file must exist before it is picked as
+	// the signing key without SigningKID; zero means JWKSMaxAge. Until then
+	// the key only verifies.
+	PublishDelay time.Duration
+	// Secret enables HS256: tokens without kid are verified with it, and it
+	// signs when Dir holds no private key.
+	Secret string
+}
+
+type Key struct {
+	ID        string
+	Algorithm string
+	Public    crypto.PublicKey
+	private   crypto.Signer
+	added     time.Time // modification time of the key file
+}
+
+type KeySet struct {
+	cfg Config
+
+	mu      sync.RWMutex
+	keys    map[string]*Key
+	signing *Key
+}
+
+// Load reads the keys in cfg.Dir. It fails when no key can sign.
+func Load(cfg Config) (*KeySet, error) {
+	if cfg.PublishDelay == 0 {
+		cfg.PublishDelay = JWKSMaxAge
+	}
+	ks := &KeySet{cfg: cfg}
+	if err := ks.Reload(); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// Reload re-reads the key directory. On error the previous keys stay in use.
+func (ks *KeySet) Reload() error {
+	keys := make(map[string]*Key)
+	if ks.cfg.Dir != "" {
+		paths, err := filepath.Glob(filepath.Join(ks.cfg.Dir, "*.pem"))
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			key, err := readKey(path)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			keys[key.ID] = key
+		}
+	}
+
+	var signing *Key
+	if ks.cfg.SigningKID != "" {
+		signing = keys[ks.cfg.SigningKID]
+		if signing == nil || signing.private == nil {
+			return fmt.Errorf("signing key %q: no private key in %s", ks.cfg.SigningKID, ks.cfg.Dir)
+		}
+	} else {
+		// Keys younger than PublishDelay may still be missing from cached
+		// JWKS, so they sign only once old enough. The current signing key
+		// counts as published; new keys alone sign at once on a fresh start.
+		ks.mu.RLock()
+		current := ks.signing
+		ks.mu.RUnlock()
+		var ids, published []string
+		cutoff := time.Now().Add(-ks.cfg.PublishDelay)
+		for id, key := range keys {
+			if key.private == nil {
+				continue
+			}
+			ids = append(ids, id)
+			if !key.added.After(cutoff) || (current != nil && current.ID == id) {
+				published = append(published, id)
+			}
+		}
+		if len(published) > 0 {
+			signing = keys[slices.Max(published)]
+		} else if len(ids) > 0 {
+			signing = keys[slices.Max(ids)]
+		}
+	}
+	if signing == nil && ks.cfg.Secret == "" {
+		return ErrNoSigningKey
+	}
+
+	ks.mu.Lock()
+	ks.keys, ks.signing = keys, signing
+	ks.mu.Unlock()
+	return nil
+}
+
+// Watch reloads the keys every interval until ctx is cancelled, so a
+// rotation needs no restart.
+func (ks *KeySet) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := ks.Reload(); err != nil {
+			log.Printf("reload jwt keys failed: %v", err)
+		}
+	}
+}
+
+func readKey(path string) (*Key, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block")
+	}
+
+	key := &Key{ID: strings.TrimSuffix(filepath.Base(path), ".pem"), added: info.ModTime()}
+	var parsed any
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Algorithm, key.Public, key.private = jwt.SigningMethodRS256.Alg(), &k.PublicKey, k
+	case ed25519.PrivateKey:
+		key.Algorithm, key.Public, key.private = jwt.SigningMethodEdDSA.Alg(), k.Public(), k
+	case *rsa.PublicKey:
+		key.Algorithm, key.Public = jwt.SigningMethodRS256.Alg(), k
+	case ed25519.PublicKey:
+		key.Algorithm, key.Public = jwt.SigningMethodEdDSA.Alg(), k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+	return key, nil
+}
+
+// Sign signs claims with the signing key, or with the HS256 secret when no
+// key is configured.
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	ks.mu.RLock()
+	signing := ks.signing
+	ks.mu.RUnlock()
+
+	if signing == nil {
+		if ks.cfg.Secret == "" {
+			return "", ErrNoSigningKey
+		}
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(ks.cfg.Secret))
+	}
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(signing.Algorithm), claims)
+	token.Header["kid"] = signing.ID
+	return token.SignedString(signing.private)
+}
+
+// Parse verifies a token and returns its claims. The algorithm must match
+// the key named by kid; tokens without kid are only accepted as HS256.
+func (ks *KeySet) Parse(tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			if ks.cfg.Secret == "" || token.Method != jwt.SigningMethodHS256 {
+				return nil, ErrUnknownKey
+			}
+			return []byte(ks.cfg.Secret), nil
+		}
+
+		ks.mu.RLock()
+		key := ks.keys[kid]
+		ks.mu.RUnlock()
+		if key == nil || token.Method.Alg() != key.Algorithm {
+			return nil, ErrUnknownKey
+		}
+		return key.Public, nil
+	}, jwt.WithValidMethods([]string{"RS256", "EdDSA", "HS256"}))
+	if err != nil {
+		return nil, err
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("invalid claims")
+	}
+	return claims, nil
+}
+
+// Keys returns the verification keys sorted by kid.
+func (ks *KeySet) Keys() []*Key {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	keys := make([]*Key, 0, len(ks.keys))
+	for _, key := range ks.keys {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b *Key) int { return strings.Compare(a.ID, b.ID) })
+	return keys
+}
@@ -0,0 +1,50 @@
+// Package contact validates and normalizes user contact details.
+package contact
+
+import (
+	"errors"
+	"net/mail"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrInvalidEmail = errors.New("invalid email")
+	ErrInvalidPhone = errors.New("phone must be in E.164 format, e.g. +14155552671")
+)
+
+// e164 is a plus sign followed by up to 15 digits, the first not zero.
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+
+// Email returns the bare address in s with the domain lowercased. Display
+// names ("Alice <a@b.c>") and addresses without a dotted domain are
+// rejected.
+func Email(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Address != s || addr.Name != "" || len(s) > 254 {
+		return "", ErrInvalidEmail
+	}
+	at := strings.LastIndexByte(s, '@')
+	domain := strings.ToLower(s[at+1:])
+	if !strings.Contains(domain, ".") || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") {
+		return "", ErrInvalidEmail
+	}
+	return s[:at+1] + domain, nil
+}
+
+// Phone returns s in E.164 format. Spaces, dashes, dots and parentheses
+// used for grouping digits are dropped.
+func Phone(s string) (string, error) {
+	s = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')':
+			return -1
+		}
+		return r
+	}, s)
+	if !e164.MatchString(s) {
+		return "", ErrInvalidPhone
+	}
+	return s, nil
+}
@@ -0,0 +1,54 @@
+// Package dataexport writes the archive a user receives when they ask for
+// a copy of their personal data.
+package dataexport
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// File is one JSON document of the archive.
+type File struct {
+	Name        string
+	Description string
+	Data        any
+}
+
+// Write stores files as indented JSON in a ZIP archive, together with a
+// README.txt listing them.
+func Write(w io.Writer, userID string, createdAt time.Time, files []File) error {
+	zw := zip.NewWriter(w)
+
+	var readme strings.Builder
+	fmt.Fprintf(&readme, "Personal data of user %s, exported %s.\n\n", userID, createdAt.UTC().Format(time.RFC3339))
+	for _, f := range files {
+		fmt.Fprintf(&readme, "%s\t%s\n", f.Name, f.Description)
+	}
+	if err := add(zw, "README.txt", createdAt, []byte(readme.String())); err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		data, err := json.MarshalIndent(f.Data, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encode %s: %w", f.Name, err)
+		}
+		if err := add(zw, f.Name, createdAt, append(data, '\n')); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func add(zw *zip.Writer, name string, modified time.Time, data []byte) error {
+	fw, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+	_, err = fw.Write(data)
+	return err
+}
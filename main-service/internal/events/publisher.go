@@ -0,0 +1,473 @@
+// Package events delivers view and like events to Kafka without making the
+// HTTP request wait for the brokers.
+//
+// Every event is first appended to a write-ahead spool file and synced, so
+// it survives a Kafka outage, a restart of main-service and a crash of the
+// host. Concurrent events share one sync. A bounded in-memory queue holds
+// the head of the spool; when it is full, newer events stay on disk only
+// and are read back once the queue drains. The spool is truncated whenever
+// everything in it has been acknowledged by Kafka.
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// ErrSpoolFull is returned by Publish when the spool reached MaxSpoolBytes.
+var ErrSpoolFull = errors.New("event spool is full")
+
+// MessageWriter is the part of *kafka.Writer the publisher needs.
+type MessageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+type Config struct {
+	// Dir holds the spool file and the acknowledged offset.
+	Dir string
+	// QueueSize bounds the number of events kept in memory.
+	QueueSize int
+	// MaxSpoolBytes bounds the spool file; zero means 256 MiB.
+	MaxSpoolBytes int64
+	// BatchSize is the maximum number of messages per Kafka write.
+	BatchSize int
+}
+
+type record struct {
+	Topic string `json:"topic"`
+	Key   []byte `json:"key,omitempty"`
+	Value []byte `json:"value"`
+
+	end int64 // spool offset just past this record
+}
+
+type Publisher struct {
+	cfg    Config
+	writer MessageWriter
+
+	mu      sync.Mutex
+	spool   *os.File
+	size    int64    // bytes written to the spool
+	loaded  int64    // spool offset up to which records are in queue
+	acked   int64    // spool offset up to which Kafka acknowledged
+	queue   []record // unacknowledged records, oldest first
+	pending int      // unacknowledged records, in memory or on disk
+	notify  chan struct{}
+
+	synced   *sync.Cond // broadcast when a sync of the spool finishes
+	written  int64      // bytes ever appended, not reset by compaction
+	durable  int64      // of written, bytes known to be on disk
+	syncing  bool       // a sync runs without holding mu
+	syncErr  error      // error of the last failed sync
+	failedAt int64      // written offset syncErr applies up to
+
+	closed  bool
+	stop    context.CancelFunc // cancels a running Run
+	running chan struct{}      // closed when Run returns
+}
+
+// NewPublisher opens or creates the spool in cfg.Dir and picks up events a
+// previous process had not delivered. A nil writer keeps events spooled
+// until a publisher with a writer takes over the directory.
+func NewPublisher(cfg Config, writer MessageWriter) (*Publisher, error) {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1000
+	}
+	if cfg.MaxSpoolBytes <= 0 {
+		cfg.MaxSpoolBytes = 256 << 20
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	spool, err := os.OpenFile(filepath.Join(cfg.Dir, "events.spool"), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	p := &Publisher{cfg: cfg, writer: writer, spool: spool, notify: make(chan struct{}, 1)}
+	p.synced = sync.NewCond(&p.mu)
+	if err := p.recover(); err != nil {
+		spool.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// recover restores the acknowledged offset, drops a record torn by a crash
+// and counts what is left to deliver.
+func (p *Publisher) recover() error {
+	if data, err := os.ReadFile(p.offsetPath()); err == nil {
+		p.acked, _ = strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	info, err := p.spool.Stat()
+	if err != nil {
+		return err
+	}
+	p.acked = min(p.acked, info.Size())
+
+	valid := p.acked
+	if _, err := p.spool.Seek(p.acked, io.SeekStart); err != nil {
+		return err
+	}
+	reader := bufio.NewReader(p.spool)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			break
+		}
+		var rec record
+		if json.Unmarshal(line, &rec) != nil {
+			break
+		}
+		valid += int64(len(line))
+		p.pending++
+	}
+	if err := p.spool.Truncate(valid); err != nil {
+		return err
+	}
+	if _, err := p.spool.Seek(valid, io.SeekStart); err != nil {
+		return err
+	}
+	p.size, p.loaded = valid, p.acked
+	return nil
+}
+
+func (p *Publisher) offsetPath() string {
+	return filepath.Join(p.cfg.Dir, "events.offset")
+}
+
+// Publish spools the event and returns once it is on disk, without waiting
+// for Kafka.
+func (p *Publisher) Publish(topic string, key, value []byte) error {
+	line, err := json.Marshal(record{Topic: topic, Key: key, Value: value})
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.size+int64(len(line)) > p.cfg.MaxSpoolBytes {
+		return ErrSpoolFull
+	}
+	if _, err := p.spool.Write(line); err != nil {
+		return fmt.Errorf("spool event: %w", err)
+	}
+	p.size += int64(len(line))
+	p.written += int64(len(line))
+	p.pending++
+
+	// Keep the queue in spool order: only take the fast path when nothing
+	// older is waiting on disk.
+	if p.loaded == p.size-int64(len(line)) && len(p.queue) < p.cfg.QueueSize {
+		p.queue = append(p.queue, record{Topic: topic, Key: key, Value: value, end: p.size})
+		p.loaded = p.size
+	}
+	select {
+	case p.notify <- struct{}{}:
+	default:
+	}
+	return p.waitDurableLocked(p.written)
+}
+
+// waitDurableLocked returns once the spool is on disk up to end. Callers
+// share syncs: one that finds none running syncs everything written so far
+// without holding mu, and the others wait for it.
+func (p *Publisher) waitDurableLocked(end int64) error {
+	for p.durable < end {
+		if p.failedAt >= end {
+			return fmt.Errorf("sync spool: %w", p.syncErr)
+		}
+		if p.syncing {
+			p.synced.Wait()
+			continue
+		}
+		p.syncing = true
+		spool, target := p.spool, p.written
+		p.mu.Unlock()
+		err := spool.Sync()
+		p.mu.Lock()
+		p.syncing = false
+		if err != nil {
+			p.syncErr, p.failedAt = err, target
+		} else {
+			p.durable = max(p.durable, target)
+		}
+		p.synced.Broadcast()
+	}
+	return nil
+}
+
+// Topic returns a handle publishing to one topic.
+func (p *Publisher) Topic(name string) *Topic {
+	return &Topic{publisher: p, name: name}
+}
+
+type Topic struct {
+	publisher *Publisher
+	name      string
+}
+
+func (t *Topic) Publish(key, value []byte) error {
+	return t.publisher.Publish(t.name, key, value)
+}
+
+// Stats reports the delivery backlog.
+type Stats struct {
+	Pending       int   // events not yet acknowledged by Kafka
+	Queued        int   // of which held in memory
+	SpoolBytes    int64 // unacknowledged bytes in the spool
+	MaxSpoolBytes int64
+}
+
+func (p *Publisher) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return Stats{Pending: p.pending, Queued: len(p.queue), SpoolBytes: p.size - p.acked, MaxSpoolBytes: p.cfg.MaxSpoolBytes}
+}
+
+// Run delivers spooled events until ctx is cancelled or the publisher is
+// closed, retrying with exponential backoff while Kafka is unavailable.
+func (p *Publisher) Run(ctx context.Context) {
+	if p.writer == nil {
+		log.Printf("events: no kafka writer configured, events stay in the spool")
+		return
+	}
+	p.mu.Lock()
+	if p.closed || p.running != nil {
+		p.mu.Unlock()
+		return
+	}
+	ctx, p.stop = context.WithCancel(ctx)
+	p.running = make(chan struct{})
+	defer close(p.running)
+	p.mu.Unlock()
+	backoff := time.Second
+	for {
+		batch, err := p.nextBatch()
+		if err != nil {
+			log.Printf("events: read spool failed: %v", err)
+		}
+		if len(batch) == 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-p.notify:
+				continue
+			}
+		}
+
+		msgs := make([]kafka.Message, len(batch))
+		for i, rec := range batch {
+			msgs[i] = kafka.Message{Topic: rec.Topic, Key: rec.Key, Value: rec.Value}
+		}
+		if err := p.writer.WriteMessages(ctx, msgs...); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("events: kafka write failed, retrying in %s: %v", backoff, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, 30*time.Second)
+			continue
+		}
+		backoff = time.Second
+		if err := p.ack(len(batch)); err != nil {
+			log.Printf("events: save spool offset failed: %v", err)
+		}
+	}
+}
+
+// nextBatch returns the oldest unacknowledged records, reading them back
+// from the spool when the queue ran dry.
+func (p *Publisher) nextBatch() ([]record, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.queue) == 0 && p.loaded < p.size {
+		if err := p.loadLocked(); err != nil {
+			return nil, err
+		}
+	}
+	n := min(len(p.queue), p.cfg.BatchSize)
+	return p.queue[:n:n], nil
+}
+
+func (p *Publisher) loadLocked() error {
+	reader := bufio.NewReader(io.NewSectionReader(p.spool, p.loaded, p.size-p.loaded))
+	for len(p.queue) < p.cfg.QueueSize && p.loaded < p.size {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			return err
+		}
+		var rec record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return err
+		}
+		p.loaded += int64(len(line))
+		rec.end = p.loaded
+		p.queue = append(p.queue, rec)
+	}
+	return nil
+}
+
+// ack drops n delivered records and persists the new offset. Once the
+// delivered head of the spool grows large, it is cut off so a steady stream
+// of events does not fill the spool. Only Run calls it, so the offset does
+// not move while it is written without holding mu.
+func (p *Publisher) ack(n int) error {
+	p.mu.Lock()
+	p.acked = p.queue[n-1].end
+	p.queue = p.queue[n:]
+	p.pending -= n
+	acked, compact := p.acked, p.acked == p.size || p.acked >= p.cfg.MaxSpoolBytes/4
+	p.mu.Unlock()
+
+	if compact {
+		return p.compact()
+	}
+	return p.saveOffset(acked)
+}
+
+// compact rewrites the spool without its delivered head, streaming the tail
+// so a large backlog is not held in memory. The tail is copied while Publish
+// keeps appending; only what was appended meanwhile is copied under mu
+// before the files are swapped. The offset is reset first: a crash in
+// between re-sends delivered events rather than skipping undelivered ones.
+func (p *Publisher) compact() error {
+	p.mu.Lock()
+	spool, from, to := p.spool, p.acked, p.size
+	p.mu.Unlock()
+
+	path := spool.Name()
+	tmp := path + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, io.NewSectionReader(spool, from, to-from))
+	if err == nil {
+		err = out.Sync()
+	}
+	if err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// The old spool is closed below, so let a running sync finish first.
+	for p.syncing {
+		p.synced.Wait()
+	}
+	_, err = io.Copy(out, io.NewSectionReader(spool, to, p.size-to))
+	if err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := p.saveOffset(0); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	if err := syncDir(p.cfg.Dir); err != nil {
+		return err
+	}
+	compacted, err := os.OpenFile(path, os.O_RDWR, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := compacted.Seek(0, io.SeekEnd); err != nil {
+		compacted.Close()
+		return err
+	}
+	spool.Close()
+	p.spool = compacted
+
+	for i := range p.queue {
+		p.queue[i].end -= p.acked
+	}
+	p.size -= p.acked
+	p.loaded -= p.acked
+	p.acked = 0
+	// The new spool was synced as a whole.
+	p.durable = p.written
+	p.synced.Broadcast()
+	return nil
+}
+
+// saveOffset persists the acknowledged offset. It is synced to disk because
+// compaction relies on the reset offset surviving a crash.
+func (p *Publisher) saveOffset(offset int64) error {
+	f, err := os.OpenFile(p.offsetPath(), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(strconv.FormatInt(offset, 10))
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// Close stops Run, waits for it to return and closes the spool. Events not
+// yet delivered stay in the spool for the next process.
+func (p *Publisher) Close() error {
+	p.mu.Lock()
+	p.closed = true
+	stop, running := p.stop, p.running
+	p.mu.Unlock()
+	if stop != nil {
+		stop()
+		<-running
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for p.syncing {
+		p.synced.Wait()
+	}
+	return p.spool.Close()
+}
@@ -0,0 +1,403 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"main-service/internal/lockout"
+	proto "posts-service/proto"
+)
+
+type adminUser struct {
+	ID               int64      `json:"id"`
+	Login            string     `json:"login"`
+	Email            *string    `json:"email"`
+	EmailVerified    bool       `json:"email_verified"`
+	Role             string     `json:"role"`
+	TwoFactorEnabled bool       `json:"two_factor_enabled"`
+	CreatedAt        *time.Time `json:"created_at"` // null for accounts older than roles
+	SuspendedAt      *time.Time `json:"suspended_at"`
+	SuspendedReason  *string    `json:"suspended_reason"`
+	SuspendedBy      *int64     `json:"suspended_by"`
+	SuspendedByRole  *string    `json:"suspended_by_role"`
+}
+
+type adminUserDetails struct {
+	adminUser
+	FailedLogins []failedLogin `json:"failed_logins"`
+}
+
+type failedLogin struct {
+	IP     string    `json:"ip"`
+	Reason string    `json:"reason"`
+	At     time.Time `json:"at"`
+}
+
+type adminUsersResponse struct {
+	Users    []adminUser `json:"users"`
+	Page     int         `json:"page"`
+	PageSize int         `json:"page_size"`
+	Total    int64       `json:"total"`
+}
+
+type suspendRequest struct {
+	Reason string `json:"reason"`
+}
+
+type roleRequest struct {
+	Role string `json:"role"`
+}
+
+type adminStatsResponse struct {
+	Users struct {
+		Total     int64            `json:"total"`
+		ByRole    map[string]int64 `json:"by_role"`
+		Suspended int64            `json:"suspended"`
+		NewLast7d int64            `json:"new_last_7d"`
+	} `json:"users"`
+	Posts struct {
+		Total int64 `json:"total"`
+	} `json:"posts"`
+	Events struct {
+		Pending    int   `json:"pending"`
+		Queued     int   `json:"queued"`
+		SpoolBytes int64 `json:"spool_bytes"`
+	} `json:"events"`
+}
+
+const adminUserColumns = `id, login, email, email_verified, role, totp_enabled, created_at,
+	suspended_at, suspended_reason, suspended_by, suspended_by_role`
+
+func scanAdminUser(row interface{ Scan(...any) error }) (adminUser, error) {
+	var u adminUser
+	err := row.Scan(&u.ID, &u.Login, &u.Email, &u.EmailVerified, &u.Role, &u.TwoFactorEnabled,
+		&u.CreatedAt, &u.SuspendedAt, &u.SuspendedReason, &u.SuspendedBy, &u.SuspendedByRole)
+	return u, err
+}
+
+// AdminUsers lists users for moderators, newest first. q matches the login
+// or email, role and suspended narrow the list down.
+func AdminUsers(db *sql.DB) http.HandlerFunc {
+	return RequireRole(RoleModerator, func(w http.ResponseWriter, r *http.Request, _ string) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		query := r.URL.Query()
+		page, pageSize := 1, 20
+		if v := query.Get("page"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				http.Error(w, "invalid page parameter", http.StatusBadRequest)
+				return
+			}
+			page = n
+		}
+		if v := query.Get("page_size"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > 100 {
+				http.Error(w, "invalid page_size parameter", http.StatusBadRequest)
+				return
+			}
+			pageSize = n
+		}
+
+		var where []string
+		var args []any
+		if q := strings.TrimSpace(query.Get("q")); q != "" {
+			args = append(args, "%"+escapeLike(q)+"%")
+			where = append(where, fmt.Sprintf("(login ilike $%d or email ilike $%[1]d)", len(args)))
+		}
+		if role := query.Get("role"); role != "" {
+			if !ValidRole(role) {
+				http.Error(w, "invalid role parameter", http.StatusBadRequest)
+				return
+			}
+			args = append(args, role)
+			where = append(where, fmt.Sprintf("role = $%d", len(args)))
+		}
+		switch query.Get("suspended") {
+		case "":
+		case "true":
+			where = append(where, "suspended_at is not null")
+		case "false":
+			where = append(where, "suspended_at is null")
+		default:
+			http.Error(w, "invalid suspended parameter", http.StatusBadRequest)
+			return
+		}
+		filter := ""
+		if len(where) > 0 {
+			filter = " where " + strings.Join(where, " and ")
+		}
+
+		resp := adminUsersResponse{Users: []adminUser{}, Page: page, PageSize: pageSize}
+		if err := db.QueryRowContext(r.Context(), `select count(*) from users`+filter, args...).Scan(&resp.Total); err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		args = append(args, pageSize, (page-1)*pageSize)
+		rows, err := db.QueryContext(r.Context(),
+			fmt.Sprintf(`select %s from users%s order by id desc limit $%d offset $%d`,
+				adminUserColumns, filter, len(args)-1, len(args)),
+			args...)
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+		for rows.Next() {
+			u, err := scanAdminUser(rows)
+			if err != nil {
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+			resp.Users = append(resp.Users, u)
+		}
+		if err := rows.Err(); err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		respondJSON(w, http.StatusOK, resp)
+	})
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// AdminUser serves /admin/users/{id} and its actions:
+//
+//	GET               details with the latest failed logins (moderator)
+//	POST .../suspend  suspend the account and end its sessions (moderator)
+//	POST .../unsuspend                                         (moderator)
+//	POST .../unlock   lift a login lockout                     (moderator)
+//	PUT  .../role     change the role                          (admin)
+//
+// Moderators may only act on plain users, and nobody on themselves. Only
+// administrators may change or lift a suspension an administrator imposed.
+func AdminUser(db *sql.DB, guard *lockout.Guard) http.HandlerFunc {
+	return RequireRole(RoleModerator, func(w http.ResponseWriter, r *http.Request, callerID string) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/admin/users/"), "/")
+		id, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil || len(parts) > 2 {
+			http.NotFound(w, r)
+			return
+		}
+		action := ""
+		if len(parts) == 2 {
+			action = parts[1]
+		}
+
+		target, err := scanAdminUser(db.QueryRowContext(r.Context(),
+			`select `+adminUserColumns+` from users where id = $1`, id))
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		if action == "" {
+			if r.Method != http.MethodGet {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			attempts, err := guard.FailedAttempts(r.Context(), target.Login, 20)
+			if err != nil {
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+			details := adminUserDetails{adminUser: target, FailedLogins: []failedLogin{}}
+			for _, a := range attempts {
+				details.FailedLogins = append(details.FailedLogins, failedLogin{IP: a.IP, Reason: a.Reason, At: a.At})
+			}
+			respondJSON(w, http.StatusOK, details)
+			return
+		}
+
+		method := http.MethodPost
+		if action == "role" {
+			method = http.MethodPut
+		}
+		if r.Method != method {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if strconv.FormatInt(target.ID, 10) == callerID {
+			http.Error(w, "cannot change your own account", http.StatusForbidden)
+			return
+		}
+		if !hasRole(r, RoleAdmin) && target.Role != RoleUser {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		callerRole := RoleModerator
+		if hasRole(r, RoleAdmin) {
+			callerRole = RoleAdmin
+		}
+		suspendedByAdmin := target.SuspendedAt != nil && target.SuspendedByRole != nil && *target.SuspendedByRole == RoleAdmin
+		if (action == "suspend" || action == "unsuspend") && suspendedByAdmin && callerRole != RoleAdmin {
+			http.Error(w, "suspended by an administrator", http.StatusForbidden)
+			return
+		}
+
+		var suspension sql.Result
+		switch action {
+		case "suspend":
+			var req suspendRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "bad request", http.StatusBadRequest)
+				return
+			}
+			// The check on the suspender is repeated in SQL in case an
+			// administrator suspended the account meanwhile.
+			suspension, err = db.ExecContext(r.Context(),
+				`update users set suspended_at = coalesce(suspended_at, now()), suspended_reason = $1,
+				        suspended_by = $3, suspended_by_role = $4,
+				        session_version = session_version + 1
+				  where id = $2 and ($4 = 'admin' or suspended_at is null or suspended_by_role is distinct from 'admin')`,
+				strings.TrimSpace(req.Reason), target.ID, callerID, callerRole)
+		case "unsuspend":
+			suspension, err = db.ExecContext(r.Context(),
+				`update users set suspended_at = null, suspended_reason = null, suspended_by = null, suspended_by_role = null
+				  where id = $1 and ($2 = 'admin' or suspended_by_role is distinct from 'admin')`,
+				target.ID, callerRole)
+		case "unlock":
+			err = guard.Unlock(r.Context(), target.Login)
+		case "role":
+			if !hasRole(r, RoleAdmin) {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+			var req roleRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "bad request", http.StatusBadRequest)
+				return
+			}
+			if !ValidRole(req.Role) {
+				http.Error(w, "validation error", http.StatusBadRequest)
+				return
+			}
+			_, err = db.ExecContext(r.Context(),
+				`update users set role = $1, session_version = session_version + 1 where id = $2 and role <> $1`,
+				req.Role, target.ID)
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if suspension != nil {
+			if n, err := suspension.RowsAffected(); err == nil && n == 0 {
+				http.Error(w, "suspended by an administrator", http.StatusForbidden)
+				return
+			}
+		}
+		log.Printf("user %s: %s on user %d", callerID, action, target.ID)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// AdminPosts serves DELETE /admin/posts/{id}, which removes any post.
+// posts-service checks the moderator role again from the identity token.
+func AdminPosts(client proto.PostsServiceClient) http.HandlerFunc {
+	return RequireRole(RoleModerator, func(w http.ResponseWriter, r *http.Request, callerID string) {
+		id := strings.TrimPrefix(r.URL.Path, "/admin/posts/")
+		if id == "" || strings.Contains(id, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodDelete {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		_, err := client.ModerateDeletePost(r.Context(), &proto.ModerateDeletePostRequest{Id: id})
+		switch status.Code(err) {
+		case codes.OK:
+		case codes.NotFound:
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		case codes.PermissionDenied:
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		default:
+			http.Error(w, "service error", http.StatusBadGateway)
+			return
+		}
+		log.Printf("user %s: deleted post %s", callerID, id)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// AdminStats gives administrators an overview of users, posts and the
+// event backlog.
+func AdminStats(db *sql.DB, client proto.PostsServiceClient, publisher eventStats) http.HandlerFunc {
+	return RequireRole(RoleAdmin, func(w http.ResponseWriter, r *http.Request, _ string) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var resp adminStatsResponse
+		resp.Users.ByRole = map[string]int64{RoleUser: 0, RoleModerator: 0, RoleAdmin: 0}
+		rows, err := db.QueryContext(r.Context(), `select role, count(*) from users group by role`)
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var role string
+			var n int64
+			if err := rows.Scan(&role, &n); err != nil {
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+			resp.Users.ByRole[role] = n
+			resp.Users.Total += n
+		}
+		if err := rows.Err(); err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		err = db.QueryRowContext(r.Context(),
+			`select count(*) filter (where suspended_at is not null),
+			        count(*) filter (where created_at > now() - interval '7 days')
+			   from users`).Scan(&resp.Users.Suspended, &resp.Users.NewLast7d)
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		posts, err := client.ListPosts(r.Context(), &proto.ListPostsRequest{Page: 1, PageSize: 1})
+		if err != nil {
+			http.Error(w, "service error", http.StatusBadGateway)
+			return
+		}
+		resp.Posts.Total = int64(posts.GetTotal())
+
+		events := publisher.Stats()
+		resp.Events.Pending, resp.Events.Queued, resp.Events.SpoolBytes = events.Pending, events.Queued, events.SpoolBytes
+
+		respondJSON(w, http.StatusOK, resp)
+	})
+}
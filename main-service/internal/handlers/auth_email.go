@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"main-service/internal/auth"
+)
+
+type emailVerifiedResponse struct {
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+}
+
+// AuthEmailVerify confirms the pending email of the link's token and makes
+// it the user's email. The token works once and only while that address is
+// still the pending one. Opening the link again, as mail scanners that
+// prefetch links do before the user, answers the same success without
+// changing anything.
+func AuthEmailVerify(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			http.Error(w, "validation error", http.StatusBadRequest)
+			return
+		}
+
+		tx, err := db.BeginTx(r.Context(), nil)
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		var userID int64
+		var email string
+		err = tx.QueryRowContext(r.Context(),
+			`update email_verifications set used_at = now()
+			  where token_hash = $1 and used_at is null and expires_at > now()
+			  returning user_id, email`,
+			auth.HashOpaqueToken(token)).Scan(&userID, &email)
+		if errors.Is(err, sql.ErrNoRows) {
+			err = tx.QueryRowContext(r.Context(),
+				`select u.email from email_verifications e join users u on u.id = e.user_id
+				  where e.token_hash = $1 and e.used_at is not null
+				    and u.email_verified and lower(u.email) = lower(e.email)`,
+				auth.HashOpaqueToken(token)).Scan(&email)
+			if err == nil {
+				respondJSON(w, http.StatusOK, emailVerifiedResponse{Email: email, EmailVerified: true})
+				return
+			}
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "invalid or expired token", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		res, err := tx.ExecContext(r.Context(),
+			`update users set email = pending_email, email_verified = true, pending_email = null
+			  where id = $1 and pending_email = $2`,
+			userID, email)
+		if err != nil {
+			if isUniqueViolation(err) {
+				http.Error(w, "email already in use", http.StatusConflict)
+				return
+			}
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			http.Error(w, "invalid or expired token", http.StatusBadRequest)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, emailVerifiedResponse{Email: email, EmailVerified: true})
+	}
+}
+
+// isUniqueViolation reports whether err comes from a unique constraint.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
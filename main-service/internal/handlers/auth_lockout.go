@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"main-service/internal/lockout"
+)
+
+// beginLogin counts an attempt to authenticate as login. When login or the
+// client address is blocked after too many failed attempts, it answers 429
+// and reports false.
+func beginLogin(w http.ResponseWriter, r *http.Request, guard *lockout.Guard, login string) (lockout.Attempt, bool) {
+	attempt, wait, err := guard.Begin(r.Context(), login, clientIP(r))
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return attempt, false
+	}
+	if wait <= 0 {
+		return attempt, true
+	}
+	loginFailed(r, guard, attempt, lockout.ReasonBlocked)
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	http.Error(w, "too many attempts", http.StatusTooManyRequests)
+	return attempt, false
+}
+
+func loginFailed(r *http.Request, guard *lockout.Guard, attempt lockout.Attempt, reason string) {
+	if err := guard.Fail(r.Context(), attempt, reason); err != nil {
+		log.Printf("record failed login for %q failed: %v", attempt.Login, err)
+	}
+}
+
+// loginPassed takes back an attempt whose credentials were right but which
+// did not log in yet, such as one waiting for the second factor.
+func loginPassed(r *http.Request, guard *lockout.Guard, attempt lockout.Attempt) {
+	if err := guard.Pass(r.Context(), attempt); err != nil {
+		log.Printf("release login attempt for %q failed: %v", attempt.Login, err)
+	}
+}
+
+func loginSucceeded(r *http.Request, guard *lockout.Guard, attempt lockout.Attempt) {
+	if err := guard.Succeed(r.Context(), attempt); err != nil {
+		log.Printf("reset failed logins for %q failed: %v", attempt.Login, err)
+	}
+}
@@ -5,26 +5,68 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
-	"os"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
+
+	"main-service/internal/auth"
+	"main-service/internal/lockout"
 )
 
+// dummyPassHash is compared against when the login does not exist.
+var dummyPassHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("not a password"), bcrypt.DefaultCost)
+	return hash
+})
+
 type loginRequest struct {
 	Login string `json:"login"`
 	Password string `json:"password"`
 }
 
+// loginResponse carries either the session token or, when the account has
+// two-factor authentication, a short-lived challenge for /auth/login/mfa.
 type loginResponse struct {
-	Token string `json:"token"`
+	Token       string `json:"token,omitempty"`
+	MFARequired bool   `json:"mfa_required,omitempty"`
+	MFAToken    string `json:"mfa_token,omitempty"`
+}
+
+// tokenTypeClaim marks tokens that are not sessions, such as MFA
+// challenges; AuthMiddleware rejects them.
+const tokenTypeClaim = "typ"
+
+const mfaChallengeTTL = 5 * time.Minute
+
+func issueSession(keys *auth.KeySet, userID, sessionVersion int64, role string) (string, error) {
+	return keys.Sign(jwt.MapClaims{
+		"sub":               strconv.FormatInt(userID, 10),
+		"exp":               time.Now().Add(24 * time.Hour).Unix(),
+		"iat":               time.Now().Unix(),
+		sessionVersionClaim: sessionVersion,
+		roleClaim:           role,
+	})
+}
+
+func issueMFAChallenge(keys *auth.KeySet, userID, sessionVersion int64) (string, error) {
+	return keys.Sign(jwt.MapClaims{
+		"sub":               strconv.FormatInt(userID, 10),
+		"exp":               time.Now().Add(mfaChallengeTTL).Unix(),
+		"iat":               time.Now().Unix(),
+		sessionVersionClaim: sessionVersion,
+		tokenTypeClaim:      "mfa",
+	})
 }
 
-func AuthLogin(db *sql.DB) http.HandlerFunc {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
+// AuthLogin exchanges a login and password for a session. Failed attempts
+// are counted by guard, which blocks a login or address that keeps failing;
+// every failure gets the same answer so it does not tell which accounts exist.
+func AuthLogin(db *sql.DB, guard *lockout.Guard) http.HandlerFunc {
+	keys := keySet()
+	if keys == nil {
 		log.Fatal("JWT_SECRET not set")
 	}
 
@@ -39,41 +81,71 @@ func AuthLogin(db *sql.DB) http.HandlerFunc {
 			http.Error(w, "bad request", http.StatusBadRequest)
 			return
 		}
+		attempt, ok := beginLogin(w, r, guard, req.Login)
+		if !ok {
+			return
+		}
 
 		var id int64
 		var passHash []byte
+		var sessionVersion int64
+		var totpEnabled bool
+		var role string
+		var suspended bool
 
 		err := db.QueryRow(
-			`select id, pass_hash from users where login = $1`,
-			req.Login).Scan(&id, &passHash)
+			`select id, pass_hash, session_version, totp_enabled, role, suspended_at is not null
+			   from users where login = $1`,
+			req.Login).Scan(&id, &passHash, &sessionVersion, &totpEnabled, &role, &suspended)
 	
 		if err == sql.ErrNoRows {
+			// Costs as much as a wrong password, so the response time does
+			// not tell whether the login exists.
+			bcrypt.CompareHashAndPassword(dummyPassHash(), []byte(req.Password))
+			loginFailed(r, guard, attempt, lockout.ReasonInvalidCredentials)
 			http.Error(w, "invalid credentials", http.StatusUnauthorized)
 			return
 		}
 
 		if err != nil {
+			loginPassed(r, guard, attempt)
 			http.Error(w, "internal error", http.StatusInternalServerError)
 			return
 		}
 
 		if err := bcrypt.CompareHashAndPassword(passHash, []byte(req.Password)); err != nil {
+			loginFailed(r, guard, attempt, lockout.ReasonInvalidCredentials)
 			http.Error(w, "invalid credentials", http.StatusUnauthorized)
 			return
 		}
 
-		claims := jwt.MapClaims{
-			"sub": strconv.FormatInt(id, 10),
-			"exp": time.Now().Add(24 * time.Hour).Unix(),
-			"iat": time.Now().Unix(),
+		// Only told once the password is right, so it reveals nothing to
+		// someone guessing.
+		if suspended {
+			loginPassed(r, guard, attempt)
+			http.Error(w, "account suspended", http.StatusForbidden)
+			return
+		}
+
+		if totpEnabled {
+			challenge, err := issueMFAChallenge(keys, id, sessionVersion)
+			if err != nil {
+				loginPassed(r, guard, attempt)
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+			loginPassed(r, guard, attempt)
+			respondJSON(w, http.StatusOK, loginResponse{MFARequired: true, MFAToken: challenge})
+			return
 		}
 
-		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-		signed, err := token.SignedString([]byte(secret))
+		signed, err := issueSession(keys, id, sessionVersion, role)
 		if err != nil {
+			loginPassed(r, guard, attempt)
 			http.Error(w, "internal error", http.StatusInternalServerError)
 			return
 		}
+		loginSucceeded(r, guard, attempt)
 
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
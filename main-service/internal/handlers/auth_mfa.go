@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"main-service/internal/auth"
+	"main-service/internal/lockout"
+)
+
+type mfaLoginRequest struct {
+	MFAToken     string `json:"mfa_token"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// verifySecondFactor accepts a current TOTP code not used before or an
+// unused recovery code, which it uses up.
+func verifySecondFactor(ctx context.Context, q querier, userID int64, code, recoveryCode string) (bool, error) {
+	if code != "" {
+		var secret sql.NullString
+		if err := q.QueryRowContext(ctx, `select totp_secret from users where id = $1`, userID).Scan(&secret); err != nil {
+			return false, err
+		}
+		step, ok := auth.ValidateTOTP(secret.String, code, time.Now())
+		if !secret.Valid || !ok {
+			return false, nil
+		}
+		res, err := q.ExecContext(ctx,
+			`update users set totp_last_step = $1
+			  where id = $2 and (totp_last_step is null or totp_last_step < $1)`,
+			step, userID)
+		if err != nil {
+			return false, err
+		}
+		n, err := res.RowsAffected()
+		return n == 1, err
+	}
+	if recoveryCode != "" {
+		res, err := q.ExecContext(ctx,
+			`update user_recovery_codes set used_at = now()
+			  where user_id = $1 and code_hash = $2 and used_at is null`,
+			userID, auth.HashRecoveryCode(recoveryCode))
+		if err != nil {
+			return false, err
+		}
+		n, err := res.RowsAffected()
+		return n == 1, err
+	}
+	return false, nil
+}
+
+// AuthLoginMFA completes a login started by AuthLogin for an account with
+// two-factor authentication, exchanging the challenge and a TOTP or
+// recovery code for a session token. Wrong codes count towards the lockout
+// of the login like wrong passwords do.
+func AuthLoginMFA(db *sql.DB, guard *lockout.Guard) http.HandlerFunc {
+	keys := keySet()
+	if keys == nil {
+		log.Fatal("JWT_SECRET not set")
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req mfaLoginRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		claims, err := keys.Parse(req.MFAToken)
+		if err != nil || claims[tokenTypeClaim] != "mfa" {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+		sub, _ := claims["sub"].(string)
+		version, ok := claims[sessionVersionClaim].(float64)
+		if !ok {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+
+		var userID, sessionVersion int64
+		var login, role string
+		var enabled, suspended bool
+		err = db.QueryRowContext(r.Context(),
+			`select id, login, session_version, totp_enabled, role, suspended_at is not null
+			   from users where id = $1`, sub,
+		).Scan(&userID, &login, &sessionVersion, &enabled, &role, &suspended)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && (!enabled || int64(version) != sessionVersion)) {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		// The challenge proves the password, so this reveals nothing.
+		if suspended {
+			http.Error(w, "account suspended", http.StatusForbidden)
+			return
+		}
+		attempt, ok := beginLogin(w, r, guard, login)
+		if !ok {
+			return
+		}
+
+		valid, err := verifySecondFactor(r.Context(), db, userID, req.Code, req.RecoveryCode)
+		if err != nil {
+			loginPassed(r, guard, attempt)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if !valid {
+			loginFailed(r, guard, attempt, lockout.ReasonInvalidCode)
+			http.Error(w, "invalid code", http.StatusUnauthorized)
+			return
+		}
+
+		signed, err := issueSession(keys, userID, sessionVersion, role)
+		if err != nil {
+			loginPassed(r, guard, attempt)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		loginSucceeded(r, guard, attempt)
+		respondJSON(w, http.StatusOK, loginResponse{Token: signed})
+	}
+}
@@ -5,13 +5,36 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"sync/atomic"
 
-	"github.com/golang-jwt/jwt/v5"
+	"common/identity"
+	"main-service/internal/auth"
 )
 
-func AuthMiddleware(next func(w http.ResponseWriter, r *http.Request, userID string)) http.HandlerFunc {
+var tokenKeys atomic.Pointer[auth.KeySet]
+
+// UseKeySet makes the handlers sign and verify tokens with keys instead of
+// an HS256 key set built from JWT_SECRET. Call it before building handlers.
+func UseKeySet(keys *auth.KeySet) {
+	tokenKeys.Store(keys)
+}
+
+// keySet returns the configured keys or nil when there are none.
+func keySet() *auth.KeySet {
+	if keys := tokenKeys.Load(); keys != nil {
+		return keys
+	}
 	secret := os.Getenv("JWT_SECRET")
 	if secret == "" {
+		return nil
+	}
+	keys, _ := auth.Load(auth.Config{Secret: secret})
+	return keys
+}
+
+func AuthMiddleware(next func(w http.ResponseWriter, r *http.Request, userID string)) http.HandlerFunc {
+	keys := keySet()
+	if keys == nil {
 		log.Fatal("JWT_SECRET not set")
 	}
 
@@ -22,24 +45,46 @@ func AuthMiddleware(next func(w http.ResponseWriter, r *http.Request, userID str
 			return
 		}
 
-		tokenSigned := strings.TrimPrefix(auth, "Bearer ")
-
-		token, err := jwt.Parse(tokenSigned, func(_ *jwt.Token) (any, error) {
-			return []byte(secret), nil
-		})
-
-		if err != nil || !token.Valid {
+		claims, err := keys.Parse(strings.TrimPrefix(auth, "Bearer "))
+		if err != nil {
 			http.Error(w, "invalid token", http.StatusUnauthorized)
 			return
 		}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			http.Error(w, "invalid claims", http.StatusUnauthorized)
+		if _, ok := claims[tokenTypeClaim]; ok {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
 			return
 		}
-
 		userID, _ := claims["sub"].(string)
-		next(w, r, userID)
+		if !sessionCurrent(r.Context(), userID, claims) {
+			http.Error(w, "session expired", http.StatusUnauthorized)
+			return
+		}
+		// gRPC calls made with r.Context() act on behalf of the user.
+		ctx := identity.WithCaller(r.Context(), identity.Caller{UserID: userID, Role: roleFromClaims(claims)})
+		next(w, r.WithContext(ctx), userID)
+	}
+}
+
+// optionalUserID returns the caller's ID when the request carries a valid
+// token and "" otherwise, for endpoints that also serve anonymous users.
+func optionalUserID(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	keys := keySet()
+	if !strings.HasPrefix(auth, "Bearer ") || keys == nil {
+		return ""
+	}
+
+	claims, err := keys.Parse(strings.TrimPrefix(auth, "Bearer "))
+	if err != nil {
+		return ""
+	}
+	if _, ok := claims[tokenTypeClaim]; ok {
+		return ""
+	}
+	userID, _ := claims["sub"].(string)
+	if !sessionCurrent(r.Context(), userID, claims) {
+		return ""
 	}
+	return userID
 }
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"main-service/internal/auth"
+	"main-service/internal/lockout"
+	"main-service/internal/mail"
+)
+
+// passwordResetMailTimeout bounds the background lookup and mail delivery.
+const passwordResetMailTimeout = time.Minute
+
+type forgotPasswordRequest struct {
+	Login string `json:"login"`
+	Email string `json:"email"`
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// AuthPasswordForgot mails a reset link, valid for ttl, to the verified
+// email of the account matching the login or email. It answers 202 before
+// looking the account up and sends the mail in the background, so neither
+// the status nor the response time tells whether such an account exists.
+// Requests are limited per login or email and per client IP.
+func AuthPasswordForgot(db *sql.DB, mailer mail.Mailer, limiter *lockout.Limiter, resetURL string, ttl time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req forgotPasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		req.Login, req.Email = strings.TrimSpace(req.Login), strings.TrimSpace(req.Email)
+		if req.Login == "" && req.Email == "" {
+			http.Error(w, "validation error", http.StatusBadRequest)
+			return
+		}
+
+		allowed, err := limiter.Allow(r.Context(), cmp.Or(req.Login, strings.ToLower(req.Email)), clientIP(r))
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if !allowed {
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+
+		go sendPasswordReset(db, mailer, req, resetURL, ttl)
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// sendPasswordReset issues a reset token for the account matching req, if
+// there is one, and mails the link.
+func sendPasswordReset(db *sql.DB, mailer mail.Mailer, req forgotPasswordRequest, resetURL string, ttl time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), passwordResetMailTimeout)
+	defer cancel()
+
+	var userID int64
+	var email string
+	err := db.QueryRowContext(ctx,
+		`select id, email from users
+		  where email_verified
+		    and (login = $1 or lower(email) = lower($2))
+		  order by id limit 1`,
+		req.Login, req.Email).Scan(&userID, &email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return
+	}
+	if err != nil {
+		log.Printf("look up account for password reset failed: %v", err)
+		return
+	}
+
+	token, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		log.Printf("create password reset token failed: %v", err)
+		return
+	}
+	_, err = db.ExecContext(ctx,
+		`insert into password_resets (token_hash, user_id, expires_at) values ($1, $2, $3)`,
+		hash, userID, time.Now().Add(ttl))
+	if err != nil {
+		log.Printf("store password reset token for user %d failed: %v", userID, err)
+		return
+	}
+
+	link := resetURL + "?token=" + url.QueryEscape(token)
+	err = mailer.Send(ctx, mail.Message{
+		To:      email,
+		Subject: "Password reset",
+		Body: fmt.Sprintf("Someone asked to reset the password of your account.\n\n"+
+			"Follow this link within %s to choose a new one:\n%s\n\n"+
+			"If it was not you, ignore this message.\n", ttl, link),
+	})
+	if err != nil {
+		log.Printf("send password reset mail to user %d failed: %v", userID, err)
+	}
+}
+
+// AuthPasswordReset sets a new password with a token from
+// AuthPasswordForgot. The token works once; the reset also invalidates the
+// user's other reset tokens and every session.
+func AuthPasswordReset(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req resetPasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		if req.Token == "" || len(req.Password) < 8 {
+			http.Error(w, "validation error", http.StatusBadRequest)
+			return
+		}
+
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		tx, err := db.BeginTx(r.Context(), nil)
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		var userID int64
+		err = tx.QueryRowContext(r.Context(),
+			`update password_resets set used_at = now()
+			  where token_hash = $1 and used_at is null and expires_at > now()
+			  returning user_id`,
+			auth.HashOpaqueToken(req.Token)).Scan(&userID)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "invalid or expired token", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		if _, err := tx.ExecContext(r.Context(),
+			`update users set pass_hash = $1, session_version = session_version + 1 where id = $2`,
+			hash, userID); err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if _, err := tx.ExecContext(r.Context(),
+			`update password_resets set used_at = now() where user_id = $1 and used_at is null`,
+			userID); err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"main-service/internal/auth"
+)
+
+// JWKS publishes the public keys that verify main-service tokens.
+func JWKS(keys *auth.KeySet) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		// New keys start signing only after this age, so verifiers always
+		// know them first.
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(auth.JWKSMaxAge.Seconds())))
+		respondJSON(w, http.StatusOK, keys.JWKS())
+	}
+}
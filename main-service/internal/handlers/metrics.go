@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"main-service/internal/events"
+)
+
+type eventStats interface {
+	Stats() events.Stats
+}
+
+// EventsMetrics reports the backlog of view and like events waiting for
+// Kafka in the Prometheus text format.
+func EventsMetrics(publisher eventStats) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		stats := publisher.Stats()
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		fmt.Fprintln(w, "# HELP main_events_pending Events accepted but not yet acknowledged by Kafka.")
+		fmt.Fprintln(w, "# TYPE main_events_pending gauge")
+		fmt.Fprintf(w, "main_events_pending %d\n", stats.Pending)
+		fmt.Fprintln(w, "# HELP main_events_queued Pending events held in memory.")
+		fmt.Fprintln(w, "# TYPE main_events_queued gauge")
+		fmt.Fprintf(w, "main_events_queued %d\n", stats.Queued)
+		fmt.Fprintln(w, "# HELP main_events_spool_bytes Undelivered bytes in the on-disk spool.")
+		fmt.Fprintln(w, "# TYPE main_events_spool_bytes gauge")
+		fmt.Fprintf(w, "main_events_spool_bytes %d\n", stats.SpoolBytes)
+		fmt.Fprintln(w, "# HELP main_events_spool_limit_bytes Size at which the spool rejects new events.")
+		fmt.Fprintln(w, "# TYPE main_events_spool_limit_bytes gauge")
+		fmt.Fprintf(w, "main_events_spool_limit_bytes %d\n", stats.MaxSpoolBytes)
+	}
+}
@@ -3,7 +3,9 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"net"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -12,8 +14,7 @@ import (
 	"google.golang.org/grpc/status"
 
 	proto "posts-service/proto"
-
-	"github.com/segmentio/kafka-go"
+	statspb "stats-service/proto"
 )
 
 func respondJSON(w http.ResponseWriter, status int, payload any) {
@@ -71,7 +72,7 @@ func Posts(client proto.PostsServiceClient) http.HandlerFunc {
 					return
 				}
 
-				resp, err := client.CreatePost(context.Background(), &proto.CreatePostRequest{
+				resp, err := client.CreatePost(r.Context(), &proto.CreatePostRequest{
 					UserId:  userID,
 					Title:   req.Title,
 					Content: req.Content,
@@ -89,7 +90,12 @@ func Posts(client proto.PostsServiceClient) http.HandlerFunc {
 	}
 }
 
-func PostsWithID(client proto.PostsServiceClient, viewsWriter, likesWriter *kafka.Writer) http.HandlerFunc {
+// eventSink accepts an event for asynchronous delivery to one topic.
+type eventSink interface {
+	Publish(key, value []byte) error
+}
+
+func PostsWithID(client proto.PostsServiceClient, statsClient statspb.StatsServiceClient, views, likes eventSink) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		path := strings.TrimPrefix(r.URL.Path, "/posts/")
 		if path == "" {
@@ -135,7 +141,7 @@ func PostsWithID(client proto.PostsServiceClient, viewsWriter, likesWriter *kafk
 						return
 					}
 
-					resp, err := client.UpdatePost(context.Background(), &proto.UpdatePostRequest{
+					resp, err := client.UpdatePost(r.Context(), &proto.UpdatePostRequest{
 						Id:      id,
 						UserId:  userID,
 						Title:   req.Title,
@@ -150,7 +156,7 @@ func PostsWithID(client proto.PostsServiceClient, viewsWriter, likesWriter *kafk
 				})(w, r)
 			case http.MethodDelete:
 				AuthMiddleware(func(w http.ResponseWriter, r *http.Request, userID string) {
-					resp, err := client.DeletePost(context.Background(), &proto.DeletePostRequest{
+					resp, err := client.DeletePost(r.Context(), &proto.DeletePostRequest{
 						Id:     id,
 						UserId: userID,
 					})
@@ -169,12 +175,12 @@ func PostsWithID(client proto.PostsServiceClient, viewsWriter, likesWriter *kafk
 				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 				return
 			}
-			if viewsWriter == nil {
+			if views == nil {
 				http.Error(w, "service error", http.StatusBadGateway)
 				return
 			}
-			if err := sendPostEvent(r.Context(), viewsWriter, id, "view"); err != nil {
-				http.Error(w, "service error", http.StatusBadGateway)
+			if err := sendPostEvent(views, id, "view", optionalUserID(r), clientIP(r)); err != nil {
+				http.Error(w, "service unavailable", http.StatusServiceUnavailable)
 				return
 			}
 			w.WriteHeader(http.StatusAccepted)
@@ -183,29 +189,74 @@ func PostsWithID(client proto.PostsServiceClient, viewsWriter, likesWriter *kafk
 				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 				return
 			}
-			if likesWriter == nil {
+			if likes == nil {
 				http.Error(w, "service error", http.StatusBadGateway)
 				return
 			}
-			if err := sendPostEvent(r.Context(), likesWriter, id, "like"); err != nil {
-				http.Error(w, "service error", http.StatusBadGateway)
+			if err := sendPostEvent(likes, id, "like", optionalUserID(r), clientIP(r)); err != nil {
+				http.Error(w, "service unavailable", http.StatusServiceUnavailable)
 				return
 			}
 			w.WriteHeader(http.StatusAccepted)
+		case "related":
+			if r.Method != http.MethodGet {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			relatedPosts(w, r, client, statsClient, id)
 		default:
 			http.NotFound(w, r)
 		}
 	}
 }
 
-func sendPostEvent(ctx context.Context, writer *kafka.Writer, postID, eventType string) error {
+func relatedPosts(w http.ResponseWriter, r *http.Request, client proto.PostsServiceClient, statsClient statspb.StatsServiceClient, id string) {
+	limit := 5
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 50 {
+			http.Error(w, "invalid limit parameter", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	resp, err := statsClient.GetRelatedPosts(r.Context(), &statspb.RelatedPostsRequest{PostId: id, Limit: int32(limit)})
+	if err != nil {
+		http.Error(w, "service error", http.StatusBadGateway)
+		return
+	}
+
+	type relatedPost struct {
+		*proto.Post
+		Score float64 `json:"score"`
+	}
+	out := []relatedPost{}
+	for _, item := range resp.GetItems() {
+		postResp, err := client.GetPost(r.Context(), &proto.GetPostRequest{Id: item.GetPostId()})
+		if err != nil || postResp == nil || postResp.Post == nil {
+			continue
+		}
+		out = append(out, relatedPost{Post: postResp.Post, Score: item.GetScore()})
+	}
+
+	respondJSON(w, http.StatusOK, out)
+}
+
+// sendPostEvent hands the event to the publisher, which delivers it to Kafka
+// in the background; it only fails when the local spool is unusable.
+func sendPostEvent(sink eventSink, postID, eventType, userID, ip string) error {
 	payload := struct {
 		PostID    string    `json:"post_id"`
 		EventType string    `json:"event_type"`
+		UserID    string    `json:"user_id,omitempty"`
+		IP        string    `json:"ip,omitempty"`
 		Timestamp time.Time `json:"timestamp"`
 	}{
 		PostID:    postID,
 		EventType: eventType,
+		UserID:    userID,
+		IP:        ip,
 		Timestamp: time.Now().UTC(),
 	}
 
@@ -214,5 +265,22 @@ func sendPostEvent(ctx context.Context, writer *kafka.Writer, postID, eventType
 		return err
 	}
 
-	return writer.WriteMessages(ctx, kafka.Message{Value: data})
+	return sink.Publish([]byte(postID), data)
+}
+
+// clientIP is the address stats-service uses to spot bot traffic. The
+// X-Forwarded-For header is only honoured behind a trusted proxy, since
+// clients could otherwise spoof it to dodge the limits.
+func clientIP(r *http.Request) string {
+	if os.Getenv("TRUST_FORWARDED_FOR") == "true" {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			first, _, _ := strings.Cut(forwarded, ",")
+			return strings.TrimSpace(first)
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
 }
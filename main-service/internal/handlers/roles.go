@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"common/identity"
+)
+
+// roleClaim holds the role of the user a session was issued to. Changing a
+// role bumps the session version, so tokens never carry a stale one.
+const roleClaim = "role"
+
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// roleRank orders roles: each one may do everything the lower ones may.
+var roleRank = map[string]int{RoleUser: 0, RoleModerator: 1, RoleAdmin: 2}
+
+// ValidRole reports whether role is one of the known roles.
+func ValidRole(role string) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+func roleFromClaims(claims jwt.MapClaims) string {
+	// Sessions issued before roles existed belong to plain users.
+	if role, _ := claims[roleClaim].(string); ValidRole(role) {
+		return role
+	}
+	return RoleUser
+}
+
+func hasRole(r *http.Request, min string) bool {
+	return roleRank[identity.Role(r.Context())] >= roleRank[min]
+}
+
+// RequireRole is AuthMiddleware for users with at least the min role; others
+// get 403.
+func RequireRole(min string, next func(w http.ResponseWriter, r *http.Request, userID string)) http.HandlerFunc {
+	return AuthMiddleware(func(w http.ResponseWriter, r *http.Request, userID string) {
+		if !hasRole(r, min) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		next(w, r, userID)
+	})
+}
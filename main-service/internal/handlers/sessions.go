@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// sessionVersionClaim holds the session version a token was issued with.
+// Bumping users.session_version, as a password reset does, invalidates
+// every token issued before.
+const sessionVersionClaim = "sv"
+
+type SessionStore interface {
+	SessionVersion(ctx context.Context, userID string) (int64, error)
+}
+
+type sessionStoreHolder struct{ store SessionStore }
+
+var sessions atomic.Pointer[sessionStoreHolder]
+
+// UseSessionStore makes the handlers reject tokens whose session version is
+// no longer current. Without a store (or with nil) every valid token is
+// accepted.
+func UseSessionStore(store SessionStore) {
+	sessions.Store(&sessionStoreHolder{store: store})
+}
+
+type dbSessionStore struct {
+	db *sql.DB
+}
+
+func NewDBSessionStore(db *sql.DB) SessionStore {
+	return &dbSessionStore{db: db}
+}
+
+func (s *dbSessionStore) SessionVersion(ctx context.Context, userID string) (int64, error) {
+	var version int64
+	err := s.db.QueryRowContext(ctx, `select session_version from users where id = $1`, userID).Scan(&version)
+	return version, err
+}
+
+// sessionCurrent reports whether the token with claims belongs to the
+// user's current session.
+func sessionCurrent(ctx context.Context, userID string, claims jwt.MapClaims) bool {
+	holder := sessions.Load()
+	if holder == nil || holder.store == nil {
+		return true
+	}
+	current, err := holder.store.SessionVersion(ctx, userID)
+	if err != nil {
+		return false
+	}
+	version, _ := claims[sessionVersionClaim].(float64)
+	return int64(version) == current
+}
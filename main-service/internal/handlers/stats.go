@@ -3,8 +3,11 @@ package handlers
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	proto "posts-service/proto"
 	statspb "stats-service/proto"
@@ -40,6 +43,98 @@ func StatsPost(client statspb.StatsServiceClient) http.HandlerFunc {
 	}
 }
 
+// StatsPostStream relays WatchPostStats updates to the client as
+// Server-Sent Events. While no update arrives, a comment is sent every
+// heartbeat so that proxies do not close the idle connection.
+func StatsPostStream(client statspb.StatsServiceClient, heartbeat time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		id := r.URL.Query().Get("id")
+		if id == "" {
+			http.Error(w, "missing id", http.StatusBadRequest)
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+
+		stream, err := client.WatchPostStats(r.Context(), &statspb.PostStatsRequest{PostId: id})
+		if err != nil {
+			http.Error(w, "service error", http.StatusBadGateway)
+			return
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			http.Error(w, "service error", http.StatusBadGateway)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+
+		updates := make(chan *statspb.PostStatsResponse)
+		go func() {
+			defer close(updates)
+			for {
+				resp, err := stream.Recv()
+				if err != nil {
+					return
+				}
+				select {
+				case updates <- resp:
+				case <-r.Context().Done():
+					return
+				}
+			}
+		}()
+
+		send := func(resp *statspb.PostStatsResponse) bool {
+			data, err := json.Marshal(map[string]any{
+				"id":    resp.GetPostId(),
+				"views": resp.GetViews(),
+				"likes": resp.GetLikes(),
+			})
+			if err != nil {
+				return false
+			}
+			if _, err := fmt.Fprintf(w, "event: stats\ndata: %s\n\n", data); err != nil {
+				return false
+			}
+			flusher.Flush()
+			return true
+		}
+		if !send(resp) {
+			return
+		}
+
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case resp, ok := <-updates:
+				if !ok || !send(resp) {
+					return
+				}
+				ticker.Reset(heartbeat)
+			case <-ticker.C:
+				if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+					return
+				}
+				flusher.Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}
+}
+
 func StatsTopPosts(statsClient statspb.StatsServiceClient, postsClient proto.PostsServiceClient, db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -51,21 +146,31 @@ func StatsTopPosts(statsClient statspb.StatsServiceClient, postsClient proto.Pos
 		if metric == "" {
 			metric = "views"
 		}
-		if metric != "views" && metric != "likes" {
+		if metric != "views" && metric != "likes" && metric != "trending" {
 			http.Error(w, "invalid metric", http.StatusBadRequest)
 			return
 		}
+		req := &statspb.TopPostsRequest{Metric: metric, Limit: 5}
+		if v := r.URL.Query().Get("half_life_hours"); v != "" {
+			hours, err := strconv.ParseFloat(v, 64)
+			if err != nil || hours <= 0 || metric != "trending" {
+				http.Error(w, "invalid half_life_hours", http.StatusBadRequest)
+				return
+			}
+			req.HalfLifeHours = hours
+		}
 
-		resp, err := statsClient.GetTopPosts(r.Context(), &statspb.TopPostsRequest{Metric: metric, Limit: 5})
+		resp, err := statsClient.GetTopPosts(r.Context(), req)
 		if err != nil {
 			http.Error(w, "service error", http.StatusBadGateway)
 			return
 		}
 
 		type item struct {
-			ID          string `json:"id"`
-			AuthorLogin string `json:"author_login"`
-			Value       int64  `json:"value"`
+			ID          string  `json:"id"`
+			AuthorLogin string  `json:"author_login"`
+			Value       int64   `json:"value"`
+			Score       float64 `json:"score,omitempty"`
 		}
 		var out []item
 		for _, post := range resp.GetItems() {
@@ -81,6 +186,7 @@ func StatsTopPosts(statsClient statspb.StatsServiceClient, postsClient proto.Pos
 				ID:          post.GetPostId(),
 				AuthorLogin: login,
 				Value:       post.GetValue(),
+				Score:       post.GetScore(),
 			})
 		}
 
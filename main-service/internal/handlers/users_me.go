@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"net/http"
+
+	"main-service/internal/lockout"
+	proto "posts-service/proto"
 )
 
 type userProfile struct {
@@ -14,16 +18,35 @@ type userProfile struct {
 	BirthDate *string `json:"birth_date"`
 	Email     *string `json:"email"`
 	Phone     *string `json:"phone"`
+
+	EmailVerified bool    `json:"email_verified"`
+	PendingEmail  *string `json:"pending_email"`
+
+	TwoFactorEnabled bool   `json:"two_factor_enabled"`
+	Role             string `json:"role"`
+}
+
+func loadProfile(ctx context.Context, db *sql.DB, userID string) (userProfile, error) {
+	var u userProfile
+	err := db.QueryRowContext(ctx,
+		`select id, login, first_name, last_name, birth_date::text, email, phone,
+		        email_verified, pending_email, totp_enabled, role
+		from users where id = $1`, userID,
+	).Scan(&u.ID, &u.Login, &u.FirstName, &u.LastName, &u.BirthDate, &u.Email, &u.Phone,
+		&u.EmailVerified, &u.PendingEmail, &u.TwoFactorEnabled, &u.Role)
+	return u, err
 }
 
-func UserMe(db *sql.DB) http.HandlerFunc {
+// UserMe returns the profile of the signed-in user, or deletes the account
+// on DELETE.
+func UserMe(db *sql.DB, posts proto.PostsServiceClient, userEvents eventSink, guard *lockout.Guard) http.HandlerFunc {
 	return AuthMiddleware(func(w http.ResponseWriter, r *http.Request, userID string) {
-		var u userProfile
-		err := db.QueryRow(
-			`select id, login, first_name, last_name, birth_date::text, email, phone
-			from users where id = $1`, userID,
-			).Scan(&u.ID, &u.Login, &u.FirstName, &u.LastName, &u.BirthDate, &u.Email, &u.Phone)
-		
+		if r.Method == http.MethodDelete {
+			deleteAccount(w, r, userID, db, posts, userEvents, guard)
+			return
+		}
+
+		u, err := loadProfile(r.Context(), db, userID)
 		if err != nil {
 			http.Error(w, "not found", http.StatusNotFound)
 			return
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"main-service/internal/auth"
+	"main-service/internal/lockout"
+)
+
+const recoveryCodeCount = 10
+
+type totpEnrollResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+	// QRPNG is the otpauth URI as a PNG QR code, base64-encoded.
+	QRPNG []byte `json:"qr_png"`
+}
+
+type totpConfirmRequest struct {
+	Code string `json:"code"`
+}
+
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type totpDisableRequest struct {
+	Password     string `json:"password"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// UserMe2FAEnroll generates a new TOTP secret. It takes effect only once
+// confirmed with a code from the authenticator.
+func UserMe2FAEnroll(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(func(w http.ResponseWriter, r *http.Request, userID string) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var login string
+		var enabled bool
+		err := db.QueryRowContext(r.Context(),
+			`select login, totp_enabled from users where id = $1`, userID).Scan(&login, &enabled)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if enabled {
+			http.Error(w, "two-factor authentication already enabled", http.StatusConflict)
+			return
+		}
+
+		enrollment, err := auth.NewTOTP(login)
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if _, err := db.ExecContext(r.Context(),
+			`update users set totp_secret = $1, totp_last_step = null where id = $2`,
+			enrollment.Secret, userID); err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, totpEnrollResponse{
+			Secret:     enrollment.Secret,
+			OTPAuthURI: enrollment.URI,
+			QRPNG:      enrollment.QRPNG,
+		})
+	})
+}
+
+// UserMe2FAConfirm enables two-factor authentication once the user proves
+// the authenticator works, and returns recovery codes, shown only once.
+func UserMe2FAConfirm(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(func(w http.ResponseWriter, r *http.Request, userID string) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req totpConfirmRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		tx, err := db.BeginTx(r.Context(), nil)
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		var secret sql.NullString
+		var enabled bool
+		err = tx.QueryRowContext(r.Context(),
+			`select totp_secret, totp_enabled from users where id = $1 for update`, userID,
+		).Scan(&secret, &enabled)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if enabled {
+			http.Error(w, "two-factor authentication already enabled", http.StatusConflict)
+			return
+		}
+		if !secret.Valid {
+			http.Error(w, "enroll first", http.StatusBadRequest)
+			return
+		}
+		step, ok := auth.ValidateTOTP(secret.String, req.Code, time.Now())
+		if !ok {
+			http.Error(w, "invalid code", http.StatusBadRequest)
+			return
+		}
+
+		codes, hashes, err := auth.NewRecoveryCodes(recoveryCodeCount)
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if _, err := tx.ExecContext(r.Context(),
+			`update users set totp_enabled = true, totp_last_step = $1 where id = $2`, step, userID); err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if _, err := tx.ExecContext(r.Context(), `delete from user_recovery_codes where user_id = $1`, userID); err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		for _, hash := range hashes {
+			if _, err := tx.ExecContext(r.Context(),
+				`insert into user_recovery_codes (user_id, code_hash) values ($1, $2)`, userID, hash); err != nil {
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, recoveryCodesResponse{RecoveryCodes: codes})
+	})
+}
+
+// UserMe2FADisable turns two-factor authentication off. A stolen session
+// is not enough: the password and a TOTP or recovery code are required, and
+// wrong ones count towards the login lockout.
+func UserMe2FADisable(db *sql.DB, guard *lockout.Guard) http.HandlerFunc {
+	return AuthMiddleware(func(w http.ResponseWriter, r *http.Request, userID string) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req totpDisableRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		tx, err := db.BeginTx(r.Context(), nil)
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		var id int64
+		var login string
+		var passHash []byte
+		var enabled bool
+		err = tx.QueryRowContext(r.Context(),
+			`select id, login, pass_hash, totp_enabled from users where id = $1 for update`, userID,
+		).Scan(&id, &login, &passHash, &enabled)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if !enabled {
+			http.Error(w, "two-factor authentication not enabled", http.StatusConflict)
+			return
+		}
+		attempt, ok := beginLogin(w, r, guard, login)
+		if !ok {
+			return
+		}
+		if err := bcrypt.CompareHashAndPassword(passHash, []byte(req.Password)); err != nil {
+			loginFailed(r, guard, attempt, lockout.ReasonInvalidCredentials)
+			http.Error(w, "invalid credentials", http.StatusUnauthorized)
+			return
+		}
+		valid, err := verifySecondFactor(r.Context(), tx, id, req.Code, req.RecoveryCode)
+		if err != nil {
+			loginPassed(r, guard, attempt)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if !valid {
+			loginFailed(r, guard, attempt, lockout.ReasonInvalidCode)
+			http.Error(w, "invalid credentials", http.StatusUnauthorized)
+			return
+		}
+		loginPassed(r, guard, attempt)
+
+		if _, err := tx.ExecContext(r.Context(),
+			`update users set totp_enabled = false, totp_secret = null, totp_last_step = null where id = $1`, id); err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if _, err := tx.ExecContext(r.Context(), `delete from user_recovery_codes where user_id = $1`, id); err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"main-service/internal/lockout"
+	proto "posts-service/proto"
+)
+
+type deleteAccountRequest struct {
+	Password     string `json:"password"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+	// Posts is "delete" (the default) or "anonymize", which keeps the posts
+	// without an author.
+	Posts string `json:"posts"`
+}
+
+type userEvent struct {
+	EventType string    `json:"event_type"`
+	UserID    string    `json:"user_id"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// deleteAccount removes the user after checking their password, and their
+// second factor when enabled; wrong guesses count towards the login lockout.
+// The posts go first and the user_deleted event, on which stats-service
+// erases the user's events, is spooled before the account row goes, so a
+// failure at any step leaves the account in place to try again.
+func deleteAccount(w http.ResponseWriter, r *http.Request, userID string, db *sql.DB, posts proto.PostsServiceClient, userEvents eventSink, guard *lockout.Guard) {
+	var req deleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if req.Posts == "" {
+		req.Posts = "delete"
+	}
+	if req.Posts != "delete" && req.Posts != "anonymize" {
+		http.Error(w, "validation error", http.StatusBadRequest)
+		return
+	}
+
+	var id int64
+	var login string
+	var passHash []byte
+	var totpEnabled bool
+	err := db.QueryRowContext(r.Context(),
+		`select id, login, pass_hash, totp_enabled from users where id = $1`, userID,
+	).Scan(&id, &login, &passHash, &totpEnabled)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	attempt, ok := beginLogin(w, r, guard, login)
+	if !ok {
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword(passHash, []byte(req.Password)); err != nil {
+		loginFailed(r, guard, attempt, lockout.ReasonInvalidCredentials)
+		http.Error(w, "invalid credentials", http.StatusUnauthorized)
+		return
+	}
+	if totpEnabled {
+		valid, err := verifySecondFactor(r.Context(), db, id, req.Code, req.RecoveryCode)
+		if err != nil {
+			loginPassed(r, guard, attempt)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if !valid {
+			loginFailed(r, guard, attempt, lockout.ReasonInvalidCode)
+			http.Error(w, "invalid credentials", http.StatusUnauthorized)
+			return
+		}
+	}
+	loginPassed(r, guard, attempt)
+
+	if _, err := posts.DeleteUserPosts(r.Context(), &proto.DeleteUserPostsRequest{Anonymize: req.Posts == "anonymize"}); err != nil {
+		http.Error(w, "service error", http.StatusBadGateway)
+		return
+	}
+
+	data, err := json.Marshal(userEvent{EventType: "user_deleted", UserID: userID, Timestamp: time.Now().UTC()})
+	if err == nil {
+		err = userEvents.Publish([]byte(userID), data)
+	}
+	if err != nil {
+		log.Printf("publish user_deleted for user %s failed: %v", userID, err)
+		http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	if _, err := db.ExecContext(r.Context(), `delete from users where id = $1`, id); err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"common/identity"
+	"main-service/internal/dataexport"
+	proto "posts-service/proto"
+	statspb "stats-service/proto"
+)
+
+// exportTimeout bounds the assembly of an archive. Exports still pending
+// after it were lost, for instance to a restart, and count as failed.
+const exportTimeout = 10 * time.Minute
+
+const exportPostsPageSize = 100
+
+type dataExport struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	DownloadURL string     `json:"download_url"`
+}
+
+type exportPost struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+var exportIDPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// UserMeExport starts assembling a ZIP archive of the user's personal data:
+// the profile, their posts, the stats of those posts, and the views, likes
+// and flagged events stats-service holds about the user. The archive can be
+// downloaded from UserMeExportDownload for ttl. While an export is pending,
+// asking again returns it instead of starting another.
+func UserMeExport(db *sql.DB, posts proto.PostsServiceClient, stats statspb.StatsServiceClient, ttl time.Duration) http.HandlerFunc {
+	return AuthMiddleware(func(w http.ResponseWriter, r *http.Request, userID string) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if _, err := db.ExecContext(r.Context(), `delete from data_exports where expires_at < now()`); err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if _, err := db.ExecContext(r.Context(),
+			`update data_exports set status = 'failed', completed_at = now()
+			  where status = 'pending' and created_at < $1`,
+			time.Now().Add(-exportTimeout)); err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		// A unique index allows one pending export per user, so concurrent
+		// requests start a single one.
+		e, err := scanExport(db.QueryRowContext(r.Context(),
+			`insert into data_exports (user_id, expires_at) values ($1, $2)
+			 on conflict (user_id) where status = 'pending' do nothing
+			 returning id, status, created_at, completed_at, expires_at`,
+			userID, time.Now().Add(ttl)))
+		if err == nil {
+			go runExport(db, posts, stats, userID, e.ID)
+			respondJSON(w, http.StatusAccepted, e)
+			return
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		e, err = scanExport(db.QueryRowContext(r.Context(),
+			`select id, status, created_at, completed_at, expires_at from data_exports
+			  where user_id = $1 and status = 'pending'`, userID))
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		respondJSON(w, http.StatusAccepted, e)
+	})
+}
+
+// UserMeExportDownload serves /users/me/export/{id}: the archive once it is
+// ready, or the state of the export until then.
+func UserMeExportDownload(db *sql.DB) http.HandlerFunc {
+	return AuthMiddleware(func(w http.ResponseWriter, r *http.Request, userID string) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		id := strings.TrimPrefix(r.URL.Path, "/users/me/export/")
+		if !exportIDPattern.MatchString(id) {
+			http.NotFound(w, r)
+			return
+		}
+
+		var e dataExport
+		var archive []byte
+		err := db.QueryRowContext(r.Context(),
+			`select id, status, created_at, completed_at, expires_at, archive from data_exports
+			  where id = $1 and user_id = $2 and expires_at > now()`, id, userID,
+		).Scan(&e.ID, &e.Status, &e.CreatedAt, &e.CompletedAt, &e.ExpiresAt, &archive)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		e.DownloadURL = exportURL(e.ID)
+
+		switch e.Status {
+		case "ready":
+			w.Header().Set("Content-Type", "application/zip")
+			w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="personal-data-%s.zip"`, e.ID))
+			w.WriteHeader(http.StatusOK)
+			w.Write(archive)
+		case "pending":
+			w.Header().Set("Retry-After", "5")
+			respondJSON(w, http.StatusAccepted, e)
+		default:
+			http.Error(w, "export failed, start a new one", http.StatusGone)
+		}
+	})
+}
+
+func scanExport(row *sql.Row) (dataExport, error) {
+	var e dataExport
+	err := row.Scan(&e.ID, &e.Status, &e.CreatedAt, &e.CompletedAt, &e.ExpiresAt)
+	e.DownloadURL = exportURL(e.ID)
+	return e, err
+}
+
+func exportURL(id string) string {
+	return "/users/me/export/" + id
+}
+
+func runExport(db *sql.DB, posts proto.PostsServiceClient, stats statspb.StatsServiceClient, userID, exportID string) {
+	ctx, cancel := context.WithTimeout(identity.WithCaller(context.Background(), identity.Caller{UserID: userID}), exportTimeout)
+	defer cancel()
+
+	archive, err := buildExport(ctx, db, posts, stats, userID)
+	if err != nil {
+		log.Printf("data export %s of user %s failed: %v", exportID, userID, err)
+		// ctx may be what timed out.
+		failCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if _, err := db.ExecContext(failCtx,
+			`update data_exports set status = 'failed', completed_at = now() where id = $1`, exportID); err != nil {
+			log.Printf("mark data export %s failed: %v", exportID, err)
+		}
+		return
+	}
+	if _, err := db.ExecContext(ctx,
+		`update data_exports set status = 'ready', archive = $1, completed_at = now() where id = $2`,
+		archive, exportID); err != nil {
+		log.Printf("store data export %s failed: %v", exportID, err)
+	}
+}
+
+func buildExport(ctx context.Context, db *sql.DB, posts proto.PostsServiceClient, stats statspb.StatsServiceClient, userID string) ([]byte, error) {
+	profile, err := loadProfile(ctx, db, userID)
+	if err != nil {
+		return nil, fmt.Errorf("load profile: %w", err)
+	}
+
+	userPosts := []exportPost{}
+	for page := int32(1); ; page++ {
+		resp, err := posts.ListPosts(ctx, &proto.ListPostsRequest{UserId: userID, Page: page, PageSize: exportPostsPageSize})
+		if err != nil {
+			return nil, fmt.Errorf("list posts: %w", err)
+		}
+		for _, p := range resp.GetPosts() {
+			userPosts = append(userPosts, exportPost{
+				ID:        p.GetId(),
+				Title:     p.GetTitle(),
+				Content:   p.GetContent(),
+				CreatedAt: p.GetCreatedAt().AsTime(),
+				UpdatedAt: p.GetUpdatedAt().AsTime(),
+			})
+		}
+		if len(resp.GetPosts()) < exportPostsPageSize || len(userPosts) >= int(resp.GetTotal()) {
+			break
+		}
+	}
+
+	authorStats, err := stats.GetAuthorStats(ctx, &statspb.AuthorStatsRequest{UserId: userID, Days: 365})
+	if err != nil {
+		return nil, fmt.Errorf("load stats: %w", err)
+	}
+
+	events, flagged, err := exportUserEvents(ctx, stats)
+	if err != nil {
+		return nil, fmt.Errorf("load events: %w", err)
+	}
+
+	var buf bytes.Buffer
+	err = dataexport.Write(&buf, userID, time.Now(), []dataexport.File{
+		{Name: "profile.json", Description: "account profile", Data: profile},
+		{Name: "posts.json", Description: "posts written by the user", Data: userPosts},
+		{Name: "stats.json", Description: "views and likes of the user's posts over the last year", Data: toAuthorStats(authorStats)},
+		{Name: "events.json", Description: "views and likes made by the user", Data: events},
+		{Name: "flagged_events.json", Description: "views and likes of the user held back as suspected bot traffic, with the client IP", Data: flagged},
+	})
+	return buf.Bytes(), err
+}
+
+type exportEvent struct {
+	Timestamp time.Time `json:"ts"`
+	EventType string    `json:"event_type"`
+	PostID    string    `json:"post_id"`
+}
+
+type exportFlaggedEvent struct {
+	exportEvent
+	IP     string `json:"ip"`
+	Reason string `json:"reason"`
+	Status string `json:"status"`
+}
+
+// exportUserEvents reads what stats-service holds about the user of ctx.
+func exportUserEvents(ctx context.Context, stats statspb.StatsServiceClient) ([]exportEvent, []exportFlaggedEvent, error) {
+	stream, err := stats.ExportUserEvents(ctx, &statspb.UserEventsRequest{})
+	if err != nil {
+		return nil, nil, err
+	}
+	events, flagged := []exportEvent{}, []exportFlaggedEvent{}
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return events, flagged, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, e := range chunk.GetEvents() {
+			events = append(events, exportEvent{Timestamp: e.GetTs().AsTime(), EventType: e.GetEventType(), PostID: e.GetPostId()})
+		}
+		for _, f := range chunk.GetFlagged() {
+			flagged = append(flagged, exportFlaggedEvent{
+				exportEvent: exportEvent{Timestamp: f.GetTs().AsTime(), EventType: f.GetEventType(), PostID: f.GetPostId()},
+				IP:          f.GetIp(),
+				Reason:      f.GetReason(),
+				Status:      f.GetStatus(),
+			})
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"main-service/internal/lockout"
+)
+
+type changePasswordRequest struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
+// UserMePassword changes the password of a signed-in user. Wrong old
+// passwords count towards the login lockout, so a stolen session cannot be
+// used to guess the password. Every other session ends; the caller gets a
+// new token to stay signed in.
+func UserMePassword(db *sql.DB, guard *lockout.Guard) http.HandlerFunc {
+	return AuthMiddleware(func(w http.ResponseWriter, r *http.Request, userID string) {
+		if r.Method != http.MethodPut {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req changePasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		if len(req.NewPassword) < 8 {
+			http.Error(w, "validation error", http.StatusBadRequest)
+			return
+		}
+
+		tx, err := db.BeginTx(r.Context(), nil)
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		var id int64
+		var login string
+		var passHash []byte
+		err = tx.QueryRowContext(r.Context(),
+			`select id, login, pass_hash from users where id = $1 for update`, userID,
+		).Scan(&id, &login, &passHash)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		attempt, ok := beginLogin(w, r, guard, login)
+		if !ok {
+			return
+		}
+		if err := bcrypt.CompareHashAndPassword(passHash, []byte(req.OldPassword)); err != nil {
+			loginFailed(r, guard, attempt, lockout.ReasonInvalidCredentials)
+			http.Error(w, "invalid credentials", http.StatusUnauthorized)
+			return
+		}
+		loginPassed(r, guard, attempt)
+
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		var sessionVersion int64
+		var role string
+		err = tx.QueryRowContext(r.Context(),
+			`update users set pass_hash = $1, session_version = session_version + 1 where id = $2
+			 returning session_version, role`,
+			hash, id).Scan(&sessionVersion, &role)
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if _, err := tx.ExecContext(r.Context(),
+			`update password_resets set used_at = now() where user_id = $1 and used_at is null`, id); err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		signed, err := issueSession(keySet(), id, sessionVersion, role)
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		respondJSON(w, http.StatusOK, loginResponse{Token: signed})
+	})
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	statspb "stats-service/proto"
+)
+
+type postStatsItem struct {
+	ID    string `json:"id"`
+	Views int64  `json:"views"`
+	Likes int64  `json:"likes"`
+}
+
+type dailyStatsItem struct {
+	Date  string `json:"date"`
+	Views int64  `json:"views"`
+	Likes int64  `json:"likes"`
+}
+
+type authorStatsResponse struct {
+	PostCount  int64            `json:"post_count"`
+	TotalViews int64            `json:"total_views"`
+	TotalLikes int64            `json:"total_likes"`
+	Reach      int64            `json:"reach"`
+	Posts      []postStatsItem  `json:"posts"`
+	BestPosts  []postStatsItem  `json:"best_posts"`
+	Daily      []dailyStatsItem `json:"daily"`
+}
+
+func UserMeStats(client statspb.StatsServiceClient) http.HandlerFunc {
+	return AuthMiddleware(func(w http.ResponseWriter, r *http.Request, userID string) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		days := 30
+		if v := r.URL.Query().Get("days"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > 365 {
+				http.Error(w, "invalid days parameter", http.StatusBadRequest)
+				return
+			}
+			days = n
+		}
+
+		resp, err := client.GetAuthorStats(r.Context(), &statspb.AuthorStatsRequest{UserId: userID, Days: int32(days)})
+		if err != nil {
+			http.Error(w, "service error", http.StatusBadGateway)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, toAuthorStats(resp))
+	})
+}
+
+func toAuthorStats(resp *statspb.AuthorStatsResponse) authorStatsResponse {
+	out := authorStatsResponse{
+		PostCount:  resp.GetPostCount(),
+		TotalViews: resp.GetTotalViews(),
+		TotalLikes: resp.GetTotalLikes(),
+		Reach:      resp.GetReach(),
+		Posts:      toPostStatsItems(resp.GetPosts()),
+		BestPosts:  toPostStatsItems(resp.GetBestPosts()),
+		Daily:      []dailyStatsItem{},
+	}
+	for _, d := range resp.GetDaily() {
+		out.Daily = append(out.Daily, dailyStatsItem{Date: d.GetDate(), Views: d.GetViews(), Likes: d.GetLikes()})
+	}
+	return out
+}
+
+func toPostStatsItems(posts []*statspb.AuthorPostStats) []postStatsItem {
+	items := []postStatsItem{}
+	for _, p := range posts {
+		items = append(items, postStatsItem{ID: p.GetPostId(), Views: p.GetViews(), Likes: p.GetLikes()})
+	}
+	return items
+}
@@ -3,7 +3,17 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"main-service/internal/auth"
+	"main-service/internal/contact"
+	"main-service/internal/mail"
 )
 
 type updateMeRequest struct {
@@ -14,7 +24,11 @@ type updateMeRequest struct {
 	Phone     *string `json:"phone"`
 }
 
-func UserMeUpdate(db *sql.DB) http.HandlerFunc {
+// UserMeUpdate replaces the profile. A new email does not replace the
+// current one right away: it is kept as pending_email and a link, valid for
+// ttl, is mailed to it. Saving the profile again mails a new link only once
+// the previous one was used or expired. An empty email removes it.
+func UserMeUpdate(db *sql.DB, mailer mail.Mailer, verifyURL string, ttl time.Duration) http.HandlerFunc {
 	return AuthMiddleware(func(w http.ResponseWriter, r *http.Request, userID string) {
 		if r.Method != http.MethodPut {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -27,21 +41,136 @@ func UserMeUpdate(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		_, err := db.Exec(
+		var phone *string
+		if req.Phone != nil && strings.TrimSpace(*req.Phone) != "" {
+			p, err := contact.Phone(*req.Phone)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			phone = &p
+		}
+		var email string
+		if req.Email != nil && strings.TrimSpace(*req.Email) != "" {
+			e, err := contact.Email(*req.Email)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			email = e
+		}
+
+		tx, err := db.BeginTx(r.Context(), nil)
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		var current, pending sql.NullString
+		var verified bool
+		err = tx.QueryRowContext(r.Context(),
+			`select email, email_verified, pending_email from users where id = $1 for update`, userID,
+		).Scan(&current, &verified, &pending)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		_, err = tx.ExecContext(r.Context(),
 			`update users
 			   set first_name = $1,
 			       last_name  = $2,
 			       birth_date = $3::date,
-			       email      = $4,
-			       phone      = $5
-			 where id = $6`,
-			req.FirstName, req.LastName, req.BirthDate, req.Email, req.Phone, userID,
+			       phone      = $4
+			 where id = $5`,
+			req.FirstName, req.LastName, req.BirthDate, phone, userID,
 		)
 		if err != nil {
 			http.Error(w, "internal error", http.StatusInternalServerError)
 			return
 		}
 
+		// The address already waits for confirmation; saving the rest of
+		// the profile does not mail it again while its link still works.
+		var waiting bool
+		if pending.Valid && strings.EqualFold(pending.String, email) {
+			err = tx.QueryRowContext(r.Context(),
+				`select exists(select 1 from email_verifications
+				                where user_id = $1 and lower(email) = lower($2)
+				                  and used_at is null and expires_at > now())`,
+				userID, email).Scan(&waiting)
+			if err != nil {
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		var verifyToken string
+		switch {
+		case email == "":
+			_, err = tx.ExecContext(r.Context(),
+				`update users set email = null, email_verified = false, pending_email = null where id = $1`, userID)
+		case verified && strings.EqualFold(current.String, email):
+			// Asking for the verified email again cancels a pending change.
+			_, err = tx.ExecContext(r.Context(), `update users set pending_email = null where id = $1`, userID)
+		case waiting:
+		default:
+			var taken bool
+			err = tx.QueryRowContext(r.Context(),
+				`select exists(select 1 from users where email_verified and lower(email) = lower($1) and id <> $2)`,
+				email, userID).Scan(&taken)
+			if err == nil && taken {
+				http.Error(w, "email already in use", http.StatusConflict)
+				return
+			}
+			if err == nil {
+				verifyToken, err = startEmailVerification(tx, r, userID, email, ttl)
+			}
+		}
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		if verifyToken != "" {
+			link := verifyURL + "?token=" + url.QueryEscape(verifyToken)
+			err := mailer.Send(r.Context(), mail.Message{
+				To:      email,
+				Subject: "Confirm your email",
+				Body: fmt.Sprintf("Follow this link within %s to confirm this address for your account:\n%s\n\n"+
+					"If you did not ask for it, ignore this message.\n", ttl, link),
+			})
+			if err != nil {
+				log.Printf("send verification mail to user %s failed: %v", userID, err)
+			}
+		}
+
 		w.WriteHeader(http.StatusNoContent)
 	})
 }
+
+func startEmailVerification(tx *sql.Tx, r *http.Request, userID, email string, ttl time.Duration) (string, error) {
+	token, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	if _, err := tx.ExecContext(r.Context(),
+		`update users set pending_email = $1 where id = $2`, email, userID); err != nil {
+		return "", err
+	}
+	if _, err := tx.ExecContext(r.Context(),
+		`insert into email_verifications (token_hash, user_id, email, expires_at) values ($1, $2, $3, $4)`,
+		hash, userID, email, time.Now().Add(ttl)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
@@ -0,0 +1,40 @@
+package lockout
+
+import (
+	"context"
+	"time"
+)
+
+// Limiter caps how often an action is requested per login and per client
+// IP within a window. It keeps its counters in a Store under keys of its
+// own, so it can share the store of a Guard.
+type Limiter struct {
+	store  Store
+	action string
+	login  int
+	ip     int
+	window time.Duration
+	now    func() time.Time
+}
+
+// NewLimiter allows perLogin requests for one login and perIP requests from
+// one address; the counts start over after window without requests.
+func NewLimiter(store Store, action string, perLogin, perIP int, window time.Duration) *Limiter {
+	return &Limiter{store: store, action: action, login: perLogin, ip: perIP, window: window, now: time.Now}
+}
+
+// Allow counts a request and reports whether both its login and its IP are
+// still within their limits. The request is counted either way, so retrying
+// early does not help.
+func (l *Limiter) Allow(ctx context.Context, login, ip string) (bool, error) {
+	now := l.now()
+	allowed := true
+	for key, limit := range map[string]int{loginKey(login): l.login, ipKey(ip): l.ip} {
+		n, _, err := l.store.Attempt(ctx, l.action+"|"+key, now, Policy{Free: limit, ResetAfter: l.window})
+		if err != nil {
+			return false, err
+		}
+		allowed = allowed && n <= limit
+	}
+	return allowed, nil
+}
@@ -0,0 +1,192 @@
+// Package lockout slows down password guessing. Login attempts are counted
+// per login and per client IP before the password is checked, and taken
+// back when it turns out right; past a number of free attempts each further
+// failure blocks the key for twice as long as the previous one, up to a
+// maximum that acts as a temporary lockout. Every failure is also recorded
+// for auditing.
+package lockout
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+)
+
+// Counter is the failure state of one key.
+type Counter struct {
+	Failures     int
+	LastFailure  time.Time
+	BlockedUntil time.Time
+}
+
+// FailedAttempt is the audit record of a failed login.
+type FailedAttempt struct {
+	Login  string
+	IP     string
+	Reason string
+	At     time.Time
+}
+
+const (
+	ReasonInvalidCredentials = "invalid_credentials"
+	ReasonInvalidCode        = "invalid_code"
+	ReasonBlocked            = "blocked"
+)
+
+type Store interface {
+	// Attempt counts an attempt at at, unless key is blocked then, in one
+	// atomic step: concurrent attempts cannot all slip past the check. The
+	// count starts over from one when the previous attempt is older than
+	// p.ResetAfter, and the key is blocked for p.Delay of the new count. It
+	// returns the count and, when the attempt was refused, until when the
+	// key is blocked.
+	Attempt(ctx context.Context, key string, at time.Time, p Policy) (failures int, blockedUntil time.Time, err error)
+	// Release takes back the attempt made at at, lifting the block it set.
+	Release(ctx context.Context, key string, at time.Time) error
+	Reset(ctx context.Context, key string) error
+	Audit(ctx context.Context, a FailedAttempt) error
+	// FailedAttempts returns the latest failures for login, newest first.
+	FailedAttempts(ctx context.Context, login string, limit int) ([]FailedAttempt, error)
+	// Prune drops counters without attempts since countersBefore and audit
+	// records older than auditBefore.
+	Prune(ctx context.Context, countersBefore, auditBefore time.Time) error
+}
+
+type Policy struct {
+	// Free failures are not delayed.
+	Free int
+	// The first delayed failure blocks for Base, each next one for twice
+	// as long, up to Max.
+	Base time.Duration
+	Max  time.Duration
+	// ResetAfter without failures forgets the earlier ones.
+	ResetAfter time.Duration
+}
+
+var (
+	DefaultLoginPolicy = Policy{Free: 5, Base: time.Second, Max: 15 * time.Minute, ResetAfter: 24 * time.Hour}
+	// Many users can share an address, so an IP gets more free attempts.
+	DefaultIPPolicy = Policy{Free: 50, Base: time.Second, Max: 15 * time.Minute, ResetAfter: time.Hour}
+)
+
+// Delay returns how long the key is blocked after its failures-th failure.
+func (p Policy) Delay(failures int) time.Duration {
+	if failures <= p.Free {
+		return 0
+	}
+	d := p.Base
+	for i := p.Free + 1; i < failures; i++ {
+		d *= 2
+		if d >= p.Max {
+			return p.Max
+		}
+	}
+	return min(d, p.Max)
+}
+
+type Guard struct {
+	store Store
+	login Policy
+	ip    Policy
+	now   func() time.Time
+}
+
+func NewGuard(store Store, login, ip Policy) *Guard {
+	return &Guard{store: store, login: login, ip: ip, now: time.Now}
+}
+
+// SetClockForTest replaces the clock in tests.
+func (g *Guard) SetClockForTest(now func() time.Time) {
+	g.now = now
+}
+
+func loginKey(login string) string {
+	return "login:" + strings.ToLower(strings.TrimSpace(login))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Attempt is a login attempt counted by Begin.
+type Attempt struct {
+	Login string
+	IP    string
+	At    time.Time
+}
+
+// Begin counts an attempt to log in as login from ip before the credentials
+// are checked. It returns how long the caller has to wait when login or ip
+// is blocked, in which case nothing is counted. Otherwise the attempt counts
+// as a failure until Pass or Succeed takes it back.
+func (g *Guard) Begin(ctx context.Context, login, ip string) (Attempt, time.Duration, error) {
+	// Postgres keeps microseconds; Release matches the attempt by time.
+	a := Attempt{Login: strings.ToLower(strings.TrimSpace(login)), IP: ip, At: g.now().Truncate(time.Microsecond)}
+
+	_, until, err := g.store.Attempt(ctx, ipKey(ip), a.At, g.ip)
+	if err != nil || until.After(a.At) {
+		return a, until.Sub(a.At), err
+	}
+	_, until, err = g.store.Attempt(ctx, loginKey(a.Login), a.At, g.login)
+	if err == nil && until.After(a.At) {
+		err = g.store.Release(ctx, ipKey(ip), a.At)
+		return a, until.Sub(a.At), err
+	}
+	return a, 0, err
+}
+
+// Fail records the failed attempt for auditing; Begin already counted it.
+func (g *Guard) Fail(ctx context.Context, a Attempt, reason string) error {
+	return g.store.Audit(ctx, FailedAttempt{Login: a.Login, IP: a.IP, Reason: reason, At: a.At})
+}
+
+// Pass takes back an attempt that turned out not to be a failure, such as a
+// right password still waiting for its second factor.
+func (g *Guard) Pass(ctx context.Context, a Attempt) error {
+	if err := g.store.Release(ctx, loginKey(a.Login), a.At); err != nil {
+		return err
+	}
+	return g.store.Release(ctx, ipKey(a.IP), a.At)
+}
+
+// Succeed forgets the failures of the login. Those of the IP stay, so that
+// one valid account does not let an address keep guessing others.
+func (g *Guard) Succeed(ctx context.Context, a Attempt) error {
+	if err := g.store.Reset(ctx, loginKey(a.Login)); err != nil {
+		return err
+	}
+	return g.store.Release(ctx, ipKey(a.IP), a.At)
+}
+
+// Unlock lifts the block of login, for administrators.
+func (g *Guard) Unlock(ctx context.Context, login string) error {
+	return g.store.Reset(ctx, loginKey(login))
+}
+
+func (g *Guard) FailedAttempts(ctx context.Context, login string, limit int) ([]FailedAttempt, error) {
+	return g.store.FailedAttempts(ctx, strings.ToLower(strings.TrimSpace(login)), limit)
+}
+
+// Prune drops counters both policies have forgotten, including those of
+// logins that do not exist, and audit records older than auditRetention.
+func (g *Guard) Prune(ctx context.Context, auditRetention time.Duration) error {
+	now := g.now()
+	return g.store.Prune(ctx, now.Add(-max(g.login.ResetAfter, g.ip.ResetAfter)), now.Add(-auditRetention))
+}
+
+// RunPrune calls Prune every interval until ctx is cancelled.
+func (g *Guard) RunPrune(ctx context.Context, interval, auditRetention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := g.Prune(ctx, auditRetention); err != nil {
+			log.Printf("prune login attempts failed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package lockout
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// auditCapacity bounds the failed attempts a Memory store remembers.
+const auditCapacity = 10000
+
+// Memory keeps counters in the process. It suits a single instance and
+// tests; counters are lost on restart.
+type Memory struct {
+	mu       sync.Mutex
+	counters map[string]Counter
+	audit    []FailedAttempt
+}
+
+func NewMemory() *Memory {
+	return &Memory{counters: make(map[string]Counter)}
+}
+
+func (m *Memory) Attempt(_ context.Context, key string, at time.Time, p Policy) (int, time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c := m.counters[key]
+	if c.BlockedUntil.After(at) {
+		return c.Failures, c.BlockedUntil, nil
+	}
+	if c.LastFailure.Before(at.Add(-p.ResetAfter)) {
+		c.Failures = 0
+	}
+	c.Failures++
+	c.LastFailure = at
+	c.BlockedUntil = time.Time{}
+	if d := p.Delay(c.Failures); d > 0 {
+		c.BlockedUntil = at.Add(d)
+	}
+	m.counters[key] = c
+	return c.Failures, time.Time{}, nil
+}
+
+func (m *Memory) Release(_ context.Context, key string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.counters[key]
+	if !ok {
+		return nil
+	}
+	c.Failures = max(c.Failures-1, 0)
+	if c.LastFailure.Equal(at) {
+		c.BlockedUntil = time.Time{}
+	}
+	m.counters[key] = c
+	return nil
+}
+
+func (m *Memory) Reset(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.counters, key)
+	return nil
+}
+
+func (m *Memory) Audit(_ context.Context, a FailedAttempt) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.audit) == auditCapacity {
+		m.audit = m.audit[1:]
+	}
+	m.audit = append(m.audit, a)
+	return nil
+}
+
+func (m *Memory) FailedAttempts(_ context.Context, login string, limit int) ([]FailedAttempt, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []FailedAttempt
+	for i := len(m.audit) - 1; i >= 0 && len(out) < limit; i-- {
+		if m.audit[i].Login == login {
+			out = append(out, m.audit[i])
+		}
+	}
+	return out, nil
+}
+
+func (m *Memory) Prune(_ context.Context, countersBefore, auditBefore time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, c := range m.counters {
+		if c.LastFailure.Before(countersBefore) {
+			delete(m.counters, key)
+		}
+	}
+	m.audit = slices.DeleteFunc(m.audit, func(a FailedAttempt) bool { return a.At.Before(auditBefore) })
+	return nil
+}
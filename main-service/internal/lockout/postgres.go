@@ -0,0 +1,99 @@
+package lockout
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Postgres shares counters between instances of main-service; see
+// migrations/005_login_attempts.sql.
+type Postgres struct {
+	db *sql.DB
+}
+
+func NewPostgres(db *sql.DB) *Postgres {
+	return &Postgres{db: db}
+}
+
+// Attempt counts and blocks in a single upsert, which Postgres serialises
+// per key. The block duration mirrors Policy.Delay.
+func (p *Postgres) Attempt(ctx context.Context, key string, at time.Time, pol Policy) (int, time.Time, error) {
+	var n int
+	var blocked sql.NullTime
+	err := p.db.QueryRowContext(ctx,
+		`with a as (select $2::timestamptz as at, $3::timestamptz as reset_before,
+		                   $4::int as free, $5::float8 as base, $6::float8 as max_secs)
+		 insert into login_attempt_counters as c (key, failures, last_failure, blocked_until)
+		 select $1, 1, a.at, case when 1 > a.free then a.at + make_interval(secs => least(a.base, a.max_secs)) end from a
+		 on conflict (key) do update
+		   set failures = case when c.last_failure < $3 then 1 else c.failures + 1 end,
+		       last_failure = $2,
+		       blocked_until = case
+		         when (case when c.last_failure < $3 then 1 else c.failures + 1 end) > $4
+		         then $2::timestamptz + make_interval(secs => least(
+		           $5 * power(2, (case when c.last_failure < $3 then 1 else c.failures + 1 end) - $4 - 1), $6))
+		       end
+		   where c.blocked_until is null or c.blocked_until <= $2
+		 returning failures`,
+		key, at, at.Add(-pol.ResetAfter), pol.Free, pol.Base.Seconds(), pol.Max.Seconds(),
+	).Scan(&n)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return n, time.Time{}, err
+	}
+	// The key is blocked, so nothing was counted.
+	err = p.db.QueryRowContext(ctx,
+		`select failures, blocked_until from login_attempt_counters where key = $1`, key,
+	).Scan(&n, &blocked)
+	return n, blocked.Time, err
+}
+
+func (p *Postgres) Release(ctx context.Context, key string, at time.Time) error {
+	_, err := p.db.ExecContext(ctx,
+		`update login_attempt_counters
+		    set failures = greatest(failures - 1, 0),
+		        blocked_until = case when last_failure = $2 then null else blocked_until end
+		  where key = $1`, key, at)
+	return err
+}
+
+func (p *Postgres) Reset(ctx context.Context, key string) error {
+	_, err := p.db.ExecContext(ctx, `delete from login_attempt_counters where key = $1`, key)
+	return err
+}
+
+func (p *Postgres) Audit(ctx context.Context, a FailedAttempt) error {
+	_, err := p.db.ExecContext(ctx,
+		`insert into failed_logins (login, ip, reason, created_at) values ($1, $2, $3, $4)`,
+		a.Login, a.IP, a.Reason, a.At)
+	return err
+}
+
+func (p *Postgres) FailedAttempts(ctx context.Context, login string, limit int) ([]FailedAttempt, error) {
+	rows, err := p.db.QueryContext(ctx,
+		`select login, ip, reason, created_at from failed_logins
+		  where login = $1 order by created_at desc limit $2`, login, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []FailedAttempt
+	for rows.Next() {
+		var a FailedAttempt
+		if err := rows.Scan(&a.Login, &a.IP, &a.Reason, &a.At); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+func (p *Postgres) Prune(ctx context.Context, countersBefore, auditBefore time.Time) error {
+	if _, err := p.db.ExecContext(ctx,
+		`delete from login_attempt_counters where last_failure < $1`, countersBefore); err != nil {
+		return err
+	}
+	_, err := p.db.ExecContext(ctx, `delete from failed_logins where created_at < $1`, auditBefore)
+	return err
+}
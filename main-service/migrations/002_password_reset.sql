@@ -0,0 +1,11 @@
+alter table users add column if not exists session_version bigint not null default 0;
+
+create table if not exists password_resets (
+  token_hash  bytea primary key,
+  user_id     bigint not null references users(id) on delete cascade,
+  created_at  timestamptz not null default now(),
+  expires_at  timestamptz not null,
+  used_at     timestamptz
+);
+
+create index if not exists password_resets_user_id_idx on password_resets (user_id);
@@ -0,0 +1,29 @@
+alter table users add column if not exists pending_email text;
+
+-- Emails saved before verification existed stay usable, so they are marked
+-- verified once, when the column is added. If several accounts share an
+-- address, only the oldest one keeps it.
+do $$
+begin
+  if not exists (select 1 from information_schema.columns
+                  where table_name = 'users' and column_name = 'email_verified') then
+    alter table users add column email_verified boolean not null default false;
+    update users u set email_verified = true
+     where u.email is not null
+       and not exists (select 1 from users o where lower(o.email) = lower(u.email) and o.id < u.id);
+  end if;
+end $$;
+
+-- A verified email belongs to one account only.
+create unique index if not exists users_verified_email_idx on users (lower(email)) where email_verified;
+
+create table if not exists email_verifications (
+  token_hash  bytea primary key,
+  user_id     bigint not null references users(id) on delete cascade,
+  email       text not null,
+  created_at  timestamptz not null default now(),
+  expires_at  timestamptz not null,
+  used_at     timestamptz
+);
+
+create index if not exists email_verifications_user_id_idx on email_verifications (user_id);
@@ -0,0 +1,11 @@
+alter table users add column if not exists totp_secret text;
+alter table users add column if not exists totp_enabled boolean not null default false;
+-- Last accepted time step, so a code cannot be used twice.
+alter table users add column if not exists totp_last_step bigint;
+
+create table if not exists user_recovery_codes (
+  user_id    bigint not null references users(id) on delete cascade,
+  code_hash  bytea not null,
+  used_at    timestamptz,
+  primary key (user_id, code_hash)
+);
@@ -0,0 +1,16 @@
+create table if not exists login_attempt_counters (
+  key            text primary key,
+  failures       integer not null,
+  last_failure   timestamptz not null,
+  blocked_until  timestamptz
+);
+
+create table if not exists failed_logins (
+  id          bigserial primary key,
+  login       text not null,
+  ip          text not null,
+  reason      text not null,
+  created_at  timestamptz not null default now()
+);
+
+create index if not exists failed_logins_login_idx on failed_logins (login, created_at desc);
@@ -0,0 +1,11 @@
+create table if not exists data_exports (
+  id            uuid primary key default gen_random_uuid(),
+  user_id       bigint not null references users(id) on delete cascade,
+  status        text not null default 'pending',
+  archive       bytea,
+  created_at    timestamptz not null default now(),
+  completed_at  timestamptz,
+  expires_at    timestamptz not null
+);
+
+create index if not exists data_exports_user_idx on data_exports (user_id, created_at desc);
@@ -0,0 +1,15 @@
+alter table users add column if not exists role text not null default 'user'
+  check (role in ('user', 'moderator', 'admin'));
+-- Accounts older than the column keep a null creation time rather than the
+-- time of the migration, so they do not count as new sign-ups.
+alter table users add column if not exists created_at timestamptz;
+alter table users alter column created_at set default now();
+alter table users add column if not exists suspended_at timestamptz;
+alter table users add column if not exists suspended_reason text;
+-- Who suspended the account; moderators cannot lift a suspension imposed by
+-- an administrator. The role stays when the suspender's account is gone.
+alter table users add column if not exists suspended_by bigint references users(id) on delete set null;
+alter table users add column if not exists suspended_by_role text
+  check (suspended_by_role in ('moderator', 'admin'));
+
+create index if not exists users_role_idx on users (role) where role <> 'user';
@@ -0,0 +1,3 @@
+-- Old counters and audit records are pruned periodically.
+create index if not exists login_attempt_counters_last_failure_idx on login_attempt_counters (last_failure);
+create index if not exists failed_logins_created_at_idx on failed_logins (created_at);
@@ -0,0 +1,9 @@
+-- At most one pending export per user. Older duplicates left by concurrent
+-- requests count as failed.
+update data_exports e set status = 'failed', completed_at = now()
+ where status = 'pending'
+   and exists (select 1 from data_exports n
+                where n.user_id = e.user_id and n.status = 'pending'
+                  and (n.created_at, n.id) > (e.created_at, e.id));
+
+create unique index if not exists data_exports_pending_idx on data_exports (user_id) where status = 'pending';
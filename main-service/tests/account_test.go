@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"main-service/internal/handlers"
+)
+
+func accountTestToken(t *testing.T) string {
+	t.Helper()
+	t.Setenv("JWT_SECRET", "test-secret")
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "42",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte("test-secret"))
+	return token
+}
+
+func TestUserMePasswordValidation(t *testing.T) {
+	token := accountTestToken(t)
+	h := handlers.UserMePassword(nil, nil)
+
+	for _, tc := range []struct {
+		method, body string
+		want         int
+	}{
+		{http.MethodPost, `{"old_password":"old-password","new_password":"new-password"}`, http.StatusMethodNotAllowed},
+		{http.MethodPut, `{"old_password":"old-password","new_password":"short"}`, http.StatusBadRequest},
+		{http.MethodPut, `not json`, http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(tc.method, "/users/me/password", strings.NewReader(tc.body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		if rec.Code != tc.want {
+			t.Fatalf("%s %s: expected %d, got %d", tc.method, tc.body, tc.want, rec.Code)
+		}
+	}
+}
+
+func TestUserMeDeleteValidation(t *testing.T) {
+	token := accountTestToken(t)
+	posts := &e2ePostsClient{}
+	h := handlers.UserMe(nil, posts, nil, nil)
+
+	req := httptest.NewRequest(http.MethodDelete, "/users/me", strings.NewReader(`{"password":"secret123","posts":"archive"}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	h(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected an unknown posts mode to be rejected, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/users/me", strings.NewReader(`{}`))
+	rec = httptest.NewRecorder()
+	h(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected deletion without a session to be rejected, got %d", rec.Code)
+	}
+}
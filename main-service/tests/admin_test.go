@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"common/identity"
+	"main-service/internal/handlers"
+	"main-service/internal/lockout"
+	proto "posts-service/proto"
+)
+
+func roleToken(t *testing.T, userID, role string) string {
+	t.Helper()
+	t.Setenv("JWT_SECRET", "test-secret")
+	claims := jwt.MapClaims{"sub": userID, "exp": time.Now().Add(time.Hour).Unix()}
+	if role != "" {
+		claims["role"] = role
+	}
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("test-secret"))
+	return token
+}
+
+func TestRequireRole(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	var role string
+	h := handlers.RequireRole(handlers.RoleModerator, func(w http.ResponseWriter, r *http.Request, _ string) {
+		role = identity.Role(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	for _, tc := range []struct {
+		role string
+		want int
+	}{
+		{"", http.StatusForbidden},
+		{"user", http.StatusForbidden},
+		{"superuser", http.StatusForbidden},
+		{"moderator", http.StatusNoContent},
+		{"admin", http.StatusNoContent},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/admin/users", nil)
+		req.Header.Set("Authorization", "Bearer "+roleToken(t, "7", tc.role))
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		if rec.Code != tc.want {
+			t.Fatalf("role %q: expected %d, got %d", tc.role, tc.want, rec.Code)
+		}
+		if rec.Code == http.StatusNoContent && role != tc.role {
+			t.Fatalf("expected role %q in the request context, got %q", tc.role, role)
+		}
+	}
+}
+
+func TestAdminPostsDelete(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	posts := &e2ePostsClient{posts: map[string]*proto.Post{"p1": {Id: "p1", OwnerId: "3"}}}
+	h := handlers.AdminPosts(posts)
+	call := func(method, path, role string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+roleToken(t, "7", role))
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec.Code
+	}
+
+	if code := call(http.MethodDelete, "/admin/posts/p1", "user"); code != http.StatusForbidden {
+		t.Fatalf("expected plain users to be refused, got %d", code)
+	}
+	if code := call(http.MethodGet, "/admin/posts/p1", "moderator"); code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", code)
+	}
+	if code := call(http.MethodDelete, "/admin/posts/p1", "moderator"); code != http.StatusNoContent {
+		t.Fatalf("expected the post to be deleted, got %d", code)
+	}
+	if _, ok := posts.posts["p1"]; ok {
+		t.Fatal("expected posts-service to delete the post")
+	}
+	if code := call(http.MethodDelete, "/admin/posts/p1", "admin"); code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a missing post, got %d", code)
+	}
+}
+
+func TestAdminUsersValidation(t *testing.T) {
+	token := roleToken(t, "7", "admin")
+	users := handlers.AdminUsers(nil)
+	user := handlers.AdminUser(nil, lockout.NewGuard(lockout.NewMemory(), lockout.DefaultLoginPolicy, lockout.DefaultIPPolicy))
+
+	for _, tc := range []struct {
+		h    http.HandlerFunc
+		path string
+		want int
+	}{
+		{users, "/admin/users?page_size=1000", http.StatusBadRequest},
+		{users, "/admin/users?page=0", http.StatusBadRequest},
+		{users, "/admin/users?role=root", http.StatusBadRequest},
+		{users, "/admin/users?suspended=maybe", http.StatusBadRequest},
+		{user, "/admin/users/abc", http.StatusNotFound},
+		{user, "/admin/users/1/suspend/now", http.StatusNotFound},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		tc.h(rec, req)
+		if rec.Code != tc.want {
+			t.Fatalf("%s: expected %d, got %d", tc.path, tc.want, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package tests
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"main-service/internal/auth"
+	"main-service/internal/handlers"
+)
+
+func writeKey(t *testing.T, dir, kid string, key any) {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+}
+
+func testClaims() jwt.MapClaims {
+	return jwt.MapClaims{"sub": "42", "exp": time.Now().Add(time.Hour).Unix()}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key: %v", err)
+	}
+	writeKey(t, dir, "2026-01", rsaKey)
+
+	keys, err := auth.Load(auth.Config{Dir: dir})
+	if err != nil {
+		t.Fatalf("load keys: %v", err)
+	}
+	oldToken, err := keys.Sign(testClaims())
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	writeKey(t, dir, "2026-02", edKey)
+	if err := keys.Reload(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	unpublished, err := keys.Sign(testClaims())
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	parsed, _, _ := jwt.NewParser().ParseUnverified(unpublished, jwt.MapClaims{})
+	if parsed.Header["kid"] != "2026-01" {
+		t.Fatalf("expected the new key to wait for JWKS caches, got %v", parsed.Header)
+	}
+
+	published := time.Now().Add(-auth.JWKSMaxAge - time.Minute)
+	if err := os.Chtimes(filepath.Join(dir, "2026-02.pem"), published, published); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+	if err := keys.Reload(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	newToken, err := keys.Sign(testClaims())
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	parsed, _, _ = jwt.NewParser().ParseUnverified(newToken, jwt.MapClaims{})
+	if parsed.Header["kid"] != "2026-02" || parsed.Method.Alg() != "EdDSA" {
+		t.Fatalf("expected the newest key to sign, got %v", parsed.Header)
+	}
+
+	for _, token := range []string{oldToken, newToken} {
+		claims, err := keys.Parse(token)
+		if err != nil || claims["sub"] != "42" {
+			t.Fatalf("expected token to verify during rotation: %v", err)
+		}
+	}
+
+	os.Remove(filepath.Join(dir, "2026-01.pem"))
+	if err := keys.Reload(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if _, err := keys.Parse(oldToken); err == nil {
+		t.Fatal("expected token of a removed key to be rejected")
+	}
+}
+
+func TestKeySetRejectsAlgorithmConfusion(t *testing.T) {
+	dir := t.TempDir()
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	writeKey(t, dir, "k1", edKey)
+	keys, err := auth.Load(auth.Config{Dir: dir})
+	if err != nil {
+		t.Fatalf("load keys: %v", err)
+	}
+
+	// An HS256 token claiming the kid of a public key must not verify.
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims())
+	token.Header["kid"] = "k1"
+	forged, _ := token.SignedString([]byte(edKey.Public().(ed25519.PublicKey)))
+	if _, err := keys.Parse(forged); err == nil {
+		t.Fatal("expected forged token to be rejected")
+	}
+
+	// Without JWT_SECRET, tokens without kid are not accepted at all.
+	plain, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims()).SignedString([]byte("secret"))
+	if _, err := keys.Parse(plain); err == nil {
+		t.Fatal("expected HS256 token to be rejected without a secret")
+	}
+}
+
+func TestKeySetFallsBackToSecret(t *testing.T) {
+	dir := t.TempDir()
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	writeKey(t, dir, "k1", edKey)
+	keys, err := auth.Load(auth.Config{Dir: dir, Secret: "legacy"})
+	if err != nil {
+		t.Fatalf("load keys: %v", err)
+	}
+
+	legacy, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims()).SignedString([]byte("legacy"))
+	if _, err := keys.Parse(legacy); err != nil {
+		t.Fatalf("expected legacy HS256 token to verify: %v", err)
+	}
+
+	if _, err := auth.Load(auth.Config{Dir: t.TempDir()}); err == nil {
+		t.Fatal("expected an error without any signing key")
+	}
+}
+
+func TestJWKSEndpoint(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	writeKey(t, dir, "a", rsaKey)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	writeKey(t, dir, "b", edKey)
+	keys, err := auth.Load(auth.Config{Dir: dir, Secret: "never-published"})
+	if err != nil {
+		t.Fatalf("load keys: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	handlers.JWKS(keys)(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var set auth.JWKSet
+	if err := json.NewDecoder(rec.Body).Decode(&set); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(set.Keys) != 2 {
+		t.Fatalf("expected two keys, got %+v", set.Keys)
+	}
+	if k := set.Keys[0]; k.Kid != "a" || k.Kty != "RSA" || k.Alg != "RS256" || k.N == "" || k.E != "AQAB" {
+		t.Fatalf("unexpected rsa jwk: %+v", k)
+	}
+	if k := set.Keys[1]; k.Kid != "b" || k.Kty != "OKP" || k.Crv != "Ed25519" || k.Alg != "EdDSA" || k.X == "" {
+		t.Fatalf("unexpected ed25519 jwk: %+v", k)
+	}
+}
@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"main-service/internal/contact"
+	"main-service/internal/handlers"
+	"main-service/internal/mail"
+)
+
+func TestContactEmail(t *testing.T) {
+	valid := map[string]string{
+		"alice@example.com":      "alice@example.com",
+		" Bob.Smith@Example.COM": "Bob.Smith@example.com",
+		"a+tag@mail.example.org": "a+tag@mail.example.org",
+	}
+	for in, want := range valid {
+		got, err := contact.Email(in)
+		if err != nil || got != want {
+			t.Fatalf("Email(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "alice", "alice@", "@example.com", "alice@localhost", "Alice <alice@example.com>", "a@b.c\r\nBcc: x@y.z", "alice@example."} {
+		if _, err := contact.Email(in); err == nil {
+			t.Fatalf("expected %q to be rejected", in)
+		}
+	}
+}
+
+func TestContactPhone(t *testing.T) {
+	valid := map[string]string{
+		"+14155552671":       "+14155552671",
+		"+7 (912) 345-67-89": "+79123456789",
+		"+44.20.7946.0958":   "+442079460958",
+	}
+	for in, want := range valid {
+		got, err := contact.Phone(in)
+		if err != nil || got != want {
+			t.Fatalf("Phone(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "89123456789", "+0123456", "+1", "+1234567890123456", "+7912abc4567", "++79123456789"} {
+		if _, err := contact.Phone(in); err == nil {
+			t.Fatalf("expected %q to be rejected", in)
+		}
+	}
+}
+
+func TestUserMeUpdateValidatesContacts(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "42",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte("test-secret"))
+	h := handlers.UserMeUpdate(nil, mail.NewWriter(&bytes.Buffer{}, ""), "http://localhost/verify", time.Hour)
+
+	for _, body := range []string{`{"phone":"8 912 345 67 89"}`, `{"email":"not-an-email"}`} {
+		req := httptest.NewRequest(http.MethodPut, "/users/me/update", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", body, rec.Code)
+		}
+	}
+}
+
+func TestUserMeUpdateMailsAgainOnceTheLinkExpired(t *testing.T) {
+	token := accountTestToken(t)
+	var pending any
+	var links []bool // whether each mailed link still works
+	db := newFakeDB(t, func(query string, args []driver.NamedValue) (fakeResult, error) {
+		switch {
+		case strings.Contains(query, "for update"):
+			return fakeResult{
+				columns: []string{"email", "email_verified", "pending_email"},
+				rows:    [][]driver.Value{{"old@example.com", true, pending}},
+			}, nil
+		case strings.Contains(query, "from email_verifications"):
+			return fakeResult{columns: []string{"exists"}, rows: [][]driver.Value{{slices.Contains(links, true)}}}, nil
+		case strings.Contains(query, "select exists"):
+			return fakeResult{columns: []string{"exists"}, rows: [][]driver.Value{{false}}}, nil
+		case strings.Contains(query, "set pending_email = $1"):
+			pending = args[0].Value
+		case strings.Contains(query, "insert into email_verifications"):
+			links = append(links, true)
+		}
+		return fakeResult{}, nil
+	})
+	var out bytes.Buffer
+	h := handlers.UserMeUpdate(db, mail.NewWriter(&out, ""), "http://localhost/verify", time.Hour)
+
+	save := func() {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPut, "/users/me/update", strings.NewReader(`{"email":"new@example.com"}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("expected 204, got %d: %s", rec.Code, rec.Body)
+		}
+	}
+	mails := func() int { return strings.Count(out.String(), "Subject: Confirm your email") }
+
+	save()
+	save()
+	if mails() != 1 {
+		t.Fatalf("expected one mail while the link works, got %d", mails())
+	}
+	links[0] = false // expired
+	save()
+	if mails() != 2 || len(links) != 2 {
+		t.Fatalf("expected a second mail and link after the first expired, got %d mails and %d links", mails(), len(links))
+	}
+}
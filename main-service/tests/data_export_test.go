@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"main-service/internal/dataexport"
+	"main-service/internal/handlers"
+)
+
+func TestDataExportArchive(t *testing.T) {
+	var buf bytes.Buffer
+	err := dataexport.Write(&buf, "42", time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC), []dataexport.File{
+		{Name: "profile.json", Description: "account profile", Data: map[string]any{"login": "alice"}},
+		{Name: "posts.json", Description: "posts", Data: []map[string]string{{"id": "p1", "title": "hello"}}},
+	})
+	if err != nil {
+		t.Fatalf("write archive: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("read archive: %v", err)
+	}
+	contents := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		contents[f.Name] = string(data)
+	}
+
+	if len(contents) != 3 {
+		t.Fatalf("expected README and two documents, got %v", zr.File)
+	}
+	if readme := contents["README.txt"]; !strings.Contains(readme, "user 42") || !strings.Contains(readme, "posts.json\tposts") {
+		t.Fatalf("unexpected README:\n%s", readme)
+	}
+	var profile map[string]string
+	if err := json.Unmarshal([]byte(contents["profile.json"]), &profile); err != nil || profile["login"] != "alice" {
+		t.Fatalf("unexpected profile.json %q: %v", contents["profile.json"], err)
+	}
+}
+
+func TestDataExportDownloadRejectsUnknownIDs(t *testing.T) {
+	token := accountTestToken(t)
+	h := handlers.UserMeExportDownload(nil)
+
+	for _, path := range []string{"/users/me/export/", "/users/me/export/../../users", "/users/me/export/not-a-uuid"} {
+		req := httptest.NewRequest(http.MethodGet, "/users/me/export/", nil)
+		req.URL.Path = path
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("%s: expected 404, got %d", path, rec.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/users/me/export/0b5e4c1e-8d2a-4f7e-9c39-6f1d2a3b4c5d", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	h(rec, req)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", rec.Code)
+	}
+}
@@ -1,13 +1,19 @@
 package tests
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"main-service/internal/handlers"
 	proto "posts-service/proto"
 	statspb "stats-service/proto"
@@ -15,6 +21,7 @@ import (
 
 type e2ePostsClient struct {
 	listResp *proto.ListPostsResponse
+	posts    map[string]*proto.Post
 }
 
 func (c *e2ePostsClient) CreatePost(context.Context, *proto.CreatePostRequest, ...grpc.CallOption) (*proto.CreatePostResponse, error) {
@@ -29,16 +36,33 @@ func (c *e2ePostsClient) DeletePost(context.Context, *proto.DeletePostRequest, .
 	return nil, nil
 }
 
-func (c *e2ePostsClient) GetPost(context.Context, *proto.GetPostRequest, ...grpc.CallOption) (*proto.GetPostResponse, error) {
+func (c *e2ePostsClient) GetPost(_ context.Context, in *proto.GetPostRequest, _ ...grpc.CallOption) (*proto.GetPostResponse, error) {
+	if post, ok := c.posts[in.GetId()]; ok {
+		return &proto.GetPostResponse{Post: post}, nil
+	}
 	return nil, nil
 }
 
+func (c *e2ePostsClient) DeleteUserPosts(context.Context, *proto.DeleteUserPostsRequest, ...grpc.CallOption) (*proto.DeleteUserPostsResponse, error) {
+	return &proto.DeleteUserPostsResponse{}, nil
+}
+
+func (c *e2ePostsClient) ModerateDeletePost(_ context.Context, in *proto.ModerateDeletePostRequest, _ ...grpc.CallOption) (*proto.DeletePostResponse, error) {
+	if _, ok := c.posts[in.GetId()]; !ok {
+		return nil, status.Error(codes.NotFound, "not found")
+	}
+	delete(c.posts, in.GetId())
+	return &proto.DeletePostResponse{Success: true}, nil
+}
+
 func (c *e2ePostsClient) ListPosts(context.Context, *proto.ListPostsRequest, ...grpc.CallOption) (*proto.ListPostsResponse, error) {
 	return c.listResp, nil
 }
 
 type e2eStatsClient struct {
 	postResp *statspb.PostStatsResponse
+	updates  []*statspb.PostStatsResponse
+	related  []*statspb.PostItem
 }
 
 func (c e2eStatsClient) GetPostStats(context.Context, *statspb.PostStatsRequest, ...grpc.CallOption) (*statspb.PostStatsResponse, error) {
@@ -53,6 +77,55 @@ func (e2eStatsClient) GetTopUsersByLikes(context.Context, *statspb.TopUsersReque
 	return nil, nil
 }
 
+func (c e2eStatsClient) WatchPostStats(context.Context, *statspb.PostStatsRequest, ...grpc.CallOption) (grpc.ServerStreamingClient[statspb.PostStatsResponse], error) {
+	return &e2eStatsStream{updates: c.updates}, nil
+}
+
+func (c e2eStatsClient) GetRelatedPosts(context.Context, *statspb.RelatedPostsRequest, ...grpc.CallOption) (*statspb.RelatedPostsResponse, error) {
+	return &statspb.RelatedPostsResponse{Items: c.related}, nil
+}
+
+func (c e2eStatsClient) GetAuthorStats(_ context.Context, in *statspb.AuthorStatsRequest, _ ...grpc.CallOption) (*statspb.AuthorStatsResponse, error) {
+	return &statspb.AuthorStatsResponse{
+		UserId:     in.GetUserId(),
+		PostCount:  1,
+		TotalViews: 12,
+		TotalLikes: 5,
+		Posts:      []*statspb.AuthorPostStats{{PostId: "p-" + in.GetUserId(), Views: 12, Likes: 5}},
+		Daily:      []*statspb.DailyStats{{Date: "2026-01-01", Views: 3}},
+	}, nil
+}
+
+func (e2eStatsClient) ExportEvents(context.Context, *statspb.ExportEventsRequest, ...grpc.CallOption) (grpc.ServerStreamingClient[statspb.ExportEventsChunk], error) {
+	return nil, io.EOF
+}
+
+func (e2eStatsClient) ListFlaggedEvents(context.Context, *statspb.ListFlaggedEventsRequest, ...grpc.CallOption) (*statspb.ListFlaggedEventsResponse, error) {
+	return &statspb.ListFlaggedEventsResponse{}, nil
+}
+
+func (e2eStatsClient) ReviewFlaggedEvent(context.Context, *statspb.ReviewFlaggedEventRequest, ...grpc.CallOption) (*statspb.ReviewFlaggedEventResponse, error) {
+	return nil, io.EOF
+}
+
+func (e2eStatsClient) ExportUserEvents(context.Context, *statspb.UserEventsRequest, ...grpc.CallOption) (grpc.ServerStreamingClient[statspb.UserEventsChunk], error) {
+	return nil, io.EOF
+}
+
+type e2eStatsStream struct {
+	grpc.ClientStream
+	updates []*statspb.PostStatsResponse
+}
+
+func (s *e2eStatsStream) Recv() (*statspb.PostStatsResponse, error) {
+	if len(s.updates) == 0 {
+		return nil, io.EOF
+	}
+	resp := s.updates[0]
+	s.updates = s.updates[1:]
+	return resp, nil
+}
+
 func TestMainPostsFlow(t *testing.T) {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/posts", handlers.Posts(&e2ePostsClient{listResp: &proto.ListPostsResponse{Posts: []*proto.Post{{Id: "1", Title: "hello"}}}}))
@@ -106,3 +179,170 @@ func TestMainStatsPostFlow(t *testing.T) {
 		t.Fatalf("unexpected body: %+v", body)
 	}
 }
+
+func TestMainStatsPostStreamFlow(t *testing.T) {
+	statsClient := e2eStatsClient{updates: []*statspb.PostStatsResponse{
+		{PostId: "1", Views: 1, Likes: 0},
+		{PostId: "1", Views: 5, Likes: 2},
+	}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/stats/post/stream", handlers.StatsPostStream(statsClient, time.Minute))
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/stats/post/stream?id=1")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected event stream, got %q", ct)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	want := "event: stats\ndata: {\"id\":\"1\",\"likes\":0,\"views\":1}\n\n" +
+		"event: stats\ndata: {\"id\":\"1\",\"likes\":2,\"views\":5}\n\n"
+	if string(body) != want {
+		t.Fatalf("unexpected stream body:\n%s", body)
+	}
+}
+
+type idleStatsClient struct{ e2eStatsClient }
+
+func (idleStatsClient) WatchPostStats(ctx context.Context, in *statspb.PostStatsRequest, _ ...grpc.CallOption) (grpc.ServerStreamingClient[statspb.PostStatsResponse], error) {
+	return &idleStatsStream{ctx: ctx, first: &statspb.PostStatsResponse{PostId: in.GetPostId()}}, nil
+}
+
+// idleStatsStream sends one snapshot and then nothing until cancelled.
+type idleStatsStream struct {
+	grpc.ClientStream
+	ctx   context.Context
+	first *statspb.PostStatsResponse
+}
+
+func (s *idleStatsStream) Recv() (*statspb.PostStatsResponse, error) {
+	if first := s.first; first != nil {
+		s.first = nil
+		return first, nil
+	}
+	<-s.ctx.Done()
+	return nil, s.ctx.Err()
+}
+
+func TestMainStatsPostStreamHeartbeat(t *testing.T) {
+	srv := httptest.NewServer(handlers.StatsPostStream(idleStatsClient{}, 20*time.Millisecond))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/stats/post/stream?id=1", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if scanner.Text() == ": ping" {
+			return
+		}
+	}
+	t.Fatalf("expected a heartbeat on the idle stream: %v", scanner.Err())
+}
+
+func TestMainRelatedPostsFlow(t *testing.T) {
+	postsClient := &e2ePostsClient{posts: map[string]*proto.Post{
+		"2": {Id: "2", Title: "second"},
+		"3": {Id: "3", Title: "third"},
+	}}
+	statsClient := e2eStatsClient{related: []*statspb.PostItem{
+		{PostId: "2", Score: 0.9},
+		{PostId: "deleted", Score: 0.7},
+		{PostId: "3", Score: 0.4},
+	}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/posts/", handlers.PostsWithID(postsClient, statsClient, nil, nil))
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/posts/1/related")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	var body []map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if len(body) != 2 || body[0]["id"] != "2" || body[0]["score"] != 0.9 || body[1]["title"] != "third" {
+		t.Fatalf("unexpected body: %+v", body)
+	}
+}
+
+func TestMainUserStatsFlow(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "42",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte("test-secret"))
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users/me/stats", handlers.UserMeStats(e2eStatsClient{}))
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/users/me/stats")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/users/me/stats?days=7", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var body struct {
+		TotalViews int64 `json:"total_views"`
+		Posts      []struct {
+			ID string `json:"id"`
+		} `json:"posts"`
+		BestPosts []any `json:"best_posts"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if body.TotalViews != 12 || len(body.Posts) != 1 || body.Posts[0].ID != "p-42" || body.BestPosts == nil {
+		t.Fatalf("unexpected body: %+v", body)
+	}
+}
@@ -0,0 +1,267 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"main-service/internal/events"
+	"main-service/internal/handlers"
+)
+
+type stubWriter struct {
+	mu       sync.Mutex
+	fail     bool
+	messages []kafka.Message
+}
+
+func (w *stubWriter) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.fail {
+		return errors.New("kafka unavailable")
+	}
+	w.messages = append(w.messages, msgs...)
+	return nil
+}
+
+func (w *stubWriter) values() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	values := make([]string, len(w.messages))
+	for i, msg := range w.messages {
+		values[i] = string(msg.Value)
+	}
+	return values
+}
+
+func waitDelivered(t *testing.T, publisher *events.Publisher, writer *stubWriter, want int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if len(writer.values()) >= want && publisher.Stats().Pending == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("expected %d delivered events, got %d (stats %+v)", want, len(writer.values()), publisher.Stats())
+}
+
+func TestPublisherKeepsEventsAcrossRestart(t *testing.T) {
+	dir := t.TempDir()
+	publisher, err := events.NewPublisher(events.Config{Dir: dir}, &stubWriter{fail: true})
+	if err != nil {
+		t.Fatalf("new publisher: %v", err)
+	}
+	views := publisher.Topic("post_views")
+	for i := 0; i < 3; i++ {
+		if err := views.Publish([]byte("p1"), []byte(fmt.Sprintf("view-%d", i))); err != nil {
+			t.Fatalf("publish: %v", err)
+		}
+	}
+	if stats := publisher.Stats(); stats.Pending != 3 || stats.SpoolBytes == 0 {
+		t.Fatalf("unexpected stats before restart: %+v", stats)
+	}
+	publisher.Close()
+
+	writer := &stubWriter{}
+	publisher, err = events.NewPublisher(events.Config{Dir: dir}, writer)
+	if err != nil {
+		t.Fatalf("reopen publisher: %v", err)
+	}
+	defer publisher.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go publisher.Run(ctx)
+
+	waitDelivered(t, publisher, writer, 3)
+	got := writer.values()
+	for i, value := range got {
+		if value != fmt.Sprintf("view-%d", i) {
+			t.Fatalf("events delivered out of order: %v", got)
+		}
+	}
+	if stats := publisher.Stats(); stats.SpoolBytes != 0 {
+		t.Fatalf("expected the spool to be compacted, got %+v", stats)
+	}
+}
+
+func TestPublisherReadsOverflowBackFromSpool(t *testing.T) {
+	writer := &stubWriter{}
+	publisher, err := events.NewPublisher(events.Config{Dir: t.TempDir(), QueueSize: 2, BatchSize: 3}, writer)
+	if err != nil {
+		t.Fatalf("new publisher: %v", err)
+	}
+	defer publisher.Close()
+
+	for i := 0; i < 7; i++ {
+		if err := publisher.Publish("post_likes", nil, []byte(fmt.Sprintf("like-%d", i))); err != nil {
+			t.Fatalf("publish: %v", err)
+		}
+	}
+	if stats := publisher.Stats(); stats.Pending != 7 || stats.Queued != 2 {
+		t.Fatalf("expected 2 of 7 events in memory, got %+v", stats)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go publisher.Run(ctx)
+
+	waitDelivered(t, publisher, writer, 7)
+	got := writer.values()
+	for i, value := range got {
+		if value != fmt.Sprintf("like-%d", i) {
+			t.Fatalf("events delivered out of order: %v", got)
+		}
+	}
+}
+
+// blockingWriter holds every write until its context is cancelled.
+type blockingWriter struct {
+	started chan struct{}
+}
+
+func (w *blockingWriter) WriteMessages(ctx context.Context, _ ...kafka.Message) error {
+	close(w.started)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestPublisherCloseStopsRun(t *testing.T) {
+	dir := t.TempDir()
+	writer := &blockingWriter{started: make(chan struct{})}
+	publisher, err := events.NewPublisher(events.Config{Dir: dir}, writer)
+	if err != nil {
+		t.Fatalf("new publisher: %v", err)
+	}
+	if err := publisher.Publish("post_views", nil, []byte("v1")); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		publisher.Run(context.Background())
+		close(stopped)
+	}()
+	<-writer.started
+	if err := publisher.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	select {
+	case <-stopped:
+	default:
+		t.Fatal("expected Close to wait for Run")
+	}
+
+	reopened, err := events.NewPublisher(events.Config{Dir: dir}, nil)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+	if pending := reopened.Stats().Pending; pending != 1 {
+		t.Fatalf("expected the undelivered event to stay spooled, got %d", pending)
+	}
+}
+
+func TestPublisherRejectsEventsWhenSpoolIsFull(t *testing.T) {
+	publisher, err := events.NewPublisher(events.Config{Dir: t.TempDir(), MaxSpoolBytes: 64}, nil)
+	if err != nil {
+		t.Fatalf("new publisher: %v", err)
+	}
+	defer publisher.Close()
+
+	if err := publisher.Publish("post_views", nil, []byte("v")); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if err := publisher.Publish("post_views", nil, []byte("a much longer event that does not fit")); !errors.Is(err, events.ErrSpoolFull) {
+		t.Fatalf("expected ErrSpoolFull, got %v", err)
+	}
+}
+
+func TestPublisherKeepsConcurrentEventsWhileCompacting(t *testing.T) {
+	writer := &stubWriter{}
+	publisher, err := events.NewPublisher(events.Config{Dir: t.TempDir(), MaxSpoolBytes: 8 << 10, BatchSize: 7}, writer)
+	if err != nil {
+		t.Fatalf("new publisher: %v", err)
+	}
+	defer publisher.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go publisher.Run(ctx)
+
+	const publishers, each = 8, 100
+	var wg sync.WaitGroup
+	errs := make(chan error, publishers)
+	for g := 0; g < publishers; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < each; i++ {
+				value := []byte(fmt.Sprintf("%d-%d", g, i))
+				err := publisher.Publish("post_views", nil, value)
+				for errors.Is(err, events.ErrSpoolFull) {
+					time.Sleep(time.Millisecond)
+					err = publisher.Publish("post_views", nil, value)
+				}
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("publish: %v", err)
+	}
+
+	waitDelivered(t, publisher, writer, publishers*each)
+	next := make(map[int]int)
+	for _, value := range writer.values() {
+		var g, i int
+		if _, err := fmt.Sscanf(value, "%d-%d", &g, &i); err != nil {
+			t.Fatalf("unexpected event %q", value)
+		}
+		if i != next[g] {
+			t.Fatalf("publisher %d: expected event %d, got %d", g, next[g], i)
+		}
+		next[g]++
+	}
+	if got := len(writer.values()); got != publishers*each {
+		t.Fatalf("expected %d events, got %d", publishers*each, got)
+	}
+}
+
+func TestViewHandlerSpoolsEvent(t *testing.T) {
+	publisher, err := events.NewPublisher(events.Config{Dir: t.TempDir(), MaxSpoolBytes: 512}, nil)
+	if err != nil {
+		t.Fatalf("new publisher: %v", err)
+	}
+	defer publisher.Close()
+	handler := handlers.PostsWithID(nil, nil, publisher.Topic("post_views"), publisher.Topic("post_likes"))
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, "/posts/p1/view", nil))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if stats := publisher.Stats(); stats.Pending != 1 {
+		t.Fatalf("expected the view to be spooled, got %+v", stats)
+	}
+
+	for rec.Code == http.StatusAccepted {
+		rec = httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, "/posts/p1/view", nil))
+	}
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 once the spool is full, got %d", rec.Code)
+	}
+}
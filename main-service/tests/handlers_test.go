@@ -33,6 +33,14 @@ func (c *listPostsClient) GetPost(context.Context, *proto.GetPostRequest, ...grp
 	return nil, nil
 }
 
+func (c *listPostsClient) DeleteUserPosts(context.Context, *proto.DeleteUserPostsRequest, ...grpc.CallOption) (*proto.DeleteUserPostsResponse, error) {
+	return nil, nil
+}
+
+func (c *listPostsClient) ModerateDeletePost(context.Context, *proto.ModerateDeletePostRequest, ...grpc.CallOption) (*proto.DeletePostResponse, error) {
+	return nil, nil
+}
+
 func (c *listPostsClient) ListPosts(context.Context, *proto.ListPostsRequest, ...grpc.CallOption) (*proto.ListPostsResponse, error) {
 	return c.resp, c.err
 }
@@ -0,0 +1,64 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"common/identity"
+	"main-service/internal/handlers"
+)
+
+func TestInternalTokenCarriesAuthenticatedUser(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	userToken, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":  "42",
+		"role": "moderator",
+		"exp":  time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte("test-secret"))
+
+	var ctx context.Context
+	h := handlers.AuthMiddleware(func(_ http.ResponseWriter, r *http.Request, _ string) {
+		ctx = r.Context()
+	})
+	req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+	req.Header.Set("Authorization", "Bearer "+userToken)
+	h(httptest.NewRecorder(), req)
+	if identity.UserID(ctx) != "42" {
+		t.Fatalf("expected the user in the request context, got %q", identity.UserID(ctx))
+	}
+
+	var sent metadata.MD
+	interceptor := identity.NewSigner("internal", "main-service").UnaryClientInterceptor("posts-service")
+	err := interceptor(ctx, "/posts.PostsService/CreatePost", nil, nil, nil,
+		func(ctx context.Context, _ string, _, _ any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+			sent, _ = metadata.FromOutgoingContext(ctx)
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("interceptor: %v", err)
+	}
+	tokens := sent.Get(identity.MetadataKey)
+	if len(tokens) != 1 {
+		t.Fatalf("expected one internal token, got %v", sent)
+	}
+
+	var claims struct {
+		jwt.RegisteredClaims
+		Role string `json:"role"`
+	}
+	_, err = jwt.ParseWithClaims(tokens[0], &claims, func(*jwt.Token) (any, error) { return []byte("internal"), nil },
+		jwt.WithAudience("posts-service"), jwt.WithExpirationRequired())
+	if err != nil {
+		t.Fatalf("parse internal token: %v", err)
+	}
+	if claims.Issuer != "main-service" || claims.Subject != "42" || claims.Role != "moderator" {
+		t.Fatalf("unexpected claims: %+v", claims)
+	}
+}
@@ -0,0 +1,277 @@
+package tests
+
+import (
+	"context"
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"main-service/internal/handlers"
+	"main-service/internal/lockout"
+)
+
+func TestLockoutPolicyDelay(t *testing.T) {
+	p := lockout.Policy{Free: 3, Base: time.Second, Max: 10 * time.Second}
+	want := map[int]time.Duration{1: 0, 3: 0, 4: time.Second, 5: 2 * time.Second, 6: 4 * time.Second, 7: 8 * time.Second, 8: 10 * time.Second, 100: 10 * time.Second}
+	for failures, d := range want {
+		if got := p.Delay(failures); got != d {
+			t.Errorf("Delay(%d) = %s, want %s", failures, got, d)
+		}
+	}
+}
+
+func newTestGuard(now *time.Time) *lockout.Guard {
+	g := lockout.NewGuard(lockout.NewMemory(),
+		lockout.Policy{Free: 2, Base: time.Second, Max: time.Minute, ResetAfter: time.Hour},
+		lockout.Policy{Free: 5, Base: time.Second, Max: time.Minute, ResetAfter: time.Hour})
+	g.SetClockForTest(func() time.Time { return *now })
+	return g
+}
+
+// attemptFailed makes a login attempt that turns out wrong and returns how
+// long the caller had to wait, if the attempt was refused.
+func attemptFailed(t *testing.T, g *lockout.Guard, login, ip string) time.Duration {
+	t.Helper()
+	a, wait, err := g.Begin(context.Background(), login, ip)
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	reason := lockout.ReasonInvalidCredentials
+	if wait > 0 {
+		reason = lockout.ReasonBlocked
+	}
+	if err := g.Fail(context.Background(), a, reason); err != nil {
+		t.Fatalf("fail: %v", err)
+	}
+	return wait
+}
+
+// blockedFor reports how long login from ip is blocked, taking back the
+// probe attempt when it is let through.
+func blockedFor(t *testing.T, g *lockout.Guard, login, ip string) time.Duration {
+	t.Helper()
+	a, wait, err := g.Begin(context.Background(), login, ip)
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	if wait == 0 {
+		if err := g.Pass(context.Background(), a); err != nil {
+			t.Fatalf("pass: %v", err)
+		}
+	}
+	return wait
+}
+
+func TestLockoutGuardBackoff(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	g := newTestGuard(&now)
+
+	attemptFailed(t, g, "alice", "10.0.0.1")
+	attemptFailed(t, g, "alice", "10.0.0.1")
+	if d := blockedFor(t, g, "alice", "10.0.0.1"); d != 0 {
+		t.Fatalf("free attempts must not block, got %s", d)
+	}
+	attemptFailed(t, g, "Alice", "10.0.0.2")
+	if d := blockedFor(t, g, "alice", "10.0.0.3"); d != time.Second {
+		t.Fatalf("expected a 1s block of the login from any address, got %s", d)
+	}
+	now = now.Add(time.Second)
+	attemptFailed(t, g, "alice", "10.0.0.1")
+	if d := blockedFor(t, g, "alice", "10.0.0.1"); d != 2*time.Second {
+		t.Fatalf("expected the block to double, got %s", d)
+	}
+
+	attempts, err := g.FailedAttempts(ctx, "ALICE", 10)
+	if err != nil || len(attempts) != 4 || attempts[0].IP != "10.0.0.1" || attempts[1].IP != "10.0.0.2" {
+		t.Fatalf("unexpected audit %+v (%v)", attempts, err)
+	}
+
+	if err := g.Unlock(ctx, "alice"); err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+	if d := blockedFor(t, g, "alice", "10.0.0.1"); d != 0 {
+		t.Fatalf("unlock must lift the block, got %s", d)
+	}
+}
+
+func TestLockoutGuardPerIP(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	g := newTestGuard(&now)
+
+	for i := 0; i < 6; i++ {
+		attemptFailed(t, g, "user"+string(rune('a'+i)), "10.0.0.9")
+	}
+	if d := blockedFor(t, g, "someone", "10.0.0.9"); d != time.Second {
+		t.Fatalf("expected the address to be blocked, got %s", d)
+	}
+
+	now = now.Add(time.Second)
+	a, wait, err := g.Begin(ctx, "someone", "10.0.0.10")
+	if err != nil || wait != 0 {
+		t.Fatalf("other addresses must not be blocked, got %s (%v)", wait, err)
+	}
+	if err := g.Succeed(ctx, a); err != nil {
+		t.Fatalf("succeed: %v", err)
+	}
+	if d := blockedFor(t, g, "someone", "10.0.0.10"); d != 0 {
+		t.Fatalf("a successful login must not count against its address, got %s", d)
+	}
+	attemptFailed(t, g, "usera", "10.0.0.9")
+	if d := blockedFor(t, g, "someone", "10.0.0.9"); d != 2*time.Second {
+		t.Fatalf("a successful login elsewhere must not unblock the address, got %s", d)
+	}
+}
+
+func TestLockoutGuardPassTakesBackAttempt(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	g := newTestGuard(&now)
+
+	attemptFailed(t, g, "dave", "10.0.0.1")
+	attemptFailed(t, g, "dave", "10.0.0.1")
+	// The third attempt would block the login, until the password turns
+	// out right.
+	a, wait, err := g.Begin(ctx, "dave", "10.0.0.1")
+	if err != nil || wait != 0 {
+		t.Fatalf("begin: %s %v", wait, err)
+	}
+	if err := g.Pass(ctx, a); err != nil {
+		t.Fatalf("pass: %v", err)
+	}
+	if d := blockedFor(t, g, "dave", "10.0.0.1"); d != 0 {
+		t.Fatalf("a passed attempt must not block, got %s", d)
+	}
+}
+
+func TestLockoutGuardConcurrentAttempts(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	g := newTestGuard(&now)
+
+	var wg sync.WaitGroup
+	var let atomic.Int32
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, wait, err := g.Begin(context.Background(), "erin", "10.0.0."+strconv.Itoa(i))
+			if err == nil && wait == 0 {
+				let.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	// Two free attempts and the one that sets the block.
+	if n := let.Load(); n != 3 {
+		t.Fatalf("expected 3 attempts to get through, got %d", n)
+	}
+}
+
+func TestLockoutGuardResetAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	g := newTestGuard(&now)
+
+	for i := 0; i < 2; i++ {
+		attemptFailed(t, g, "bob", "10.0.0.1")
+	}
+	now = now.Add(2 * time.Hour)
+	attemptFailed(t, g, "bob", "10.0.0.1")
+	if d := blockedFor(t, g, "bob", "10.0.0.1"); d != 0 {
+		t.Fatalf("old failures must be forgotten, got %s", d)
+	}
+}
+
+func TestLockoutGuardPrune(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	g := newTestGuard(&now)
+
+	for i := 0; i < 3; i++ {
+		attemptFailed(t, g, "frank", "10.0.0.1")
+	}
+	now = now.Add(2 * time.Hour)
+	attemptFailed(t, g, "frank", "10.0.0.2")
+	if err := g.Prune(ctx, time.Hour); err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	attempts, err := g.FailedAttempts(ctx, "frank", 10)
+	if err != nil || len(attempts) != 1 || attempts[0].IP != "10.0.0.2" {
+		t.Fatalf("expected only the recent audit record to stay, got %+v (%v)", attempts, err)
+	}
+}
+
+func TestAuthLoginBlocked(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	now := time.Now()
+	g := newTestGuard(&now)
+	for i := 0; i < 3; i++ {
+		attemptFailed(t, g, "carol", "192.0.2.1")
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"login":"carol","password":"whatever"}`))
+	req.RemoteAddr = "192.0.2.7:5000"
+	rec := httptest.NewRecorder()
+	handlers.AuthLogin(nil, g)(rec, req)
+
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "1" {
+		t.Fatalf("expected 429 with Retry-After, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	attempts, _ := g.FailedAttempts(context.Background(), "carol", 1)
+	if len(attempts) != 1 || attempts[0].Reason != lockout.ReasonBlocked || attempts[0].IP != "192.0.2.7" {
+		t.Fatalf("expected the blocked attempt to be audited, got %+v", attempts)
+	}
+	if d := blockedFor(t, g, "carol", "192.0.2.7"); d > time.Second {
+		t.Fatalf("attempts while blocked must not extend the block, got %s", d)
+	}
+}
+
+func TestUserMe2FADisableCountsTowardsLockout(t *testing.T) {
+	token := accountTestToken(t)
+	now := time.Now()
+	g := newTestGuard(&now)
+	hash, _ := bcrypt.GenerateFromPassword([]byte("right password"), bcrypt.MinCost)
+	db := newFakeDB(t, func(query string, _ []driver.NamedValue) (fakeResult, error) {
+		if strings.Contains(query, "for update") {
+			return fakeResult{
+				columns: []string{"id", "login", "pass_hash", "totp_enabled"},
+				rows:    [][]driver.Value{{int64(42), "dave", hash, true}},
+			}, nil
+		}
+		return fakeResult{}, nil
+	})
+	h := handlers.UserMe2FADisable(db, g)
+
+	disable := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/users/me/2fa/disable", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.RemoteAddr = "192.0.2.9:5000"
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec
+	}
+	if rec := disable(`{"password":"wrong","code":"000000"}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a wrong password, got %d", rec.Code)
+	}
+	if rec := disable(`{"password":"right password","recovery_code":"wrong"}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a wrong code, got %d", rec.Code)
+	}
+	attempts, _ := g.FailedAttempts(context.Background(), "dave", 10)
+	if len(attempts) != 2 {
+		t.Fatalf("expected both failures to be recorded, got %+v", attempts)
+	}
+	if rec := disable(`{"password":"right password","recovery_code":"wrong"}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a wrong code, got %d", rec.Code)
+	}
+	if rec := disable(`{"password":"right password","code":"000000"}`); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 once the free attempts are used up, got %d", rec.Code)
+	}
+}
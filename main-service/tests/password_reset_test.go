@@ -0,0 +1,179 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"main-service/internal/auth"
+	"main-service/internal/handlers"
+	"main-service/internal/lockout"
+	"main-service/internal/mail"
+)
+
+type sessionStoreStub map[string]int64
+
+func (s sessionStoreStub) SessionVersion(_ context.Context, userID string) (int64, error) {
+	version, ok := s[userID]
+	if !ok {
+		return 0, errors.New("no such user")
+	}
+	return version, nil
+}
+
+func TestPasswordResetInvalidatesSessions(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	store := sessionStoreStub{"42": 0}
+	handlers.UseSessionStore(store)
+	t.Cleanup(func() { handlers.UseSessionStore(nil) })
+
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "42",
+		"sv":  0,
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte("test-secret"))
+	h := handlers.AuthMiddleware(func(w http.ResponseWriter, _ *http.Request, _ string) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	call := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/users/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec.Code
+	}
+
+	if code := call(); code != http.StatusNoContent {
+		t.Fatalf("expected current session to be accepted, got %d", code)
+	}
+	store["42"]++
+	if code := call(); code != http.StatusUnauthorized {
+		t.Fatalf("expected session from before the reset to be rejected, got %d", code)
+	}
+}
+
+func TestOpaqueTokens(t *testing.T) {
+	a, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		t.Fatalf("new token: %v", err)
+	}
+	b, _, _ := auth.NewOpaqueToken()
+	if a == b || len(a) < 40 {
+		t.Fatalf("expected long random tokens, got %q and %q", a, b)
+	}
+	if !bytes.Equal(hash, auth.HashOpaqueToken(a)) || bytes.Contains(hash, []byte(a)) {
+		t.Fatal("expected the stored hash to match the token without containing it")
+	}
+}
+
+func TestWriterMailer(t *testing.T) {
+	var out bytes.Buffer
+	m := mail.NewWriter(&out, "no-reply@example.com")
+	err := m.Send(context.Background(), mail.Message{
+		To:      "user@example.com\r\nBcc: victim@example.com",
+		Subject: "Password reset",
+		Body:    "line 1\nline 2",
+	})
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	got := out.String()
+	for _, want := range []string{"From: no-reply@example.com\r\n", "Subject: Password reset\r\n", "line 1\r\nline 2"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected %q in message:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "\r\nBcc:") {
+		t.Fatalf("expected header injection to be stripped:\n%s", got)
+	}
+}
+
+func TestSMTPMailerGivesUpWhenTheRelayHangs(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	// Accept connections but never send the greeting.
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	m := &mail.SMTP{Addr: ln.Addr().String(), From: "no-reply@example.com"}
+	msg := mail.Message{To: "user@example.com", Subject: "Password reset", Body: "hi"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := m.Send(ctx, msg); err == nil {
+		t.Fatal("expected the send to fail")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("expected the deadline to stop the send, took %s", elapsed)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	if err := m.Send(ctx, msg); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the cancelled send to fail with context.Canceled, got %v", err)
+	}
+}
+
+func TestPasswordForgotValidation(t *testing.T) {
+	limiter := lockout.NewLimiter(lockout.NewMemory(), "password_forgot", 3, 20, time.Hour)
+	h := handlers.AuthPasswordForgot(nil, mail.NewWriter(&bytes.Buffer{}, ""), limiter, "http://localhost/reset", time.Hour)
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodPost, "/auth/password/forgot", strings.NewReader(`{"login":" "}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without login or email, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handlers.AuthPasswordReset(nil)(rec, httptest.NewRequest(http.MethodPost, "/auth/password/reset", strings.NewReader(`{"token":"t","password":"short"}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a short password, got %d", rec.Code)
+	}
+}
+
+func TestPasswordForgotIsRateLimited(t *testing.T) {
+	limiter := lockout.NewLimiter(lockout.NewMemory(), "password_forgot", 3, 20, time.Hour)
+	for i := 0; i < 3; i++ {
+		if ok, err := limiter.Allow(context.Background(), "Alice", "192.0.2.1"); err != nil || !ok {
+			t.Fatalf("expected request %d to be allowed: %v", i+1, err)
+		}
+	}
+
+	// The limit is reached before the account is looked up, so the nil
+	// database is never touched.
+	h := handlers.AuthPasswordForgot(nil, mail.NewWriter(&bytes.Buffer{}, ""), limiter, "http://localhost/reset", time.Hour)
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodPost, "/auth/password/forgot", strings.NewReader(`{"login":"alice"}`)))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 once the login is over its limit, got %d", rec.Code)
+	}
+
+	for i := 0; i < 20; i++ {
+		limiter.Allow(context.Background(), fmt.Sprintf("user%d", i), "198.51.100.7")
+	}
+	if ok, _ := limiter.Allow(context.Background(), "bob", "198.51.100.7"); ok {
+		t.Fatal("expected the address to be over its limit")
+	}
+	if ok, _ := limiter.Allow(context.Background(), "bob", "198.51.100.8"); !ok {
+		t.Fatal("expected another address to be allowed")
+	}
+}
@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"bytes"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pquerna/otp/totp"
+
+	"main-service/internal/auth"
+	"main-service/internal/handlers"
+)
+
+func TestTOTPEnrollment(t *testing.T) {
+	enrollment, err := auth.NewTOTP("alice")
+	if err != nil {
+		t.Fatalf("new totp: %v", err)
+	}
+	uri, err := url.Parse(enrollment.URI)
+	if err != nil || uri.Scheme != "otpauth" || uri.Host != "totp" {
+		t.Fatalf("unexpected otpauth uri %q", enrollment.URI)
+	}
+	if q := uri.Query(); q.Get("secret") != enrollment.Secret || q.Get("issuer") != "social-network" {
+		t.Fatalf("unexpected otpauth parameters %v", q)
+	}
+	img, err := png.Decode(bytes.NewReader(enrollment.QRPNG))
+	if err != nil || img.Bounds().Dx() != 256 {
+		t.Fatalf("expected a 256px PNG QR code: %v", err)
+	}
+}
+
+func TestTOTPValidation(t *testing.T) {
+	enrollment, _ := auth.NewTOTP("alice")
+	now := time.Unix(1_700_000_000, 0)
+	code, _ := totp.GenerateCode(enrollment.Secret, now)
+
+	step, ok := auth.ValidateTOTP(enrollment.Secret, code, now)
+	if !ok || step != now.Unix()/30 {
+		t.Fatalf("expected current code to be valid at step %d, got %d %v", now.Unix()/30, step, ok)
+	}
+	if _, ok := auth.ValidateTOTP(enrollment.Secret, code, now.Add(30*time.Second)); !ok {
+		t.Fatal("expected a code of the previous period to be accepted")
+	}
+	if _, ok := auth.ValidateTOTP(enrollment.Secret, code, now.Add(90*time.Second)); ok {
+		t.Fatal("expected an old code to be rejected")
+	}
+	if _, ok := auth.ValidateTOTP(enrollment.Secret, "000000", now); ok && code != "000000" {
+		t.Fatal("expected a wrong code to be rejected")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := auth.NewRecoveryCodes(10)
+	if err != nil || len(codes) != 10 || len(hashes) != 10 {
+		t.Fatalf("expected ten codes: %v", err)
+	}
+	format := regexp.MustCompile(`^[a-z2-7]{5}-[a-z2-7]{5}$`)
+	seen := map[string]bool{}
+	for i, code := range codes {
+		if !format.MatchString(code) || seen[code] {
+			t.Fatalf("unexpected or repeated code %q", code)
+		}
+		seen[code] = true
+		typed := strings.ToUpper(strings.ReplaceAll(code, "-", " "))
+		if !bytes.Equal(auth.HashRecoveryCode(typed), hashes[i]) {
+			t.Fatalf("expected %q to match the hash of %q", typed, code)
+		}
+	}
+}
+
+func TestMFAChallengeIsNotASession(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	sign := func(claims jwt.MapClaims) string {
+		claims["sub"] = "42"
+		claims["exp"] = time.Now().Add(time.Minute).Unix()
+		token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("test-secret"))
+		return token
+	}
+
+	h := handlers.AuthMiddleware(func(w http.ResponseWriter, _ *http.Request, _ string) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/users/me", nil)
+	req.Header.Set("Authorization", "Bearer "+sign(jwt.MapClaims{"typ": "mfa"}))
+	rec := httptest.NewRecorder()
+	h(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected an MFA challenge to be refused as a session, got %d", rec.Code)
+	}
+
+	// Nor can a session token stand in for the challenge.
+	body := `{"mfa_token":"` + sign(jwt.MapClaims{}) + `","code":"123456"}`
+	rec = httptest.NewRecorder()
+	handlers.AuthLoginMFA(nil, nil)(rec, httptest.NewRequest(http.MethodPost, "/auth/login/mfa", strings.NewReader(body)))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a session token to be refused as a challenge, got %d", rec.Code)
+	}
+	// A challenge must say which session version it was issued for.
+	body = `{"mfa_token":"` + sign(jwt.MapClaims{"typ": "mfa"}) + `","code":"123456"}`
+	rec = httptest.NewRecorder()
+	handlers.AuthLoginMFA(nil, nil)(rec, httptest.NewRequest(http.MethodPost, "/auth/login/mfa", strings.NewReader(body)))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a challenge without a session version to be refused, got %d", rec.Code)
+	}
+}
@@ -1,12 +1,18 @@
 package main
 
 import (
+	"common/identity"
+	"common/mtls"
+	"context"
 	"log"
 	"net"
 	"os"
 	"posts-service/internal/app"
 	"posts-service/internal/db"
+	"strings"
+	"time"
 
+	"github.com/segmentio/kafka-go"
 	"google.golang.org/grpc"
 
 	pb "posts-service/proto"
@@ -24,9 +30,15 @@ func env(key, def string) string {
 
 func main() {
 	addr := env("GRPC_ADDR", ":50051")
-	mongoURI := env("MONGO_URI", "mongodb://posts-mongo:27017")
+	mongoURI := env("MONGO_URI", "mongodb://posts-mongo:27017/?replicaSet=rs0")
 	dbName := env("MONGO_DB", "postsdb")
 	collName := env("MONGO_COLL", "posts")
+	kafkaBrokers := env("KAFKA_BROKERS", "")
+	eventsTopic := env("KAFKA_POST_EVENTS_TOPIC", "post_events")
+	internalSecret := os.Getenv("INTERNAL_TOKEN_SECRET")
+	if internalSecret == "" {
+		log.Fatal("INTERNAL_TOKEN_SECRET is required")
+	}
 
 	lis, err := net.Listen("tcp", addr)
 	if err != nil {
@@ -39,8 +51,48 @@ func main() {
 	}
 
 	db := db.New(client, dbName, collName)
+	if err := db.EnsureOutboxIndexes(context.Background()); err != nil {
+		log.Printf("ensure outbox indexes failed: %v", err)
+	}
+
+	if kafkaBrokers != "" {
+		relay := &app.Relay{
+			Store: db,
+			Writer: &kafka.Writer{
+				Addr:                   kafka.TCP(strings.Split(kafkaBrokers, ",")...),
+				Balancer:               &kafka.Hash{},
+				RequiredAcks:           kafka.RequireAll,
+				AllowAutoTopicCreation: true,
+			},
+			Topic: eventsTopic,
+		}
+		go relay.Run(context.Background())
+	} else {
+		log.Printf("KAFKA_BROKERS is not set, post events stay in the outbox")
+	}
+
+	var serverOpts []grpc.ServerOption
+	tlsCfg := mtls.Config{
+		CertFile: os.Getenv("TLS_CERT_FILE"),
+		KeyFile:  os.Getenv("TLS_KEY_FILE"),
+		CAFile:   os.Getenv("TLS_CA_FILE"),
+		DevDir:   os.Getenv("TLS_DEV_DIR"),
+		Name:     "posts-service",
+	}
+	if tlsCfg.Enabled() {
+		creds, err := mtls.Load(tlsCfg)
+		if err != nil {
+			log.Fatalf("load tls certificates: %v", err)
+		}
+		go creds.Watch(context.Background(), time.Minute)
+		serverOpts = append(serverOpts, creds.ServerOption())
+	}
 
-	s := grpc.NewServer()
+	verifier := identity.NewVerifier(internalSecret, "posts-service")
+	s := grpc.NewServer(append(serverOpts,
+		grpc.ChainUnaryInterceptor(verifier.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(verifier.StreamServerInterceptor()),
+	)...)
 	pb.RegisterPostsServiceServer(s, &app.Server{DB: db})
 
 	log.Printf("posts-service gRPC listening on %s", addr)
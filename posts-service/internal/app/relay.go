@@ -0,0 +1,99 @@
+package app
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"posts-service/internal/db"
+)
+
+type OutboxStore interface {
+	PendingOutbox(ctx context.Context, limit int) ([]db.OutboxEvent, error)
+	MarkOutboxPublished(ctx context.Context, ids []bson.ObjectID) error
+}
+
+type MessageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// Relay publishes outbox events to Kafka, keyed by post id. An event is
+// marked published only after Kafka acknowledged it, so a crash in between
+// publishes it again: consumers must tolerate duplicates.
+type Relay struct {
+	Store        OutboxStore
+	Writer       MessageWriter
+	Topic        string
+	BatchSize    int
+	PollInterval time.Duration
+}
+
+// Run polls the outbox until ctx is cancelled, backing off while Mongo or
+// Kafka fail.
+func (r *Relay) Run(ctx context.Context) {
+	batchSize := r.BatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	poll := r.PollInterval
+	if poll <= 0 {
+		poll = 500 * time.Millisecond
+	}
+
+	backoff := time.Second
+	for {
+		n, err := r.relayBatch(ctx, batchSize)
+		if ctx.Err() != nil {
+			return
+		}
+		wait := time.Duration(0)
+		switch {
+		case err != nil:
+			log.Printf("outbox relay failed, retrying in %s: %v", backoff, err)
+			wait = backoff
+			backoff = min(backoff*2, 30*time.Second)
+		case n < batchSize:
+			backoff = time.Second
+			wait = poll
+		default:
+			backoff = time.Second
+		}
+		if wait == 0 {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (r *Relay) relayBatch(ctx context.Context, limit int) (int, error) {
+	events, err := r.Store.PendingOutbox(ctx, limit)
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+
+	msgs := make([]kafka.Message, len(events))
+	ids := make([]bson.ObjectID, len(events))
+	for i, e := range events {
+		msgs[i] = kafka.Message{
+			Topic:   r.Topic,
+			Key:     []byte(e.PostID),
+			Value:   e.Payload,
+			Headers: []kafka.Header{{Key: "event_type", Value: []byte(e.Type)}},
+		}
+		ids[i] = e.ID
+	}
+	if err := r.Writer.WriteMessages(ctx, msgs...); err != nil {
+		return 0, err
+	}
+	if err := r.Store.MarkOutboxPublished(ctx, ids); err != nil {
+		return 0, err
+	}
+	return len(events), nil
+}
@@ -7,6 +7,7 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"common/identity"
 	"posts-service/internal/db"
 	pb "posts-service/proto"
 
@@ -19,6 +20,8 @@ type PostStorage interface {
 	Delete(id, ownerID string) error
 	Get(id, ownerID string) (db.Post, error)
 	List(ownerID string, page, pageSize int64) ([]db.Post, int64, error)
+	DeleteByOwner(ownerID string, anonymize bool) (int64, error)
+	DeleteAny(id string) error
 }
 
 type Server struct {
@@ -37,10 +40,24 @@ func toPB(p db.Post) *pb.Post {
 	}
 }
 
-func (s *Server) CreatePost(_ context.Context, in *pb.CreatePostRequest) (*pb.CreatePostResponse, error) {
+// owner returns the authenticated user a mutation is made for. The user_id
+// request fields are ignored: only the caller's token decides ownership.
+func owner(ctx context.Context) (string, error) {
+	userID := identity.UserID(ctx)
+	if userID == "" {
+		return "", status.Error(codes.Unauthenticated, "user identity required")
+	}
+	return userID, nil
+}
+
+func (s *Server) CreatePost(ctx context.Context, in *pb.CreatePostRequest) (*pb.CreatePostResponse, error) {
+	ownerID, err := owner(ctx)
+	if err != nil {
+		return nil, err
+	}
 	p, err := s.DB.Create(db.Post{
 		ID:      db.NewStringID(),
-		OwnerID: in.GetUserId(),
+		OwnerID: ownerID,
 		Title:   in.GetTitle(),
 		Content: in.GetContent(),
 	})
@@ -50,8 +67,12 @@ func (s *Server) CreatePost(_ context.Context, in *pb.CreatePostRequest) (*pb.Cr
 	return &pb.CreatePostResponse{Post: toPB(p)}, nil
 }
 
-func (s *Server) UpdatePost(_ context.Context, in *pb.UpdatePostRequest) (*pb.UpdatePostResponse, error) {
-	p, err := s.DB.Update(in.GetId(), in.GetUserId(), in.GetTitle(), in.GetContent())
+func (s *Server) UpdatePost(ctx context.Context, in *pb.UpdatePostRequest) (*pb.UpdatePostResponse, error) {
+	ownerID, err := owner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p, err := s.DB.Update(in.GetId(), ownerID, in.GetTitle(), in.GetContent())
 	if err != nil {
 		if err == db.ErrNotFound {
 			return nil, status.Error(codes.NotFound, "not found")
@@ -61,9 +82,32 @@ func (s *Server) UpdatePost(_ context.Context, in *pb.UpdatePostRequest) (*pb.Up
 	return &pb.UpdatePostResponse{Post: toPB(p)}, nil
 }
 
-func (s *Server) DeletePost(_ context.Context, in *pb.DeletePostRequest) (*pb.DeletePostResponse, error) {
-	err := s.DB.Delete(in.GetId(), in.GetUserId())
+func (s *Server) DeletePost(ctx context.Context, in *pb.DeletePostRequest) (*pb.DeletePostResponse, error) {
+	ownerID, err := owner(ctx)
 	if err != nil {
+		return nil, err
+	}
+	if err := s.DB.Delete(in.GetId(), ownerID); err != nil {
+		if err == db.ErrNotFound {
+			return nil, status.Error(codes.NotFound, "not found")
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &pb.DeletePostResponse{Success: true}, nil
+}
+
+// moderatorRoles may delete the posts of others.
+var moderatorRoles = map[string]bool{"moderator": true, "admin": true}
+
+func (s *Server) ModerateDeletePost(ctx context.Context, in *pb.ModerateDeletePostRequest) (*pb.DeletePostResponse, error) {
+	caller, _ := identity.FromContext(ctx)
+	if caller.UserID == "" {
+		return nil, status.Error(codes.Unauthenticated, "user identity required")
+	}
+	if !moderatorRoles[caller.Role] {
+		return nil, status.Error(codes.PermissionDenied, "moderator role required")
+	}
+	if err := s.DB.DeleteAny(in.GetId()); err != nil {
 		if err == db.ErrNotFound {
 			return nil, status.Error(codes.NotFound, "not found")
 		}
@@ -72,6 +116,19 @@ func (s *Server) DeletePost(_ context.Context, in *pb.DeletePostRequest) (*pb.De
 	return &pb.DeletePostResponse{Success: true}, nil
 }
 
+// DeleteUserPosts is called when the user deletes their account.
+func (s *Server) DeleteUserPosts(ctx context.Context, in *pb.DeleteUserPostsRequest) (*pb.DeleteUserPostsResponse, error) {
+	ownerID, err := owner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	n, err := s.DB.DeleteByOwner(ownerID, in.GetAnonymize())
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &pb.DeleteUserPostsResponse{Affected: int32(n)}, nil
+}
+
 func (s *Server) GetPost(_ context.Context, in *pb.GetPostRequest) (*pb.GetPostResponse, error) {
 	p, err := s.DB.Get(in.GetId(), in.GetUserId())
 	if err != nil {
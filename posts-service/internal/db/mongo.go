@@ -27,11 +27,14 @@ type Post struct {
 }
 
 type DB struct {
-	coll *mongo.Collection
+	client *mongo.Client
+	coll   *mongo.Collection
+	outbox *mongo.Collection
 }
 
 func New(client *mongo.Client, dbName, collName string) *DB {
-	return &DB{coll: client.Database(dbName).Collection(collName)}
+	database := client.Database(dbName)
+	return &DB{client: client, coll: database.Collection(collName), outbox: database.Collection(OutboxCollection)}
 }
 
 func (db *DB) Create(p Post) (Post, error) {
@@ -39,7 +42,12 @@ func (db *DB) Create(p Post) (Post, error) {
 
 	p.CreatedAt = time.Now().UTC()
 	p.UpdatedAt = p.CreatedAt
-	_, err := db.coll.InsertOne(ctx, p)
+	err := db.withOutbox(ctx, func(ctx context.Context) (*OutboxEvent, error) {
+		if _, err := db.coll.InsertOne(ctx, p); err != nil {
+			return nil, err
+		}
+		return newOutboxEvent(EventPostCreated, p, p.CreatedAt)
+	})
 	return p, err
 }
 
@@ -56,7 +64,12 @@ func (db *DB) Update(id, ownerID, title, content string) (Post, error) {
 	var out Post
 	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
 
-	err := db.coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&out)
+	err := db.withOutbox(ctx, func(ctx context.Context) (*OutboxEvent, error) {
+		if err := db.coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&out); err != nil {
+			return nil, err
+		}
+		return newOutboxEvent(EventPostUpdated, out, out.UpdatedAt)
+	})
 	if errors.Is(err, mongo.ErrNoDocuments) {
 		return Post{}, ErrNotFound
 	}
@@ -66,10 +79,87 @@ func (db *DB) Update(id, ownerID, title, content string) (Post, error) {
 func (db *DB) Delete(id, ownerID string) error {
 	ctx := context.Background()
 
-	_, err := db.coll.DeleteOne(ctx, bson.D{{Key: "id", Value: id}, {Key: "owner_id", Value: ownerID}})
+	return db.withOutbox(ctx, func(ctx context.Context) (*OutboxEvent, error) {
+		res, err := db.coll.DeleteOne(ctx, bson.D{{Key: "id", Value: id}, {Key: "owner_id", Value: ownerID}})
+		if err != nil || res.DeletedCount == 0 {
+			return nil, err
+		}
+		return newOutboxEvent(EventPostDeleted, Post{ID: id, OwnerID: ownerID}, time.Now().UTC())
+	})
+}
+
+// DeleteAny removes the post whatever its owner, for moderation.
+func (db *DB) DeleteAny(id string) error {
+	ctx := context.Background()
+
+	err := db.withOutbox(ctx, func(ctx context.Context) (*OutboxEvent, error) {
+		var p Post
+		if err := db.coll.FindOneAndDelete(ctx, bson.D{{Key: "id", Value: id}}).Decode(&p); err != nil {
+			return nil, err
+		}
+		return newOutboxEvent(EventPostDeleted, Post{ID: p.ID, OwnerID: p.OwnerID}, time.Now().UTC())
+	})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrNotFound
+	}
 	return err
 }
 
+// DeleteByOwner removes every post of ownerID or, with anonymize, keeps them
+// without an owner, and returns how many posts it changed. Each post gets
+// its own lifecycle event.
+func (db *DB) DeleteByOwner(ownerID string, anonymize bool) (int64, error) {
+	ctx := context.Background()
+
+	var affected int64
+	err := db.withOutboxEvents(ctx, func(ctx context.Context) ([]*OutboxEvent, error) {
+		filter := bson.D{{Key: "owner_id", Value: ownerID}}
+		cur, err := db.coll.Find(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		var posts []Post
+		if err := cur.All(ctx, &posts); err != nil {
+			return nil, err
+		}
+		if len(posts) == 0 {
+			return nil, nil
+		}
+
+		now := time.Now().UTC()
+		eventType := EventPostDeleted
+		if anonymize {
+			eventType = EventPostUpdated
+			_, err = db.coll.UpdateMany(ctx, filter, bson.D{{Key: "$set", Value: bson.D{
+				{Key: "owner_id", Value: ""},
+				{Key: "updated_at", Value: now},
+			}}})
+		} else {
+			_, err = db.coll.DeleteMany(ctx, filter)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		events := make([]*OutboxEvent, 0, len(posts))
+		for _, p := range posts {
+			if anonymize {
+				p.OwnerID, p.UpdatedAt = "", now
+			} else {
+				p = Post{ID: p.ID, OwnerID: p.OwnerID}
+			}
+			e, err := newOutboxEvent(eventType, p, now)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, e)
+		}
+		affected = int64(len(posts))
+		return events, nil
+	})
+	return affected, err
+}
+
 func (db *DB) Get(id, ownerID string) (Post, error) {
 	ctx := context.Background()
 
@@ -0,0 +1,137 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// OutboxCollection holds post lifecycle events until the relay has published
+// them to Kafka. It lives in the posts database so that an event is written
+// in the same transaction as the mutation it describes.
+const OutboxCollection = "outbox"
+
+// outboxRetention is how long published events are kept for debugging.
+const outboxRetention = 7 * 24 * time.Hour
+
+const (
+	EventPostCreated = "post_created"
+	EventPostUpdated = "post_updated"
+	EventPostDeleted = "post_deleted"
+)
+
+// OutboxEvent is a pending or published lifecycle event. IDs are ObjectIDs so
+// that sorting by _id returns the events of a process in write order.
+type OutboxEvent struct {
+	ID          bson.ObjectID `bson:"_id"`
+	Type        string        `bson:"type"`
+	PostID      string        `bson:"post_id"`
+	Payload     []byte        `bson:"payload"`
+	CreatedAt   time.Time     `bson:"created_at"`
+	PublishedAt *time.Time    `bson:"published_at,omitempty"`
+}
+
+// PostEvent is the Kafka message body. Deleted posts carry only their ids.
+type PostEvent struct {
+	EventType string    `json:"event_type"`
+	PostID    string    `json:"post_id"`
+	OwnerID   string    `json:"owner_id"`
+	Title     string    `json:"title,omitempty"`
+	Content   string    `json:"content,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+func newOutboxEvent(eventType string, p Post, at time.Time) (*OutboxEvent, error) {
+	payload, err := json.Marshal(PostEvent{
+		EventType: eventType,
+		PostID:    p.ID,
+		OwnerID:   p.OwnerID,
+		Title:     p.Title,
+		Content:   p.Content,
+		Timestamp: at,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &OutboxEvent{
+		ID:        bson.NewObjectID(),
+		Type:      eventType,
+		PostID:    p.ID,
+		Payload:   payload,
+		CreatedAt: at,
+	}, nil
+}
+
+// withOutbox runs fn in a transaction and stores the event it returns in the
+// same transaction. fn returns a nil event when nothing changed. Transactions
+// need MongoDB to run as a replica set.
+func (db *DB) withOutbox(ctx context.Context, fn func(ctx context.Context) (*OutboxEvent, error)) error {
+	return db.withOutboxEvents(ctx, func(ctx context.Context) ([]*OutboxEvent, error) {
+		event, err := fn(ctx)
+		if err != nil || event == nil {
+			return nil, err
+		}
+		return []*OutboxEvent{event}, nil
+	})
+}
+
+// withOutboxEvents is withOutbox for mutations of several posts.
+func (db *DB) withOutboxEvents(ctx context.Context, fn func(ctx context.Context) ([]*OutboxEvent, error)) error {
+	session, err := db.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(ctx context.Context) (any, error) {
+		events, err := fn(ctx)
+		if err != nil || len(events) == 0 {
+			return nil, err
+		}
+		docs := make([]any, len(events))
+		for i, e := range events {
+			docs[i] = e
+		}
+		_, err = db.outbox.InsertMany(ctx, docs)
+		return nil, err
+	})
+	return err
+}
+
+// EnsureOutboxIndexes creates the index the relay polls by. It is a TTL index
+// as well: published events expire, pending ones have no published_at and
+// are never removed.
+func (db *DB) EnsureOutboxIndexes(ctx context.Context) error {
+	_, err := db.outbox.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "published_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(int32(outboxRetention.Seconds())),
+	})
+	return err
+}
+
+// PendingOutbox returns up to limit unpublished events, oldest first.
+func (db *DB) PendingOutbox(ctx context.Context, limit int) ([]OutboxEvent, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(limit))
+	cur, err := db.outbox.Find(ctx, bson.D{{Key: "published_at", Value: nil}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var events []OutboxEvent
+	if err := cur.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// MarkOutboxPublished records that Kafka acknowledged the events.
+func (db *DB) MarkOutboxPublished(ctx context.Context, ids []bson.ObjectID) error {
+	_, err := db.outbox.UpdateMany(ctx,
+		bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: ids}}}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "published_at", Value: time.Now().UTC()}}}},
+	)
+	return err
+}
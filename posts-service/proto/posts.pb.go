@@ -514,6 +514,142 @@ func (x *GetPostResponse) GetPost() *Post {
 	return nil
 }
 
+// ModerateDeletePost deletes any post. Only moderators and administrators,
+// per the role of the identity token, may call it.
+type ModerateDeletePostRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ModerateDeletePostRequest) Reset() {
+	*x = ModerateDeletePostRequest{}
+	mi := &file_proto_posts_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ModerateDeletePostRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ModerateDeletePostRequest) ProtoMessage() {}
+
+func (x *ModerateDeletePostRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_posts_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ModerateDeletePostRequest.ProtoReflect.Descriptor instead.
+func (*ModerateDeletePostRequest) Descriptor() ([]byte, []int) {
+	return file_proto_posts_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ModerateDeletePostRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+// DeleteUserPosts acts on every post of the calling user, taken from the
+// identity token. With anonymize the posts stay but lose their owner.
+type DeleteUserPostsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Anonymize     bool                   `protobuf:"varint,1,opt,name=anonymize,proto3" json:"anonymize,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteUserPostsRequest) Reset() {
+	*x = DeleteUserPostsRequest{}
+	mi := &file_proto_posts_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteUserPostsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteUserPostsRequest) ProtoMessage() {}
+
+func (x *DeleteUserPostsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_posts_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteUserPostsRequest.ProtoReflect.Descriptor instead.
+func (*DeleteUserPostsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_posts_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *DeleteUserPostsRequest) GetAnonymize() bool {
+	if x != nil {
+		return x.Anonymize
+	}
+	return false
+}
+
+type DeleteUserPostsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Affected      int32                  `protobuf:"varint,1,opt,name=affected,proto3" json:"affected,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteUserPostsResponse) Reset() {
+	*x = DeleteUserPostsResponse{}
+	mi := &file_proto_posts_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteUserPostsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteUserPostsResponse) ProtoMessage() {}
+
+func (x *DeleteUserPostsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_posts_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteUserPostsResponse.ProtoReflect.Descriptor instead.
+func (*DeleteUserPostsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_posts_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *DeleteUserPostsResponse) GetAffected() int32 {
+	if x != nil {
+		return x.Affected
+	}
+	return 0
+}
+
 type ListPostsRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
@@ -525,7 +661,7 @@ type ListPostsRequest struct {
 
 func (x *ListPostsRequest) Reset() {
 	*x = ListPostsRequest{}
-	mi := &file_proto_posts_proto_msgTypes[9]
+	mi := &file_proto_posts_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -537,7 +673,7 @@ func (x *ListPostsRequest) String() string {
 func (*ListPostsRequest) ProtoMessage() {}
 
 func (x *ListPostsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_posts_proto_msgTypes[9]
+	mi := &file_proto_posts_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -550,7 +686,7 @@ func (x *ListPostsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListPostsRequest.ProtoReflect.Descriptor instead.
 func (*ListPostsRequest) Descriptor() ([]byte, []int) {
-	return file_proto_posts_proto_rawDescGZIP(), []int{9}
+	return file_proto_posts_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *ListPostsRequest) GetUserId() string {
@@ -586,7 +722,7 @@ type ListPostsResponse struct {
 
 func (x *ListPostsResponse) Reset() {
 	*x = ListPostsResponse{}
-	mi := &file_proto_posts_proto_msgTypes[10]
+	mi := &file_proto_posts_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -598,7 +734,7 @@ func (x *ListPostsResponse) String() string {
 func (*ListPostsResponse) ProtoMessage() {}
 
 func (x *ListPostsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_posts_proto_msgTypes[10]
+	mi := &file_proto_posts_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -611,7 +747,7 @@ func (x *ListPostsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListPostsResponse.ProtoReflect.Descriptor instead.
 func (*ListPostsResponse) Descriptor() ([]byte, []int) {
-	return file_proto_posts_proto_rawDescGZIP(), []int{10}
+	return file_proto_posts_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *ListPostsResponse) GetPosts() []*Post {
@@ -678,7 +814,13 @@ const file_proto_posts_proto_rawDesc = "" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x17\n" +
 	"\auser_id\x18\x02 \x01(\tR\x06userId\"5\n" +
 	"\x0fGetPostResponse\x12\"\n" +
-	"\x04post\x18\x01 \x01(\v2\x0e.posts.v1.PostR\x04post\"\\\n" +
+	"\x04post\x18\x01 \x01(\v2\x0e.posts.v1.PostR\x04post\"+\n" +
+	"\x19ModerateDeletePostRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"6\n" +
+	"\x16DeleteUserPostsRequest\x12\x1c\n" +
+	"\tanonymize\x18\x01 \x01(\bR\tanonymize\"5\n" +
+	"\x17DeleteUserPostsResponse\x12\x1a\n" +
+	"\baffected\x18\x01 \x01(\x05R\baffected\"\\\n" +
 	"\x10ListPostsRequest\x12\x17\n" +
 	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x12\n" +
 	"\x04page\x18\x02 \x01(\x05R\x04page\x12\x1b\n" +
@@ -687,7 +829,7 @@ const file_proto_posts_proto_rawDesc = "" +
 	"\x05posts\x18\x01 \x03(\v2\x0e.posts.v1.PostR\x05posts\x12\x12\n" +
 	"\x04page\x18\x02 \x01(\x05R\x04page\x12\x1b\n" +
 	"\tpage_size\x18\x03 \x01(\x05R\bpageSize\x12\x14\n" +
-	"\x05total\x18\x04 \x01(\x05R\x05total2\xef\x02\n" +
+	"\x05total\x18\x04 \x01(\x05R\x05total2\xa0\x04\n" +
 	"\fPostsService\x12G\n" +
 	"\n" +
 	"CreatePost\x12\x1b.posts.v1.CreatePostRequest\x1a\x1c.posts.v1.CreatePostResponse\x12G\n" +
@@ -696,7 +838,9 @@ const file_proto_posts_proto_rawDesc = "" +
 	"\n" +
 	"DeletePost\x12\x1b.posts.v1.DeletePostRequest\x1a\x1c.posts.v1.DeletePostResponse\x12>\n" +
 	"\aGetPost\x12\x18.posts.v1.GetPostRequest\x1a\x19.posts.v1.GetPostResponse\x12D\n" +
-	"\tListPosts\x12\x1a.posts.v1.ListPostsRequest\x1a\x1b.posts.v1.ListPostsResponseB\x1bZ\x19posts-service/proto;protob\x06proto3"
+	"\tListPosts\x12\x1a.posts.v1.ListPostsRequest\x1a\x1b.posts.v1.ListPostsResponse\x12V\n" +
+	"\x0fDeleteUserPosts\x12 .posts.v1.DeleteUserPostsRequest\x1a!.posts.v1.DeleteUserPostsResponse\x12W\n" +
+	"\x12ModerateDeletePost\x12#.posts.v1.ModerateDeletePostRequest\x1a\x1c.posts.v1.DeletePostResponseB\x1bZ\x19posts-service/proto;protob\x06proto3"
 
 var (
 	file_proto_posts_proto_rawDescOnce sync.Once
@@ -710,24 +854,27 @@ func file_proto_posts_proto_rawDescGZIP() []byte {
 	return file_proto_posts_proto_rawDescData
 }
 
-var file_proto_posts_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_proto_posts_proto_msgTypes = make([]protoimpl.MessageInfo, 14)
 var file_proto_posts_proto_goTypes = []any{
-	(*Post)(nil),                  // 0: posts.v1.Post
-	(*CreatePostRequest)(nil),     // 1: posts.v1.CreatePostRequest
-	(*CreatePostResponse)(nil),    // 2: posts.v1.CreatePostResponse
-	(*UpdatePostRequest)(nil),     // 3: posts.v1.UpdatePostRequest
-	(*UpdatePostResponse)(nil),    // 4: posts.v1.UpdatePostResponse
-	(*DeletePostRequest)(nil),     // 5: posts.v1.DeletePostRequest
-	(*DeletePostResponse)(nil),    // 6: posts.v1.DeletePostResponse
-	(*GetPostRequest)(nil),        // 7: posts.v1.GetPostRequest
-	(*GetPostResponse)(nil),       // 8: posts.v1.GetPostResponse
-	(*ListPostsRequest)(nil),      // 9: posts.v1.ListPostsRequest
-	(*ListPostsResponse)(nil),     // 10: posts.v1.ListPostsResponse
-	(*timestamppb.Timestamp)(nil), // 11: google.protobuf.Timestamp
+	(*Post)(nil),                      // 0: posts.v1.Post
+	(*CreatePostRequest)(nil),         // 1: posts.v1.CreatePostRequest
+	(*CreatePostResponse)(nil),        // 2: posts.v1.CreatePostResponse
+	(*UpdatePostRequest)(nil),         // 3: posts.v1.UpdatePostRequest
+	(*UpdatePostResponse)(nil),        // 4: posts.v1.UpdatePostResponse
+	(*DeletePostRequest)(nil),         // 5: posts.v1.DeletePostRequest
+	(*DeletePostResponse)(nil),        // 6: posts.v1.DeletePostResponse
+	(*GetPostRequest)(nil),            // 7: posts.v1.GetPostRequest
+	(*GetPostResponse)(nil),           // 8: posts.v1.GetPostResponse
+	(*ModerateDeletePostRequest)(nil), // 9: posts.v1.ModerateDeletePostRequest
+	(*DeleteUserPostsRequest)(nil),    // 10: posts.v1.DeleteUserPostsRequest
+	(*DeleteUserPostsResponse)(nil),   // 11: posts.v1.DeleteUserPostsResponse
+	(*ListPostsRequest)(nil),          // 12: posts.v1.ListPostsRequest
+	(*ListPostsResponse)(nil),         // 13: posts.v1.ListPostsResponse
+	(*timestamppb.Timestamp)(nil),     // 14: google.protobuf.Timestamp
 }
 var file_proto_posts_proto_depIdxs = []int32{
-	11, // 0: posts.v1.Post.created_at:type_name -> google.protobuf.Timestamp
-	11, // 1: posts.v1.Post.updated_at:type_name -> google.protobuf.Timestamp
+	14, // 0: posts.v1.Post.created_at:type_name -> google.protobuf.Timestamp
+	14, // 1: posts.v1.Post.updated_at:type_name -> google.protobuf.Timestamp
 	0,  // 2: posts.v1.CreatePostResponse.post:type_name -> posts.v1.Post
 	0,  // 3: posts.v1.UpdatePostResponse.post:type_name -> posts.v1.Post
 	0,  // 4: posts.v1.GetPostResponse.post:type_name -> posts.v1.Post
@@ -736,14 +883,18 @@ var file_proto_posts_proto_depIdxs = []int32{
 	3,  // 7: posts.v1.PostsService.UpdatePost:input_type -> posts.v1.UpdatePostRequest
 	5,  // 8: posts.v1.PostsService.DeletePost:input_type -> posts.v1.DeletePostRequest
 	7,  // 9: posts.v1.PostsService.GetPost:input_type -> posts.v1.GetPostRequest
-	9,  // 10: posts.v1.PostsService.ListPosts:input_type -> posts.v1.ListPostsRequest
-	2,  // 11: posts.v1.PostsService.CreatePost:output_type -> posts.v1.CreatePostResponse
-	4,  // 12: posts.v1.PostsService.UpdatePost:output_type -> posts.v1.UpdatePostResponse
-	6,  // 13: posts.v1.PostsService.DeletePost:output_type -> posts.v1.DeletePostResponse
-	8,  // 14: posts.v1.PostsService.GetPost:output_type -> posts.v1.GetPostResponse
-	10, // 15: posts.v1.PostsService.ListPosts:output_type -> posts.v1.ListPostsResponse
-	11, // [11:16] is the sub-list for method output_type
-	6,  // [6:11] is the sub-list for method input_type
+	12, // 10: posts.v1.PostsService.ListPosts:input_type -> posts.v1.ListPostsRequest
+	10, // 11: posts.v1.PostsService.DeleteUserPosts:input_type -> posts.v1.DeleteUserPostsRequest
+	9,  // 12: posts.v1.PostsService.ModerateDeletePost:input_type -> posts.v1.ModerateDeletePostRequest
+	2,  // 13: posts.v1.PostsService.CreatePost:output_type -> posts.v1.CreatePostResponse
+	4,  // 14: posts.v1.PostsService.UpdatePost:output_type -> posts.v1.UpdatePostResponse
+	6,  // 15: posts.v1.PostsService.DeletePost:output_type -> posts.v1.DeletePostResponse
+	8,  // 16: posts.v1.PostsService.GetPost:output_type -> posts.v1.GetPostResponse
+	13, // 17: posts.v1.PostsService.ListPosts:output_type -> posts.v1.ListPostsResponse
+	11, // 18: posts.v1.PostsService.DeleteUserPosts:output_type -> posts.v1.DeleteUserPostsResponse
+	6,  // 19: posts.v1.PostsService.ModerateDeletePost:output_type -> posts.v1.DeletePostResponse
+	13, // [13:20] is the sub-list for method output_type
+	6,  // [6:13] is the sub-list for method input_type
 	6,  // [6:6] is the sub-list for extension type_name
 	6,  // [6:6] is the sub-list for extension extendee
 	0,  // [0:6] is the sub-list for field type_name
@@ -760,7 +911,7 @@ func file_proto_posts_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_posts_proto_rawDesc), len(file_proto_posts_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   11,
+			NumMessages:   14,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -19,11 +19,13 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	PostsService_CreatePost_FullMethodName = "/posts.v1.PostsService/CreatePost"
-	PostsService_UpdatePost_FullMethodName = "/posts.v1.PostsService/UpdatePost"
-	PostsService_DeletePost_FullMethodName = "/posts.v1.PostsService/DeletePost"
-	PostsService_GetPost_FullMethodName    = "/posts.v1.PostsService/GetPost"
-	PostsService_ListPosts_FullMethodName  = "/posts.v1.PostsService/ListPosts"
+	PostsService_CreatePost_FullMethodName         = "/posts.v1.PostsService/CreatePost"
+	PostsService_UpdatePost_FullMethodName         = "/posts.v1.PostsService/UpdatePost"
+	PostsService_DeletePost_FullMethodName         = "/posts.v1.PostsService/DeletePost"
+	PostsService_GetPost_FullMethodName            = "/posts.v1.PostsService/GetPost"
+	PostsService_ListPosts_FullMethodName          = "/posts.v1.PostsService/ListPosts"
+	PostsService_DeleteUserPosts_FullMethodName    = "/posts.v1.PostsService/DeleteUserPosts"
+	PostsService_ModerateDeletePost_FullMethodName = "/posts.v1.PostsService/ModerateDeletePost"
 )
 
 // PostsServiceClient is the client API for PostsService service.
@@ -35,6 +37,8 @@ type PostsServiceClient interface {
 	DeletePost(ctx context.Context, in *DeletePostRequest, opts ...grpc.CallOption) (*DeletePostResponse, error)
 	GetPost(ctx context.Context, in *GetPostRequest, opts ...grpc.CallOption) (*GetPostResponse, error)
 	ListPosts(ctx context.Context, in *ListPostsRequest, opts ...grpc.CallOption) (*ListPostsResponse, error)
+	DeleteUserPosts(ctx context.Context, in *DeleteUserPostsRequest, opts ...grpc.CallOption) (*DeleteUserPostsResponse, error)
+	ModerateDeletePost(ctx context.Context, in *ModerateDeletePostRequest, opts ...grpc.CallOption) (*DeletePostResponse, error)
 }
 
 type postsServiceClient struct {
@@ -95,6 +99,26 @@ func (c *postsServiceClient) ListPosts(ctx context.Context, in *ListPostsRequest
 	return out, nil
 }
 
+func (c *postsServiceClient) DeleteUserPosts(ctx context.Context, in *DeleteUserPostsRequest, opts ...grpc.CallOption) (*DeleteUserPostsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteUserPostsResponse)
+	err := c.cc.Invoke(ctx, PostsService_DeleteUserPosts_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *postsServiceClient) ModerateDeletePost(ctx context.Context, in *ModerateDeletePostRequest, opts ...grpc.CallOption) (*DeletePostResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeletePostResponse)
+	err := c.cc.Invoke(ctx, PostsService_ModerateDeletePost_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // PostsServiceServer is the server API for PostsService service.
 // All implementations must embed UnimplementedPostsServiceServer
 // for forward compatibility.
@@ -104,6 +128,8 @@ type PostsServiceServer interface {
 	DeletePost(context.Context, *DeletePostRequest) (*DeletePostResponse, error)
 	GetPost(context.Context, *GetPostRequest) (*GetPostResponse, error)
 	ListPosts(context.Context, *ListPostsRequest) (*ListPostsResponse, error)
+	DeleteUserPosts(context.Context, *DeleteUserPostsRequest) (*DeleteUserPostsResponse, error)
+	ModerateDeletePost(context.Context, *ModerateDeletePostRequest) (*DeletePostResponse, error)
 	mustEmbedUnimplementedPostsServiceServer()
 }
 
@@ -129,6 +155,12 @@ func (UnimplementedPostsServiceServer) GetPost(context.Context, *GetPostRequest)
 func (UnimplementedPostsServiceServer) ListPosts(context.Context, *ListPostsRequest) (*ListPostsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListPosts not implemented")
 }
+func (UnimplementedPostsServiceServer) DeleteUserPosts(context.Context, *DeleteUserPostsRequest) (*DeleteUserPostsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteUserPosts not implemented")
+}
+func (UnimplementedPostsServiceServer) ModerateDeletePost(context.Context, *ModerateDeletePostRequest) (*DeletePostResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ModerateDeletePost not implemented")
+}
 func (UnimplementedPostsServiceServer) mustEmbedUnimplementedPostsServiceServer() {}
 func (UnimplementedPostsServiceServer) testEmbeddedByValue()                      {}
 
@@ -240,6 +272,42 @@ func _PostsService_ListPosts_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _PostsService_DeleteUserPosts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteUserPostsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PostsServiceServer).DeleteUserPosts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PostsService_DeleteUserPosts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PostsServiceServer).DeleteUserPosts(ctx, req.(*DeleteUserPostsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PostsService_ModerateDeletePost_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ModerateDeletePostRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PostsServiceServer).ModerateDeletePost(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PostsService_ModerateDeletePost_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PostsServiceServer).ModerateDeletePost(ctx, req.(*ModerateDeletePostRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // PostsService_ServiceDesc is the grpc.ServiceDesc for PostsService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -267,6 +335,14 @@ var PostsService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ListPosts",
 			Handler:    _PostsService_ListPosts_Handler,
 		},
+		{
+			MethodName: "DeleteUserPosts",
+			Handler:    _PostsService_DeleteUserPosts_Handler,
+		},
+		{
+			MethodName: "ModerateDeletePost",
+			Handler:    _PostsService_ModerateDeletePost_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/posts.proto",
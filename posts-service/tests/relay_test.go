@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"posts-service/internal/app"
+	"posts-service/internal/db"
+)
+
+type stubOutbox struct {
+	mu     sync.Mutex
+	events []db.OutboxEvent
+}
+
+func (s *stubOutbox) PendingOutbox(_ context.Context, limit int) ([]db.OutboxEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []db.OutboxEvent
+	for _, e := range s.events {
+		if e.PublishedAt == nil && len(out) < limit {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func (s *stubOutbox) MarkOutboxPublished(_ context.Context, ids []bson.ObjectID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for i := range s.events {
+		if slices.Contains(ids, s.events[i].ID) {
+			s.events[i].PublishedAt = &now
+		}
+	}
+	return nil
+}
+
+func (s *stubOutbox) pending() int {
+	events, _ := s.PendingOutbox(context.Background(), len(s.events))
+	return len(events)
+}
+
+type flakyWriter struct {
+	mu       sync.Mutex
+	failures int
+	messages []kafka.Message
+}
+
+func (w *flakyWriter) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.failures > 0 {
+		w.failures--
+		return errors.New("kafka unavailable")
+	}
+	w.messages = append(w.messages, msgs...)
+	return nil
+}
+
+func TestRelayPublishesOutboxInOrderAfterFailure(t *testing.T) {
+	store := &stubOutbox{}
+	for _, e := range []struct{ typ, postID string }{
+		{db.EventPostCreated, "p1"},
+		{db.EventPostCreated, "p2"},
+		{db.EventPostUpdated, "p1"},
+		{db.EventPostDeleted, "p1"},
+	} {
+		store.events = append(store.events, db.OutboxEvent{
+			ID:      bson.NewObjectID(),
+			Type:    e.typ,
+			PostID:  e.postID,
+			Payload: []byte(`{"event_type":"` + e.typ + `"}`),
+		})
+	}
+	writer := &flakyWriter{failures: 1}
+	relay := &app.Relay{Store: store, Writer: writer, Topic: "post_events", BatchSize: 3, PollInterval: 10 * time.Millisecond}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go relay.Run(ctx)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for store.pending() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("outbox not drained, %d events pending", store.pending())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+	if len(writer.messages) != 4 {
+		t.Fatalf("expected 4 messages, got %d", len(writer.messages))
+	}
+	for i, msg := range writer.messages {
+		e := store.events[i]
+		if msg.Topic != "post_events" || string(msg.Key) != e.PostID || string(msg.Value) != string(e.Payload) {
+			t.Fatalf("message %d does not match outbox event %+v: %+v", i, e, msg)
+		}
+		if len(msg.Headers) != 1 || string(msg.Headers[0].Value) != e.Type {
+			t.Fatalf("message %d has unexpected headers %+v", i, msg.Headers)
+		}
+	}
+}
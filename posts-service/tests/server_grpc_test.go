@@ -1,8 +1,10 @@
 package tests
 
 import (
+	"context"
 	"testing"
 
+	"common/identity"
 	"posts-service/internal/app"
 	"posts-service/internal/db"
 	pb "posts-service/proto"
@@ -17,6 +19,8 @@ func (s *updateStub) Create(db.Post) (db.Post, error)                     { retu
 func (s *updateStub) Delete(string, string) error                         { return nil }
 func (s *updateStub) Get(string, string) (db.Post, error)                 { return db.Post{}, nil }
 func (s *updateStub) List(string, int64, int64) ([]db.Post, int64, error) { return nil, 0, nil }
+func (s *updateStub) DeleteByOwner(string, bool) (int64, error)           { return 0, nil }
+func (s *updateStub) DeleteAny(string) error                              { return nil }
 func (s *updateStub) Update(string, string, string, string) (db.Post, error) {
 	return db.Post{}, db.ErrNotFound
 }
@@ -24,7 +28,8 @@ func (s *updateStub) Update(string, string, string, string) (db.Post, error) {
 func TestServerUpdatePostNotFound(t *testing.T) {
 	srv := &app.Server{DB: &updateStub{}}
 
-	_, err := srv.UpdatePost(nil, &pb.UpdatePostRequest{Id: "missing", UserId: "user"})
+	ctx := identity.WithCaller(context.Background(), identity.Caller{Service: "main-service", UserID: "user"})
+	_, err := srv.UpdatePost(ctx, &pb.UpdatePostRequest{Id: "missing"})
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
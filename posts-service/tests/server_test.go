@@ -1,16 +1,23 @@
 package tests
 
 import (
+	"context"
 	"errors"
 	"testing"
 
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"common/identity"
 	"posts-service/internal/app"
 	"posts-service/internal/db"
 	pb "posts-service/proto"
 )
 
 type stubPostStore struct {
-	createFn func(db.Post) (db.Post, error)
+	createFn        func(db.Post) (db.Post, error)
+	deleteByOwnerFn func(string, bool) (int64, error)
+	deleteAnyFn     func(string) error
 }
 
 func (s *stubPostStore) Create(p db.Post) (db.Post, error) {
@@ -34,6 +41,20 @@ func (s *stubPostStore) List(string, int64, int64) ([]db.Post, int64, error) {
 	return nil, 0, errors.New("not implemented")
 }
 
+func (s *stubPostStore) DeleteByOwner(ownerID string, anonymize bool) (int64, error) {
+	if s.deleteByOwnerFn != nil {
+		return s.deleteByOwnerFn(ownerID, anonymize)
+	}
+	return 0, errors.New("not implemented")
+}
+
+func (s *stubPostStore) DeleteAny(id string) error {
+	if s.deleteAnyFn != nil {
+		return s.deleteAnyFn(id)
+	}
+	return errors.New("not implemented")
+}
+
 func TestToPBConversion(t *testing.T) {
 	post := db.Post{ID: "id1", OwnerID: "user1", Title: "hello", Content: "world"}
 	pbPost := app.ToPBForTest(post)
@@ -59,7 +80,8 @@ func TestServerCreatePost(t *testing.T) {
 
 	srv := &app.Server{DB: store}
 
-	resp, err := srv.CreatePost(nil, &pb.CreatePostRequest{UserId: "user42", Title: "t", Content: "c"})
+	ctx := identity.WithCaller(context.Background(), identity.Caller{Service: "main-service", UserID: "user42"})
+	resp, err := srv.CreatePost(ctx, &pb.CreatePostRequest{UserId: "spoofed", Title: "t", Content: "c"})
 	if err != nil {
 		t.Fatalf("CreatePost returned error: %v", err)
 	}
@@ -70,3 +92,67 @@ func TestServerCreatePost(t *testing.T) {
 		t.Fatalf("expected generated id, got %q", resp.GetPost().GetId())
 	}
 }
+
+func TestServerCreatePostRequiresUser(t *testing.T) {
+	srv := &app.Server{DB: &stubPostStore{}}
+
+	ctx := identity.WithCaller(context.Background(), identity.Caller{Service: "stats-service"})
+	_, err := srv.CreatePost(ctx, &pb.CreatePostRequest{UserId: "user42", Title: "t"})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated without a user identity, got %v", err)
+	}
+}
+
+func TestServerDeleteUserPosts(t *testing.T) {
+	var gotOwner string
+	var gotAnonymize bool
+	store := &stubPostStore{deleteByOwnerFn: func(ownerID string, anonymize bool) (int64, error) {
+		gotOwner, gotAnonymize = ownerID, anonymize
+		return 3, nil
+	}}
+	srv := &app.Server{DB: store}
+
+	ctx := identity.WithCaller(context.Background(), identity.Caller{Service: "main-service", UserID: "user42"})
+	resp, err := srv.DeleteUserPosts(ctx, &pb.DeleteUserPostsRequest{Anonymize: true})
+	if err != nil {
+		t.Fatalf("DeleteUserPosts returned error: %v", err)
+	}
+	if gotOwner != "user42" || !gotAnonymize || resp.GetAffected() != 3 {
+		t.Fatalf("unexpected call owner=%q anonymize=%v affected=%d", gotOwner, gotAnonymize, resp.GetAffected())
+	}
+
+	ctx = identity.WithCaller(context.Background(), identity.Caller{Service: "stats-service"})
+	if _, err := srv.DeleteUserPosts(ctx, &pb.DeleteUserPostsRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated without a user identity, got %v", err)
+	}
+}
+
+func TestServerModerateDeletePost(t *testing.T) {
+	var deleted string
+	srv := &app.Server{DB: &stubPostStore{deleteAnyFn: func(id string) error {
+		if id == "missing" {
+			return db.ErrNotFound
+		}
+		deleted = id
+		return nil
+	}}}
+
+	for _, tc := range []struct {
+		caller identity.Caller
+		id     string
+		want   codes.Code
+	}{
+		{identity.Caller{Service: "main-service"}, "p1", codes.Unauthenticated},
+		{identity.Caller{Service: "main-service", UserID: "u1", Role: "user"}, "p1", codes.PermissionDenied},
+		{identity.Caller{Service: "main-service", UserID: "u1", Role: "moderator"}, "missing", codes.NotFound},
+		{identity.Caller{Service: "main-service", UserID: "u1", Role: "admin"}, "p1", codes.OK},
+	} {
+		_, err := srv.ModerateDeletePost(identity.WithCaller(context.Background(), tc.caller), &pb.ModerateDeletePostRequest{Id: tc.id})
+		if status.Code(err) != tc.want {
+			t.Fatalf("%+v: expected %v, got %v", tc.caller, tc.want, err)
+		}
+	}
+	if deleted != "p1" {
+		t.Fatalf("expected p1 to be deleted, got %q", deleted)
+	}
+}
@@ -4,14 +4,30 @@ import (
 	"context"
 	"log"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
+	"common/mtls"
 	"stats-service/internal/app"
 )
 
 func main() {
 	ctx := context.Background()
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(ctx, os.Args[2:]); err != nil {
+			log.Fatalf("migrate failed: %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		if err := runReplay(ctx, os.Args[2:]); err != nil {
+			log.Fatalf("replay failed: %v", err)
+		}
+		return
+	}
+
 	cfg := app.Config{
 		HTTPAddr:           ":8081",
 		GRPCAddr:           ":9090",
@@ -19,11 +35,45 @@ func main() {
 		ClickHouseDB:       env("CLICKHOUSE_DB", "stats"),
 		ClickHouseUser:     env("CLICKHOUSE_USER", "default"),
 		ClickHousePassword: os.Getenv("CLICKHOUSE_PASSWORD"),
+		StorageBackend:     env("STATS_STORAGE", "clickhouse"),
+		StoragePath:        env("STATS_STORAGE_PATH", "stats-events.jsonl"),
+		SkipMigrations:     os.Getenv("CLICKHOUSE_SKIP_MIGRATIONS") == "true",
+		RawEventsTTLDays:   envInt("RAW_EVENTS_TTL_DAYS", 90),
+		HourlyStatsTTLDays: envInt("HOURLY_STATS_TTL_DAYS", 365),
 		KafkaBrokers:       splitAndClean(env("KAFKA_BROKERS", "kafka:9092")),
 		KafkaGroupID:       "stats-service",
 		ViewsTopic:         env("KAFKA_VIEWS_TOPIC", "post_views"),
 		LikesTopic:         env("KAFKA_LIKES_TOPIC", "post_likes"),
 		PostsServiceAddr:   env("POSTS_SERVICE_ADDR", "posts-service:50051"),
+		PostEventsTopic:    env("KAFKA_POST_EVENTS_TOPIC", "post_events"),
+		UserEventsTopic:    env("KAFKA_USER_EVENTS_TOPIC", "user_events"),
+		ConsumerWorkers:    envInt("CONSUMER_WORKERS", 4),
+		WatchInterval:      envDuration("WATCH_INTERVAL", time.Second),
+		WatchPollInterval:  envDuration("WATCH_POLL_INTERVAL", 5*time.Second),
+		TrendingWindow:     envDuration("TRENDING_WINDOW", 72*time.Hour),
+		TrendingHalfLife:   envDuration("TRENDING_HALF_LIFE", 6*time.Hour),
+		TrendingLikeWeight: envFloat("TRENDING_LIKE_WEIGHT", 3),
+
+		RecommendationInterval: envDuration("RECOMMENDATION_INTERVAL", time.Hour),
+		RecommendationWindow:   envDuration("RECOMMENDATION_WINDOW", 30*24*time.Hour),
+		RelatedPostsTopN:       envInt("RELATED_POSTS_TOP_N", 20),
+
+		DeletedPostsPurgeInterval: envDuration("DELETED_POSTS_PURGE_INTERVAL", 10*time.Minute),
+		PostsReconcileInterval:    envDuration("POSTS_RECONCILE_INTERVAL", 24*time.Hour),
+
+		InternalTokenSecret: os.Getenv("INTERNAL_TOKEN_SECRET"),
+		TLS: mtls.Config{
+			CertFile: os.Getenv("TLS_CERT_FILE"),
+			KeyFile:  os.Getenv("TLS_KEY_FILE"),
+			CAFile:   os.Getenv("TLS_CA_FILE"),
+			DevDir:   os.Getenv("TLS_DEV_DIR"),
+		},
+		TLSReloadInterval: envDuration("TLS_RELOAD_INTERVAL", time.Minute),
+
+		FraudWindow:      envDuration("FRAUD_WINDOW", time.Minute),
+		FraudViewerLimit: envInt("FRAUD_VIEWER_LIMIT", 30),
+		FraudPostLimit:   envInt("FRAUD_POST_LIMIT", 600),
+		FraudLikeLimit:   envInt("FRAUD_LIKE_LIMIT", 10),
 	}
 
 	if err := app.Run(ctx, cfg); err != nil {
@@ -38,6 +88,42 @@ func env(key, fallback string) string {
 	return fallback
 }
 
+func envInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("invalid %s: %v", key, err)
+	}
+	return n
+}
+
+func envFloat(key string, fallback float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Fatalf("invalid %s: %v", key, err)
+	}
+	return f
+}
+
+func envDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("invalid %s: %v", key, err)
+	}
+	return d
+}
+
 func splitAndClean(value string) []string {
 	parts := strings.Split(value, ",")
 	result := make([]string, 0, len(parts))
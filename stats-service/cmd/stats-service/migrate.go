@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"stats-service/internal/storage"
+)
+
+// runMigrate implements "stats-service migrate [up|down|status] [flags]".
+func runMigrate(ctx context.Context, args []string) error {
+	command := "up"
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		command, args = args[0], args[1:]
+	}
+
+	fs := flag.NewFlagSet("migrate "+command, flag.ContinueOnError)
+	target := fs.Int("to", 0, "migrate up to this version (default: latest)")
+	steps := fs.Int("steps", 1, "number of migrations to revert with down")
+	dryRun := fs.Bool("dry-run", false, "print the statements without executing them")
+	force := fs.Bool("force", false, "revert migrations even if that loses data")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	repo, err := storage.New(ctx, storage.Config{
+		Addr:           []string{env("CLICKHOUSE_ADDR", "stats-clickhouse:9000")},
+		DB:             env("CLICKHOUSE_DB", "stats"),
+		User:           env("CLICKHOUSE_USER", "default"),
+		Password:       os.Getenv("CLICKHOUSE_PASSWORD"),
+		SkipMigrations: true,
+	})
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	var (
+		plan      []storage.Migration
+		direction string
+	)
+	switch command {
+	case "up":
+		direction = "up"
+		plan, err = repo.MigrateUp(ctx, *target, *dryRun)
+	case "down":
+		direction = "down"
+		plan, err = repo.MigrateDown(ctx, *steps, *dryRun, *force)
+	case "status":
+		return printMigrationStatus(ctx, repo)
+	case "unlock":
+		// For a lock left by a migrator that died, without waiting for
+		// it to go stale.
+		if err := repo.UnlockMigrations(ctx); err != nil {
+			return err
+		}
+		fmt.Println("migration lock released")
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q", command)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(plan) == 0 {
+		fmt.Println("no migrations to apply")
+		return nil
+	}
+	for _, m := range plan {
+		fmt.Printf("%s %04d_%s\n", direction, m.Version, m.Name)
+		if direction == "down" && m.Destructive != "" {
+			fmt.Printf("  warning: %s\n", m.Destructive)
+		}
+		if !*dryRun {
+			continue
+		}
+		stmts := m.Up
+		if direction == "down" {
+			stmts = m.Down
+		}
+		for _, stmt := range repo.Statements(stmts) {
+			fmt.Printf("%s;\n\n", stmt)
+		}
+	}
+	return nil
+}
+
+func printMigrationStatus(ctx context.Context, repo *storage.Repository) error {
+	all, err := storage.Migrations()
+	if err != nil {
+		return err
+	}
+	applied, err := repo.AppliedMigrations(ctx)
+	if err != nil {
+		return err
+	}
+	done := make(map[int]bool, len(applied))
+	for _, v := range applied {
+		done[v] = true
+	}
+	for _, m := range all {
+		state := "pending"
+		if done[m.Version] {
+			state = "applied"
+		}
+		fmt.Printf("%04d_%s\t%s\n", m.Version, m.Name, state)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"stats-service/internal/app"
+)
+
+// runReplay implements "stats-service replay [-from earliest|RFC3339] [-progress 10s]".
+func runReplay(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	from := fs.String("from", "earliest", `replay events since this RFC3339 time, or "earliest"`)
+	progress := fs.Duration("progress", 10*time.Second, "interval between progress reports")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	opts := app.ReplayOptions{ProgressInterval: *progress}
+	if *from != "earliest" {
+		t, err := time.Parse(time.RFC3339, *from)
+		if err != nil {
+			return fmt.Errorf("invalid -from: %w", err)
+		}
+		opts.From = t
+	}
+
+	return app.Replay(ctx, app.Config{
+		ClickHouseAddr:     []string{env("CLICKHOUSE_ADDR", "stats-clickhouse:9000")},
+		ClickHouseDB:       env("CLICKHOUSE_DB", "stats"),
+		ClickHouseUser:     env("CLICKHOUSE_USER", "default"),
+		ClickHousePassword: os.Getenv("CLICKHOUSE_PASSWORD"),
+		SkipMigrations:     os.Getenv("CLICKHOUSE_SKIP_MIGRATIONS") == "true",
+		KafkaBrokers:       splitAndClean(env("KAFKA_BROKERS", "kafka:9092")),
+		KafkaGroupID:       "stats-service",
+		ViewsTopic:         env("KAFKA_VIEWS_TOPIC", "post_views"),
+		LikesTopic:         env("KAFKA_LIKES_TOPIC", "post_likes"),
+		FraudWindow:        envDuration("FRAUD_WINDOW", time.Minute),
+		FraudViewerLimit:   envInt("FRAUD_VIEWER_LIMIT", 30),
+		FraudPostLimit:     envInt("FRAUD_POST_LIMIT", 600),
+		FraudLikeLimit:     envInt("FRAUD_LIKE_LIMIT", 10),
+	}, opts)
+}
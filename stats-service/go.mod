@@ -3,7 +3,10 @@ module stats-service
 go 1.25.0
 
 require (
+	common v0.0.0-00010101000000-000000000000
 	github.com/ClickHouse/clickhouse-go/v2 v2.40.3
+	github.com/golang-jwt/jwt/v5 v5.3.0
+	github.com/parquet-go/parquet-go v0.25.1
 	github.com/segmentio/kafka-go v0.4.49
 	google.golang.org/grpc v1.76.0
 	google.golang.org/protobuf v1.36.6
@@ -30,4 +33,7 @@ require (
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20250804133106-a7a43d27e69b // indirect
 )
 
-replace posts-service => ../posts-service
+replace (
+	common => ../common
+	posts-service => ../posts-service
+)
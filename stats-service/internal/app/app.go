@@ -2,7 +2,6 @@ package app
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
@@ -18,6 +17,8 @@ import (
 	postspb "posts-service/proto"
 	statspb "stats-service/proto"
 
+	"common/identity"
+	"common/mtls"
 	"stats-service/internal/storage"
 )
 
@@ -28,21 +29,80 @@ type Config struct {
 	ClickHouseDB       string
 	ClickHouseUser     string
 	ClickHousePassword string
+	// StorageBackend selects where events are kept: "clickhouse" (default),
+	// "memory", or "file", which appends them to StoragePath. The last two
+	// need no ClickHouse and are meant for local development and CI.
+	StorageBackend string
+	StoragePath    string
+	SkipMigrations bool
+	// RawEventsTTLDays and HourlyStatsTTLDays configure retention of raw
+	// events and hourly rollups; zero disables expiry. Daily rollups are
+	// kept indefinitely so totals stay correct.
+	RawEventsTTLDays   int
+	HourlyStatsTTLDays int
 	KafkaBrokers       []string
 	KafkaGroupID       string
 	ViewsTopic         string
 	LikesTopic         string
-	PostsServiceAddr   string
+	// PostEventsTopic carries post lifecycle events from posts-service;
+	// deleted posts are dropped from rankings and purged every
+	// DeletedPostsPurgeInterval. Every PostsReconcileInterval, posts with
+	// stats are also checked against posts-service; zero disables it.
+	PostEventsTopic string
+	// UserEventsTopic carries account events from main-service; the data
+	// of deleted users is erased.
+	UserEventsTopic           string
+	DeletedPostsPurgeInterval time.Duration
+	PostsReconcileInterval    time.Duration
+	PostsServiceAddr          string
+	// InternalTokenSecret signs and verifies the identity tokens of gRPC
+	// calls between services; see package identity.
+	InternalTokenSecret string
+	// TLS enables mutual TLS for the gRPC server and the posts-service
+	// client. Certificates are re-read every TLSReloadInterval.
+	TLS               mtls.Config
+	TLSReloadInterval time.Duration
+	// ConsumerWorkers is the number of goroutines storing events per topic.
+	// A partition is always handled by the same worker.
+	ConsumerWorkers int
+	// WatchInterval is the minimum delay between two WatchPostStats updates
+	// sent to a subscriber. Events stored by other replicas are picked up
+	// by polling every WatchPollInterval.
+	WatchInterval     time.Duration
+	WatchPollInterval time.Duration
+	// Trending ranking: events older than TrendingWindow are ignored and the
+	// weight of the rest halves every TrendingHalfLife. A like counts as
+	// TrendingLikeWeight views.
+	TrendingWindow     time.Duration
+	TrendingHalfLife   time.Duration
+	TrendingLikeWeight float64
+	// Co-like recommendations are rebuilt every RecommendationInterval from
+	// likes in the last RecommendationWindow, keeping RelatedPostsTopN
+	// neighbours per post.
+	RecommendationInterval time.Duration
+	RecommendationWindow   time.Duration
+	RelatedPostsTopN       int
+	// Fraud detection: within FraudWindow, events beyond FraudViewerLimit
+	// per viewer, FraudPostLimit views per post or FraudLikeLimit likes per
+	// viewer are held back for review. Zero picks the default, a negative
+	// limit disables the rule.
+	FraudWindow      time.Duration
+	FraudViewerLimit int
+	FraudPostLimit   int
+	FraudLikeLimit   int
 }
 
 type event struct {
 	PostID    string    `json:"post_id"`
 	EventType string    `json:"event_type"`
+	UserID    string    `json:"user_id"`
+	IP        string    `json:"ip"`
 	Timestamp time.Time `json:"timestamp"`
 }
 
 type kafkaMessageReader interface {
-	ReadMessage(context.Context) (kafka.Message, error)
+	FetchMessage(context.Context) (kafka.Message, error)
+	CommitMessages(context.Context, ...kafka.Message) error
 	Close() error
 }
 
@@ -54,7 +114,49 @@ type statsRepository interface {
 	SaveEvent(ctx context.Context, e storage.Event) error
 	PostStats(ctx context.Context, postID string) (int64, int64, error)
 	TopPosts(ctx context.Context, eventType string, limit int) ([]storage.PostCount, error)
+	TrendingPosts(ctx context.Context, window, halfLife time.Duration, likeWeight float64, limit int) ([]storage.PostScore, error)
 	LikesPerPost(ctx context.Context) ([]storage.PostCount, error)
+	RelatedPosts(ctx context.Context, postID string, limit int) ([]storage.PostScore, error)
+	AuthorStats(ctx context.Context, postIDs []string, since time.Time) (storage.AuthorStats, error)
+	ExportEvents(ctx context.Context, filter storage.ExportFilter, fn func(storage.ExportRow) error) error
+	FlagEvent(ctx context.Context, f storage.FlaggedEvent) error
+	FlaggedEvents(ctx context.Context, status, postID string, limit int) ([]storage.FlaggedEvent, error)
+	ReviewFlaggedEvent(ctx context.Context, id string, approve bool, reviewer string) (storage.FlaggedEvent, error)
+	UserEvents(ctx context.Context, userID string, fn func(storage.Event) error) error
+	UserFlaggedEvents(ctx context.Context, userID string) ([]storage.FlaggedEvent, error)
+}
+
+// repository is a statsRepository that also rebuilds recommendations and
+// owns its connection.
+type repository interface {
+	statsRepository
+	relatedPostsBuilder
+	deletedPostsStore
+	userDataStore
+	Close() error
+}
+
+func openRepository(ctx context.Context, cfg Config) (repository, error) {
+	switch cfg.StorageBackend {
+	case "", "clickhouse":
+		return storage.New(ctx, storage.Config{
+			Addr:               cfg.ClickHouseAddr,
+			DB:                 cfg.ClickHouseDB,
+			User:               cfg.ClickHouseUser,
+			Password:           cfg.ClickHousePassword,
+			SkipMigrations:     cfg.SkipMigrations,
+			RawEventsTTLDays:   cfg.RawEventsTTLDays,
+			HourlyStatsTTLDays: cfg.HourlyStatsTTLDays,
+		})
+	case "memory":
+		return storage.NewMemory(), nil
+	case "file":
+		if cfg.StoragePath == "" {
+			return nil, fmt.Errorf("file storage needs a path")
+		}
+		return storage.OpenFile(cfg.StoragePath)
+	}
+	return nil, fmt.Errorf("unknown storage backend %q", cfg.StorageBackend)
 }
 
 func Run(ctx context.Context, cfg Config) error {
@@ -82,17 +184,44 @@ func Run(ctx context.Context, cfg Config) error {
 	if cfg.LikesTopic == "" {
 		cfg.LikesTopic = "post_likes"
 	}
+	if cfg.PostEventsTopic == "" {
+		cfg.PostEventsTopic = "post_events"
+	}
+	if cfg.UserEventsTopic == "" {
+		cfg.UserEventsTopic = "user_events"
+	}
 	if len(cfg.KafkaBrokers) == 0 {
 		return fmt.Errorf("no kafka brokers configured")
 	}
+	if cfg.DeletedPostsPurgeInterval <= 0 {
+		cfg.DeletedPostsPurgeInterval = 10 * time.Minute
+	}
 	if cfg.PostsServiceAddr == "" {
 		cfg.PostsServiceAddr = "posts-service:50051"
 	}
+	if cfg.InternalTokenSecret == "" {
+		return fmt.Errorf("no internal token secret configured")
+	}
+	if cfg.TLSReloadInterval <= 0 {
+		cfg.TLSReloadInterval = time.Minute
+	}
+	if cfg.ConsumerWorkers <= 0 {
+		cfg.ConsumerWorkers = 4
+	}
+	if cfg.RecommendationInterval <= 0 {
+		cfg.RecommendationInterval = time.Hour
+	}
+	if cfg.RecommendationWindow <= 0 {
+		cfg.RecommendationWindow = 30 * 24 * time.Hour
+	}
+
+	metrics := newConsumerMetrics()
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
+	mux.Handle("/metrics", metrics)
 
 	srv := &http.Server{
 		Addr:    cfg.HTTPAddr,
@@ -120,26 +249,56 @@ func Run(ctx context.Context, cfg Config) error {
 		}
 	}()
 
-	repo, err := storage.New(ctx, storage.Config{
-		Addr:     cfg.ClickHouseAddr,
-		DB:       cfg.ClickHouseDB,
-		User:     cfg.ClickHouseUser,
-		Password: cfg.ClickHousePassword,
-	})
+	repo, err := openRepository(ctx, cfg)
 	if err != nil {
 		return fmt.Errorf("storage init failed: %w", err)
 	}
 	defer repo.Close()
 
-	postsConn, err := grpc.NewClient(cfg.PostsServiceAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	dialTransport := grpc.WithTransportCredentials(insecure.NewCredentials())
+	var serverOpts []grpc.ServerOption
+	if cfg.TLS.Enabled() {
+		if cfg.TLS.Name == "" {
+			cfg.TLS.Name = "stats-service"
+		}
+		creds, err := mtls.Load(cfg.TLS)
+		if err != nil {
+			grpcListener.Close()
+			return fmt.Errorf("tls init failed: %w", err)
+		}
+		go creds.Watch(ctx, cfg.TLSReloadInterval)
+		dialTransport = creds.DialOption()
+		serverOpts = append(serverOpts, creds.ServerOption())
+	}
+
+	signer := identity.NewSigner(cfg.InternalTokenSecret, "stats-service")
+	postsConn, err := grpc.NewClient(cfg.PostsServiceAddr,
+		dialTransport,
+		grpc.WithChainUnaryInterceptor(signer.UnaryClientInterceptor("posts-service")),
+		grpc.WithChainStreamInterceptor(signer.StreamClientInterceptor("posts-service")),
+	)
 	if err != nil {
 		grpcListener.Close()
 		return fmt.Errorf("posts client init failed: %w", err)
 	}
 	defer postsConn.Close()
 
-	grpcSrv := grpc.NewServer()
-	statspb.RegisterStatsServiceServer(grpcSrv, newStatsServer(repo, postspb.NewPostsServiceClient(postsConn)))
+	hub := newStatsHub()
+	posts := postspb.NewPostsServiceClient(postsConn)
+
+	statsSrv := newStatsServer(repo, posts, hub, cfg.WatchInterval)
+	if cfg.WatchPollInterval > 0 {
+		statsSrv.watchPoll = cfg.WatchPollInterval
+	}
+	statsSrv.trending = newTrendingConfig(cfg.TrendingWindow, cfg.TrendingHalfLife, cfg.TrendingLikeWeight)
+
+	verifier := identity.NewVerifier(cfg.InternalTokenSecret, "stats-service")
+	mux.Handle("/export/events", exportHandler(statsSrv, verifier))
+	grpcSrv := grpc.NewServer(append(serverOpts,
+		grpc.ChainUnaryInterceptor(verifier.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(verifier.StreamServerInterceptor()),
+	)...)
+	statspb.RegisterStatsServiceServer(grpcSrv, statsSrv)
 
 	go func() {
 		if err := grpcSrv.Serve(grpcListener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
@@ -152,21 +311,48 @@ func Run(ctx context.Context, cfg Config) error {
 		grpcSrv.GracefulStop()
 	}()
 
+	cons := &consumer{
+		repo:     repo,
+		hub:      hub,
+		detector: newFraudDetector(newFraudRules(cfg.FraudWindow, cfg.FraudViewerLimit, cfg.FraudPostLimit, cfg.FraudLikeLimit)),
+		metrics:  metrics,
+		workers:  cfg.ConsumerWorkers,
+	}
+
 	var wg sync.WaitGroup
-	wg.Add(2)
+	wg.Add(6)
 
-	go consumeTopic(ctx, &wg, repo, kafka.ReaderConfig{
-		Brokers: cfg.KafkaBrokers,
-		Topic:   cfg.ViewsTopic,
-		GroupID: cfg.KafkaGroupID,
+	go cons.consumeTopic(ctx, &wg, kafka.ReaderConfig{
+		Brokers:        cfg.KafkaBrokers,
+		Topic:          cfg.ViewsTopic,
+		GroupID:        cfg.KafkaGroupID,
+		CommitInterval: time.Second,
 	}, "view")
 
-	go consumeTopic(ctx, &wg, repo, kafka.ReaderConfig{
-		Brokers: cfg.KafkaBrokers,
-		Topic:   cfg.LikesTopic,
-		GroupID: cfg.KafkaGroupID,
+	go cons.consumeTopic(ctx, &wg, kafka.ReaderConfig{
+		Brokers:        cfg.KafkaBrokers,
+		Topic:          cfg.LikesTopic,
+		GroupID:        cfg.KafkaGroupID,
+		CommitInterval: time.Second,
 	}, "like")
 
+	go consumePostEvents(ctx, &wg, repo, kafka.ReaderConfig{
+		Brokers:        cfg.KafkaBrokers,
+		Topic:          cfg.PostEventsTopic,
+		GroupID:        cfg.KafkaGroupID,
+		CommitInterval: time.Second,
+	})
+
+	go consumeUserEvents(ctx, &wg, repo, kafka.ReaderConfig{
+		Brokers:        cfg.KafkaBrokers,
+		Topic:          cfg.UserEventsTopic,
+		GroupID:        cfg.KafkaGroupID,
+		CommitInterval: time.Second,
+	})
+
+	go runRecommendations(ctx, &wg, repo, cfg.RecommendationInterval, cfg.RecommendationWindow, cfg.RelatedPostsTopN)
+	go runPostCleanup(ctx, &wg, repo, posts, cfg.DeletedPostsPurgeInterval, cfg.PostsReconcileInterval)
+
 	wgDone := make(chan struct{})
 	go func() {
 		wg.Wait()
@@ -187,14 +373,85 @@ func Run(ctx context.Context, cfg Config) error {
 	}
 }
 
-func consumeTopic(ctx context.Context, wg *sync.WaitGroup, repo statsRepository, cfg kafka.ReaderConfig, defaultType string) {
+// consumer stores the events of one or more topics. Each topic is read by a
+// single group reader whose messages are spread over workers by partition:
+// producers key messages by post ID, so a post's events stay in order while
+// different partitions are stored in parallel. Scaling beyond one instance
+// works by running more stats-service replicas in the same consumer group.
+type consumer struct {
+	repo     statsRepository
+	hub      *statsHub
+	detector *fraudDetector
+	metrics  *consumerMetrics
+	workers  int
+}
+
+const (
+	storeRetryBackoff    = 100 * time.Millisecond
+	maxStoreRetryBackoff = 10 * time.Second
+	shutdownDrainTimeout = 10 * time.Second
+)
+
+func (c *consumer) consumeTopic(ctx context.Context, wg *sync.WaitGroup, cfg kafka.ReaderConfig, defaultType string) {
 	defer wg.Done()
 
 	reader := newKafkaReader(cfg)
 	defer reader.Close()
 
+	// Workers outlive ctx so that messages already queued are stored on
+	// shutdown; workCtx is cancelled once shutdownDrainTimeout has passed.
+	workCtx, cancelWork := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelWork()
+
+	workers := max(c.workers, 1)
+	queues := make([]chan kafka.Message, workers)
+	var workersDone sync.WaitGroup
+	for i := range queues {
+		queues[i] = make(chan kafka.Message, 64)
+		workersDone.Add(1)
+		go func(queue <-chan kafka.Message) {
+			defer workersDone.Done()
+			failed := make(map[int]bool)
+			for msg := range queue {
+				// Committing a later offset would skip a message that was not
+				// stored, so a partition stops committing after a failure and
+				// is replayed from there on restart.
+				if failed[msg.Partition] {
+					continue
+				}
+				result := c.process(workCtx, msg, defaultType)
+				c.metrics.observe(msg, result)
+				if result == "failed" {
+					failed[msg.Partition] = true
+					continue
+				}
+				// Offsets are committed only after the event is stored, so
+				// a crash replays the message instead of losing it.
+				if err := reader.CommitMessages(workCtx, msg); err != nil && workCtx.Err() == nil {
+					log.Printf("commit kafka message failed: %v", err)
+				}
+			}
+		}(queues[i])
+	}
+	defer func() {
+		for _, queue := range queues {
+			close(queue)
+		}
+		drained := make(chan struct{})
+		go func() {
+			workersDone.Wait()
+			close(drained)
+		}()
+		select {
+		case <-drained:
+		case <-time.After(shutdownDrainTimeout):
+			cancelWork()
+			<-drained
+		}
+	}()
+
 	for {
-		msg, err := reader.ReadMessage(ctx)
+		msg, err := reader.FetchMessage(ctx)
 		if err != nil {
 			if ctx.Err() != nil {
 				return
@@ -203,29 +460,49 @@ func consumeTopic(ctx context.Context, wg *sync.WaitGroup, repo statsRepository,
 			time.Sleep(time.Second)
 			continue
 		}
-
-		var e event
-		if err := json.Unmarshal(msg.Value, &e); err != nil {
-			log.Printf("decode message failed: %v", err)
-			continue
-		}
-		if e.EventType == "" {
-			e.EventType = defaultType
-		}
-		if e.PostID == "" {
-			log.Printf("skip message without post_id")
-			continue
+		select {
+		case queues[msg.Partition%workers] <- msg:
+		case <-ctx.Done():
+			return
 		}
-		if e.Timestamp.IsZero() {
-			e.Timestamp = time.Now().UTC()
+	}
+}
+
+func (c *consumer) process(ctx context.Context, msg kafka.Message, defaultType string) string {
+	e, ip, ok := decodeEvent(msg, defaultType)
+	if !ok {
+		return "invalid"
+	}
+	if reason := c.detector.check(e, ip); reason != "" {
+		flag := storage.FlaggedEvent{ID: flagID(msg), Event: e, IP: ip, Reason: reason}
+		if err := retryStore(ctx, "flag event", func() error { return c.repo.FlagEvent(ctx, flag) }); err != nil {
+			return "failed"
 		}
+		return "flagged"
+	}
+	if err := retryStore(ctx, "save event", func() error { return c.repo.SaveEvent(ctx, e) }); err != nil {
+		return "failed"
+	}
+	c.hub.publish(e.PostID)
+	return "saved"
+}
 
-		if err := repo.SaveEvent(ctx, storage.Event{
-			EventType: e.EventType,
-			PostID:    e.PostID,
-			Timestamp: e.Timestamp,
-		}); err != nil {
-			log.Printf("save event failed: %v", err)
+// retryStore calls store until it succeeds, backing off between attempts.
+// It gives up only when ctx is done, so a storage outage stalls the
+// partition instead of dropping events.
+func retryStore(ctx context.Context, what string, store func() error) error {
+	backoff := storeRetryBackoff
+	for {
+		err := store()
+		if err == nil {
+			return nil
+		}
+		log.Printf("%s failed, retrying in %s: %v", what, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
 		}
+		backoff = min(2*backoff, maxStoreRetryBackoff)
 	}
 }
@@ -0,0 +1,105 @@
+package app
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	postspb "posts-service/proto"
+	statspb "stats-service/proto"
+
+	"common/identity"
+	"stats-service/internal/storage"
+)
+
+const authorPostsPageSize = 100
+
+func (s *statsServer) GetAuthorStats(ctx context.Context, in *statspb.AuthorStatsRequest) (*statspb.AuthorStatsResponse, error) {
+	// A call made for a user only reads that user's stats.
+	userID := identity.UserID(ctx)
+	if userID == "" {
+		userID = in.GetUserId()
+	}
+	if userID == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	days := int(in.GetDays())
+	if days <= 0 {
+		days = 30
+	}
+	top := int(in.GetTop())
+	if top <= 0 {
+		top = 3
+	}
+
+	postIDs, err := s.authorPostIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	since := today.AddDate(0, 0, -(days - 1))
+	stats, err := s.repo.AuthorStats(ctx, postIDs, since)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &statspb.AuthorStatsResponse{UserId: userID, PostCount: int64(len(postIDs)), Reach: stats.Reach}
+
+	totals := make(map[string]storage.PostTotals, len(stats.Posts))
+	for _, p := range stats.Posts {
+		totals[p.PostID] = p
+	}
+	for _, id := range postIDs {
+		p := totals[id]
+		resp.TotalViews += p.Views
+		resp.TotalLikes += p.Likes
+		resp.Posts = append(resp.Posts, &statspb.AuthorPostStats{PostId: id, Views: p.Views, Likes: p.Likes})
+	}
+
+	best := make([]*statspb.AuthorPostStats, len(resp.Posts))
+	copy(best, resp.Posts)
+	sort.SliceStable(best, func(i, j int) bool {
+		if best[i].GetLikes() != best[j].GetLikes() {
+			return best[i].GetLikes() > best[j].GetLikes()
+		}
+		return best[i].GetViews() > best[j].GetViews()
+	})
+	if len(best) > top {
+		best = best[:top]
+	}
+	resp.BestPosts = best
+
+	byDay := make(map[string]storage.DayTotals, len(stats.Daily))
+	for _, d := range stats.Daily {
+		byDay[d.Day.Format("2006-01-02")] = d
+	}
+	for day := since; !day.After(today); day = day.AddDate(0, 0, 1) {
+		key := day.Format("2006-01-02")
+		d := byDay[key]
+		resp.Daily = append(resp.Daily, &statspb.DailyStats{Date: key, Views: d.Views, Likes: d.Likes})
+	}
+	return resp, nil
+}
+
+func (s *statsServer) authorPostIDs(ctx context.Context, userID string) ([]string, error) {
+	if s.postsClient == nil {
+		return nil, nil
+	}
+	var ids []string
+	for page := int32(1); ; page++ {
+		resp, err := s.postsClient.ListPosts(ctx, &postspb.ListPostsRequest{UserId: userID, Page: page, PageSize: authorPostsPageSize})
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range resp.GetPosts() {
+			ids = append(ids, p.GetId())
+		}
+		if len(resp.GetPosts()) < authorPostsPageSize || int32(len(ids)) >= resp.GetTotal() {
+			return ids, nil
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	postspb "posts-service/proto"
+)
+
+type deletedPostsStore interface {
+	MarkPostDeleted(ctx context.Context, postID string, at time.Time) error
+	PurgeDeletedPosts(ctx context.Context, limit int) (int, error)
+	LivePostIDs(ctx context.Context) ([]string, error)
+}
+
+type postEvent struct {
+	EventType string    `json:"event_type"`
+	PostID    string    `json:"post_id"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// consumePostEvents marks posts deleted as posts-service reports it. Other
+// lifecycle events are acknowledged and ignored.
+func consumePostEvents(ctx context.Context, wg *sync.WaitGroup, store deletedPostsStore, cfg kafka.ReaderConfig) {
+	defer wg.Done()
+
+	reader := newKafkaReader(cfg)
+	defer reader.Close()
+
+	for {
+		msg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("read post event failed: %v", err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		var e postEvent
+		if err := json.Unmarshal(msg.Value, &e); err != nil {
+			log.Printf("decode post event failed: %v", err)
+		} else if e.EventType == "post_deleted" && e.PostID != "" {
+			if e.Timestamp.IsZero() {
+				e.Timestamp = time.Now().UTC()
+			}
+			// The offset is not committed until the deletion is stored.
+			for {
+				err := store.MarkPostDeleted(ctx, e.PostID, e.Timestamp)
+				if err == nil || ctx.Err() != nil {
+					break
+				}
+				log.Printf("mark post %s deleted failed: %v", e.PostID, err)
+				select {
+				case <-ctx.Done():
+				case <-time.After(time.Second):
+				}
+			}
+		}
+		if err := reader.CommitMessages(ctx, msg); err != nil && ctx.Err() == nil {
+			log.Printf("commit post event failed: %v", err)
+		}
+	}
+}
+
+// runPostCleanup purges the rows of deleted posts every purgeInterval and,
+// every reconcileInterval, asks posts-service about every post with stats
+// to catch deletions whose events were missed. A non-positive
+// reconcileInterval disables reconciliation.
+func runPostCleanup(ctx context.Context, wg *sync.WaitGroup, store deletedPostsStore, posts postsClient, purgeInterval, reconcileInterval time.Duration) {
+	defer wg.Done()
+
+	purge := time.NewTicker(purgeInterval)
+	defer purge.Stop()
+	var reconcile <-chan time.Time
+	if reconcileInterval > 0 && posts != nil {
+		ticker := time.NewTicker(reconcileInterval)
+		defer ticker.Stop()
+		reconcile = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-purge.C:
+			purgeDeletedPosts(ctx, store)
+		case <-reconcile:
+			if n, err := reconcileDeletedPosts(ctx, store, posts); err != nil {
+				log.Printf("reconcile deleted posts failed: %v", err)
+			} else if n > 0 {
+				log.Printf("reconcile found %d deleted posts", n)
+			}
+		}
+	}
+}
+
+func purgeDeletedPosts(ctx context.Context, store deletedPostsStore) {
+	for {
+		n, err := store.PurgeDeletedPosts(ctx, 0)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("purge deleted posts failed: %v", err)
+			}
+			return
+		}
+		if n == 0 {
+			return
+		}
+		log.Printf("purged stats of %d deleted posts", n)
+	}
+}
+
+// reconcileDeletedPosts marks every post posts-service no longer knows as
+// deleted. Other errors leave the post alone.
+func reconcileDeletedPosts(ctx context.Context, store deletedPostsStore, posts postsClient) (int, error) {
+	ids, err := store.LivePostIDs(ctx)
+	if err != nil {
+		return 0, err
+	}
+	marked := 0
+	for _, id := range ids {
+		_, err := posts.GetPost(ctx, &postspb.GetPostRequest{Id: id})
+		if status.Code(err) != codes.NotFound {
+			if ctx.Err() != nil {
+				return marked, ctx.Err()
+			}
+			continue
+		}
+		if err := store.MarkPostDeleted(ctx, id, time.Now().UTC()); err != nil {
+			return marked, err
+		}
+		marked++
+	}
+	return marked, nil
+}
@@ -0,0 +1,378 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	statspb "stats-service/proto"
+
+	"common/identity"
+	"stats-service/internal/storage"
+)
+
+const (
+	exportChunkRows    = 500
+	exportRowGroupRows = 10000
+)
+
+var errInvalidExport = errors.New("invalid export request")
+
+type exportParams struct {
+	from        time.Time
+	to          time.Time
+	postID      string
+	ownerID     string
+	granularity string
+}
+
+// exportFilter validates the parameters and resolves the owner filter into
+// post IDs. ok is false when the filters cannot match any row.
+func (s *statsServer) exportFilter(ctx context.Context, p exportParams) (filter storage.ExportFilter, ok bool, err error) {
+	if p.from.IsZero() || p.to.IsZero() || !p.from.Before(p.to) {
+		return filter, false, fmt.Errorf("%w: from must be before to", errInvalidExport)
+	}
+	switch p.granularity {
+	case "":
+		p.granularity = storage.GranularityRaw
+	case storage.GranularityRaw, storage.GranularityHour, storage.GranularityDay:
+	default:
+		return filter, false, fmt.Errorf("%w: unknown granularity %q", errInvalidExport, p.granularity)
+	}
+	filter = storage.ExportFilter{From: p.from, To: p.to, Granularity: p.granularity}
+
+	if p.ownerID != "" {
+		ids, err := s.authorPostIDs(ctx, p.ownerID)
+		if err != nil {
+			return filter, false, err
+		}
+		if p.postID != "" {
+			owned := false
+			for _, id := range ids {
+				owned = owned || id == p.postID
+			}
+			if !owned {
+				return filter, false, nil
+			}
+			ids = []string{p.postID}
+		}
+		if len(ids) == 0 {
+			return filter, false, nil
+		}
+		filter.PostIDs = ids
+	} else if p.postID != "" {
+		filter.PostIDs = []string{p.postID}
+	}
+	return filter, true, nil
+}
+
+func (s *statsServer) ExportEvents(in *statspb.ExportEventsRequest, stream grpc.ServerStreamingServer[statspb.ExportEventsChunk]) error {
+	ctx := stream.Context()
+	var from, to time.Time
+	if in.GetFrom() != nil {
+		from = in.GetFrom().AsTime()
+	}
+	if in.GetTo() != nil {
+		to = in.GetTo().AsTime()
+	}
+
+	filter, ok, err := s.exportFilter(ctx, exportParams{
+		from:        from,
+		to:          to,
+		postID:      in.GetPostId(),
+		ownerID:     in.GetOwnerId(),
+		granularity: in.GetGranularity(),
+	})
+	if errors.Is(err, errInvalidExport) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil || !ok {
+		return err
+	}
+
+	chunk := &statspb.ExportEventsChunk{}
+	err = s.repo.ExportEvents(ctx, filter, func(row storage.ExportRow) error {
+		chunk.Rows = append(chunk.Rows, &statspb.ExportRow{
+			Ts:        timestamppb.New(row.Timestamp),
+			EventType: row.EventType,
+			PostId:    row.PostID,
+			UserId:    row.UserID,
+			Count:     row.Count,
+		})
+		if len(chunk.Rows) < exportChunkRows {
+			return nil
+		}
+		if err := stream.Send(chunk); err != nil {
+			return err
+		}
+		chunk = &statspb.ExportEventsChunk{}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(chunk.Rows) > 0 {
+		return stream.Send(chunk)
+	}
+	return nil
+}
+
+// ExportUserEvents streams the caller's own raw events, then the flagged
+// events about them in the last chunk. The user comes from the identity
+// token only, so nobody can export someone else's data.
+func (s *statsServer) ExportUserEvents(_ *statspb.UserEventsRequest, stream grpc.ServerStreamingServer[statspb.UserEventsChunk]) error {
+	ctx := stream.Context()
+	userID := identity.UserID(ctx)
+	if userID == "" {
+		return status.Error(codes.Unauthenticated, "export requires an authenticated user")
+	}
+
+	chunk := &statspb.UserEventsChunk{}
+	err := s.repo.UserEvents(ctx, userID, func(e storage.Event) error {
+		chunk.Events = append(chunk.Events, &statspb.UserEvent{
+			Ts:        timestamppb.New(e.Timestamp),
+			EventType: e.EventType,
+			PostId:    e.PostID,
+		})
+		if len(chunk.Events) < exportChunkRows {
+			return nil
+		}
+		if err := stream.Send(chunk); err != nil {
+			return err
+		}
+		chunk = &statspb.UserEventsChunk{}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	flagged, err := s.repo.UserFlaggedEvents(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, f := range flagged {
+		chunk.Flagged = append(chunk.Flagged, flaggedEventProto(f))
+	}
+	if len(chunk.Events) > 0 || len(chunk.Flagged) > 0 {
+		return stream.Send(chunk)
+	}
+	return nil
+}
+
+// exportHandler serves GET /export/events, streaming the rows as CSV,
+// NDJSON or Parquet with chunked transfer encoding. The rows carry user IDs,
+// so callers need an internal identity token like gRPC calls do.
+func exportHandler(s *statsServer, verifier *identity.Verifier) http.Handler {
+	return verifier.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		q := r.URL.Query()
+
+		from, err := time.Parse(time.RFC3339, q.Get("from"))
+		if err != nil {
+			http.Error(w, "invalid from parameter", http.StatusBadRequest)
+			return
+		}
+		to := time.Now().UTC()
+		if v := q.Get("to"); v != "" {
+			if to, err = time.Parse(time.RFC3339, v); err != nil {
+				http.Error(w, "invalid to parameter", http.StatusBadRequest)
+				return
+			}
+		}
+
+		format := q.Get("format")
+		if format == "" {
+			format = "csv"
+		}
+		newWriter, contentType, ok := exportWriters(format)
+		if !ok {
+			http.Error(w, "invalid format parameter", http.StatusBadRequest)
+			return
+		}
+
+		filter, matches, err := s.exportFilter(r.Context(), exportParams{
+			from:        from,
+			to:          to,
+			postID:      q.Get("post_id"),
+			ownerID:     q.Get("owner_id"),
+			granularity: q.Get("granularity"),
+		})
+		if errors.Is(err, errInvalidExport) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, "service error", http.StatusBadGateway)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="events-%s.%s"`, filter.Granularity, format))
+		w.WriteHeader(http.StatusOK)
+
+		out := newWriter(&flushWriter{w: w})
+		if matches {
+			err = s.repo.ExportEvents(r.Context(), filter, out.Write)
+		}
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			// The status line is already sent; abort so the client sees a
+			// truncated body instead of a silently incomplete export.
+			log.Printf("export events failed: %v", err)
+			panic(http.ErrAbortHandler)
+		}
+	}))
+}
+
+type exportRowWriter interface {
+	Write(storage.ExportRow) error
+	Close() error
+}
+
+func exportWriters(format string) (func(io.Writer) exportRowWriter, string, bool) {
+	switch format {
+	case "csv":
+		return newCSVExportWriter, "text/csv", true
+	case "ndjson":
+		return newNDJSONExportWriter, "application/x-ndjson", true
+	case "parquet":
+		return newParquetExportWriter, "application/vnd.apache.parquet", true
+	}
+	return nil, "", false
+}
+
+// flushWriter pushes every write to the client so nothing accumulates in
+// the response buffer.
+type flushWriter struct {
+	w http.ResponseWriter
+}
+
+func (f *flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if flusher, ok := f.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return n, err
+}
+
+type csvExportWriter struct {
+	w    *csv.Writer
+	rows int
+}
+
+func newCSVExportWriter(w io.Writer) exportRowWriter {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"ts", "event_type", "post_id", "user_id", "count"})
+	return &csvExportWriter{w: cw}
+}
+
+func (c *csvExportWriter) Write(row storage.ExportRow) error {
+	if err := c.w.Write([]string{
+		row.Timestamp.UTC().Format(time.RFC3339),
+		row.EventType,
+		row.PostID,
+		row.UserID,
+		strconv.FormatInt(row.Count, 10),
+	}); err != nil {
+		return err
+	}
+	if c.rows++; c.rows%exportChunkRows == 0 {
+		c.w.Flush()
+	}
+	return c.w.Error()
+}
+
+func (c *csvExportWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type ndjsonExportWriter struct {
+	buf  *bufio.Writer
+	enc  *json.Encoder
+	rows int
+}
+
+func newNDJSONExportWriter(w io.Writer) exportRowWriter {
+	buf := bufio.NewWriter(w)
+	return &ndjsonExportWriter{buf: buf, enc: json.NewEncoder(buf)}
+}
+
+func (n *ndjsonExportWriter) Write(row storage.ExportRow) error {
+	if err := n.enc.Encode(struct {
+		Timestamp time.Time `json:"ts"`
+		EventType string    `json:"event_type"`
+		PostID    string    `json:"post_id"`
+		UserID    string    `json:"user_id,omitempty"`
+		Count     int64     `json:"count"`
+	}{row.Timestamp.UTC(), row.EventType, row.PostID, row.UserID, row.Count}); err != nil {
+		return err
+	}
+	if n.rows++; n.rows%exportChunkRows == 0 {
+		return n.buf.Flush()
+	}
+	return nil
+}
+
+func (n *ndjsonExportWriter) Close() error {
+	return n.buf.Flush()
+}
+
+type parquetExportRow struct {
+	Timestamp time.Time `parquet:"ts,timestamp(millisecond)"`
+	EventType string    `parquet:"event_type,dict"`
+	PostID    string    `parquet:"post_id"`
+	UserID    string    `parquet:"user_id"`
+	Count     int64     `parquet:"count"`
+}
+
+// parquetExportWriter emits a row group every exportRowGroupRows rows, so
+// only one row group is held in memory at a time.
+type parquetExportWriter struct {
+	w    *parquet.GenericWriter[parquetExportRow]
+	rows int
+}
+
+func newParquetExportWriter(w io.Writer) exportRowWriter {
+	return &parquetExportWriter{w: parquet.NewGenericWriter[parquetExportRow](w, parquet.Compression(&parquet.Zstd))}
+}
+
+func (p *parquetExportWriter) Write(row storage.ExportRow) error {
+	if _, err := p.w.Write([]parquetExportRow{{
+		Timestamp: row.Timestamp.UTC(),
+		EventType: row.EventType,
+		PostID:    row.PostID,
+		UserID:    row.UserID,
+		Count:     row.Count,
+	}}); err != nil {
+		return err
+	}
+	if p.rows++; p.rows%exportRowGroupRows == 0 {
+		return p.w.Flush()
+	}
+	return nil
+}
+
+func (p *parquetExportWriter) Close() error {
+	return p.w.Close()
+}
@@ -0,0 +1,236 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	statspb "stats-service/proto"
+
+	"common/identity"
+	"stats-service/internal/storage"
+)
+
+// fraudRules are per-window limits; a negative limit disables the rule.
+type fraudRules struct {
+	window time.Duration
+	// viewerLimit caps the events from one viewer (user, or IP for
+	// anonymous traffic) across all posts.
+	viewerLimit int
+	// postLimit caps the views of one post from everyone.
+	postLimit int
+	// likeLimit caps the likes from one viewer; a viewer liking the same
+	// post twice is always flagged.
+	likeLimit int
+}
+
+func newFraudRules(window time.Duration, viewerLimit, postLimit, likeLimit int) fraudRules {
+	if window <= 0 {
+		window = time.Minute
+	}
+	if viewerLimit == 0 {
+		viewerLimit = 30
+	}
+	if postLimit == 0 {
+		postLimit = 600
+	}
+	if likeLimit == 0 {
+		likeLimit = 10
+	}
+	return fraudRules{window: window, viewerLimit: viewerLimit, postLimit: postLimit, likeLimit: likeLimit}
+}
+
+// fraudDetector keeps sliding-window counters keyed by viewer and post.
+// Windows are driven by event time, so a replay reaches the same verdicts
+// as the live consumer.
+type fraudDetector struct {
+	rules fraudRules
+
+	mu        sync.Mutex
+	windows   map[string]*slidingWindow
+	lastSweep time.Time
+}
+
+func newFraudDetector(rules fraudRules) *fraudDetector {
+	return &fraudDetector{rules: rules, windows: make(map[string]*slidingWindow)}
+}
+
+// check records the event and returns the rule it violates, or "" for
+// legitimate traffic.
+func (d *fraudDetector) check(e storage.Event, ip string) string {
+	if d == nil {
+		return ""
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// One detector serves every partition, so timestamps arrive out of
+	// order; late events never move the sweep backwards.
+	now := e.Timestamp
+	if now.Sub(d.lastSweep) > d.rules.window {
+		d.sweep(now)
+	}
+
+	viewer := e.UserID
+	if viewer == "" && ip != "" {
+		viewer = "ip:" + ip
+	}
+
+	var reasons []string
+	if viewer != "" && d.over("viewer|"+viewer, now, d.rules.viewerLimit) {
+		reasons = append(reasons, "viewer_burst")
+	}
+	switch e.EventType {
+	case "view":
+		if d.over("post|"+e.PostID, now, d.rules.postLimit) {
+			reasons = append(reasons, "post_rate")
+		}
+	case "like":
+		if viewer == "" {
+			break
+		}
+		if d.over("like|"+viewer+"|"+e.PostID, now, 1) {
+			reasons = append(reasons, "repeated_like")
+		}
+		if d.over("likes|"+viewer, now, d.rules.likeLimit) {
+			reasons = append(reasons, "like_burst")
+		}
+	}
+	if len(reasons) == 0 {
+		return ""
+	}
+	return reasons[0]
+}
+
+// over records the event under key and reports whether the window now holds
+// more than limit events.
+func (d *fraudDetector) over(key string, now time.Time, limit int) bool {
+	if limit < 0 {
+		return false
+	}
+	w := d.windows[key]
+	if w == nil {
+		w = &slidingWindow{}
+		d.windows[key] = w
+	}
+	return w.add(now, d.rules.window) > limit
+}
+
+// sweep drops counters without events in the last window so memory stays
+// proportional to the active viewers.
+func (d *fraudDetector) sweep(now time.Time) {
+	for key, w := range d.windows {
+		if w.idle(now, d.rules.window) {
+			delete(d.windows, key)
+		}
+	}
+	d.lastSweep = now
+}
+
+type slidingWindow struct {
+	times []time.Time
+}
+
+// add records an event at now and returns the number of events in
+// (now-window, now]. Events may arrive late, so times are kept sorted and
+// only those outside the window of the newest event are dropped.
+func (w *slidingWindow) add(now time.Time, window time.Duration) int {
+	after := func(t time.Time) int {
+		return sort.Search(len(w.times), func(i int) bool { return w.times[i].After(t) })
+	}
+	at := after(now)
+	w.times = slices.Insert(w.times, at, now)
+	count := at + 1 - after(now.Add(-window))
+
+	drop := after(w.times[len(w.times)-1].Add(-window))
+	w.times = w.times[drop:]
+	return count
+}
+
+func (w *slidingWindow) idle(now time.Time, window time.Duration) bool {
+	return len(w.times) == 0 || !w.times[len(w.times)-1].After(now.Add(-window))
+}
+
+// moderatorRoles may see and review flagged events, which carry raw IPs.
+var moderatorRoles = map[string]bool{"moderator": true, "admin": true}
+
+// requireModerator checks the caller of the identity token, as posts-service
+// does for moderation.
+func requireModerator(ctx context.Context) (identity.Caller, error) {
+	caller, _ := identity.FromContext(ctx)
+	if caller.UserID == "" {
+		return caller, status.Error(codes.Unauthenticated, "user identity required")
+	}
+	if !moderatorRoles[caller.Role] {
+		return caller, status.Error(codes.PermissionDenied, "moderator role required")
+	}
+	return caller, nil
+}
+
+func (s *statsServer) ListFlaggedEvents(ctx context.Context, in *statspb.ListFlaggedEventsRequest) (*statspb.ListFlaggedEventsResponse, error) {
+	if _, err := requireModerator(ctx); err != nil {
+		return nil, err
+	}
+	state := in.GetStatus()
+	switch state {
+	case "":
+		state = storage.FlagPending
+	case storage.FlagPending, storage.FlagApproved, storage.FlagRejected:
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unknown status %q", state)
+	}
+
+	flagged, err := s.repo.FlaggedEvents(ctx, state, in.GetPostId(), int(in.GetLimit()))
+	if err != nil {
+		return nil, err
+	}
+	resp := &statspb.ListFlaggedEventsResponse{}
+	for _, f := range flagged {
+		resp.Events = append(resp.Events, flaggedEventProto(f))
+	}
+	return resp, nil
+}
+
+func (s *statsServer) ReviewFlaggedEvent(ctx context.Context, in *statspb.ReviewFlaggedEventRequest) (*statspb.ReviewFlaggedEventResponse, error) {
+	caller, err := requireModerator(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if in.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+	f, err := s.repo.ReviewFlaggedEvent(ctx, in.GetId(), in.GetApprove(), caller.UserID)
+	switch {
+	case errors.Is(err, storage.ErrFlagNotFound):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, storage.ErrFlagReviewed):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case err != nil:
+		return nil, err
+	}
+	if f.Status == storage.FlagApproved {
+		s.hub.publish(f.PostID)
+	}
+	return &statspb.ReviewFlaggedEventResponse{Event: flaggedEventProto(f)}, nil
+}
+
+func flaggedEventProto(f storage.FlaggedEvent) *statspb.FlaggedEvent {
+	return &statspb.FlaggedEvent{
+		Id:         f.ID,
+		Ts:         timestamppb.New(f.Timestamp),
+		EventType:  f.EventType,
+		PostId:     f.PostID,
+		UserId:     f.UserID,
+		Ip:         f.IP,
+		Reason:     f.Reason,
+		Status:     f.Status,
+		ReviewedBy: f.ReviewedBy,
+	}
+}
@@ -2,7 +2,9 @@ package app
 
 import (
 	"context"
+	"math"
 	"sort"
+	"time"
 
 	"google.golang.org/grpc"
 	postspb "posts-service/proto"
@@ -11,21 +13,57 @@ import (
 
 type postsClient interface {
 	GetPost(ctx context.Context, in *postspb.GetPostRequest, opts ...grpc.CallOption) (*postspb.GetPostResponse, error)
+	ListPosts(ctx context.Context, in *postspb.ListPostsRequest, opts ...grpc.CallOption) (*postspb.ListPostsResponse, error)
 }
 
 type statsServer struct {
 	statspb.UnimplementedStatsServiceServer
-	repo        statsRepository
-	postsClient postsClient
+	repo          statsRepository
+	postsClient   postsClient
+	hub           *statsHub
+	watchInterval time.Duration
+	// watchPoll bounds how long a watcher goes without a refresh, so it
+	// also sees events stored by other replicas of the consumer group.
+	watchPoll time.Duration
+	trending  trendingConfig
 }
 
-func newStatsServer(repo statsRepository, postsClient postsClient) *statsServer {
-	return &statsServer{repo: repo, postsClient: postsClient}
+type trendingConfig struct {
+	window     time.Duration
+	halfLife   time.Duration
+	likeWeight float64
+}
+
+func newTrendingConfig(window, halfLife time.Duration, likeWeight float64) trendingConfig {
+	if window <= 0 {
+		window = 72 * time.Hour
+	}
+	if halfLife <= 0 {
+		halfLife = 6 * time.Hour
+	}
+	if likeWeight <= 0 {
+		likeWeight = 3
+	}
+	return trendingConfig{window: window, halfLife: halfLife, likeWeight: likeWeight}
+}
+
+func newStatsServer(repo statsRepository, postsClient postsClient, hub *statsHub, watchInterval time.Duration) *statsServer {
+	if watchInterval <= 0 {
+		watchInterval = defaultWatchInterval
+	}
+	return &statsServer{
+		repo:          repo,
+		postsClient:   postsClient,
+		hub:           hub,
+		watchInterval: watchInterval,
+		watchPoll:     defaultWatchPoll,
+		trending:      newTrendingConfig(0, 0, 0),
+	}
 }
 
 // NewStatsServerForTest exposes a stats server with injected dependencies for tests.
 func NewStatsServerForTest(repo statsRepository, postsClient postsClient) statspb.StatsServiceServer {
-	return newStatsServer(repo, postsClient)
+	return newStatsServer(repo, postsClient, newStatsHub(), defaultWatchInterval)
 }
 
 func (s *statsServer) GetPostStats(ctx context.Context, in *statspb.PostStatsRequest) (*statspb.PostStatsResponse, error) {
@@ -44,16 +82,19 @@ func (s *statsServer) GetTopPosts(ctx context.Context, in *statspb.TopPostsReque
 	if metric == "" {
 		metric = "views"
 	}
+	limit := int(in.GetLimit())
+	if limit <= 0 {
+		limit = 5
+	}
+	if metric == "trending" {
+		return s.trendingPosts(ctx, in, limit)
+	}
 	eventType := "view"
 	if metric == "likes" {
 		eventType = "like"
 	} else if metric != "views" {
 		metric = "views"
 	}
-	limit := int(in.GetLimit())
-	if limit <= 0 {
-		limit = 5
-	}
 
 	posts, err := s.repo.TopPosts(ctx, eventType, limit)
 	if err != nil {
@@ -67,6 +108,48 @@ func (s *statsServer) GetTopPosts(ctx context.Context, in *statspb.TopPostsReque
 	return resp, nil
 }
 
+func (s *statsServer) trendingPosts(ctx context.Context, in *statspb.TopPostsRequest, limit int) (*statspb.TopPostsResponse, error) {
+	cfg := s.trending
+	if hours := in.GetHalfLifeHours(); hours > 0 {
+		cfg.halfLife = time.Duration(hours * float64(time.Hour))
+	}
+	if hours := in.GetWindowHours(); hours > 0 {
+		cfg.window = time.Duration(hours) * time.Hour
+	}
+
+	posts, err := s.repo.TrendingPosts(ctx, cfg.window, cfg.halfLife, cfg.likeWeight, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &statspb.TopPostsResponse{}
+	for _, p := range posts {
+		resp.Items = append(resp.Items, &statspb.PostItem{PostId: p.PostID, Value: int64(math.Round(p.Score)), Score: p.Score})
+	}
+	return resp, nil
+}
+
+func (s *statsServer) GetRelatedPosts(ctx context.Context, in *statspb.RelatedPostsRequest) (*statspb.RelatedPostsResponse, error) {
+	if in.GetPostId() == "" {
+		return &statspb.RelatedPostsResponse{}, nil
+	}
+	limit := int(in.GetLimit())
+	if limit <= 0 {
+		limit = 5
+	}
+
+	related, err := s.repo.RelatedPosts(ctx, in.GetPostId(), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &statspb.RelatedPostsResponse{}
+	for _, p := range related {
+		resp.Items = append(resp.Items, &statspb.PostItem{PostId: p.PostID, Score: p.Score})
+	}
+	return resp, nil
+}
+
 func (s *statsServer) GetTopUsersByLikes(ctx context.Context, in *statspb.TopUsersRequest) (*statspb.TopUsersResponse, error) {
 	limit := int(in.GetLimit())
 	if limit <= 0 {
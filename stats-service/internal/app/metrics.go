@@ -0,0 +1,79 @@
+package app
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+)
+
+type topicPartition struct {
+	topic     string
+	partition int
+}
+
+type topicResult struct {
+	topic  string
+	result string
+}
+
+// consumerMetrics tracks per-partition lag and processed message counts and
+// serves them in the Prometheus text format.
+type consumerMetrics struct {
+	mu       sync.Mutex
+	lag      map[topicPartition]int64
+	messages map[topicResult]int64
+}
+
+func newConsumerMetrics() *consumerMetrics {
+	return &consumerMetrics{
+		lag:      make(map[topicPartition]int64),
+		messages: make(map[topicResult]int64),
+	}
+}
+
+// observe records a processed message; result is "saved", "flagged",
+// "invalid" or "failed".
+func (m *consumerMetrics) observe(msg kafka.Message, result string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lag[topicPartition{msg.Topic, msg.Partition}] = max(msg.HighWaterMark-msg.Offset-1, 0)
+	m.messages[topicResult{msg.Topic, result}]++
+}
+
+func (m *consumerMetrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	m.mu.Lock()
+	lag := make([]topicPartition, 0, len(m.lag))
+	for tp := range m.lag {
+		lag = append(lag, tp)
+	}
+	messages := make([]topicResult, 0, len(m.messages))
+	for tr := range m.messages {
+		messages = append(messages, tr)
+	}
+	slices.SortFunc(lag, func(a, b topicPartition) int {
+		return cmp.Or(cmp.Compare(a.topic, b.topic), cmp.Compare(a.partition, b.partition))
+	})
+	slices.SortFunc(messages, func(a, b topicResult) int {
+		return cmp.Or(cmp.Compare(a.topic, b.topic), cmp.Compare(a.result, b.result))
+	})
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprintln(w, "# HELP stats_consumer_lag Messages between the last processed offset and the partition high watermark.")
+	fmt.Fprintln(w, "# TYPE stats_consumer_lag gauge")
+	for _, tp := range lag {
+		fmt.Fprintf(w, "stats_consumer_lag{topic=%q,partition=\"%d\"} %d\n", tp.topic, tp.partition, m.lag[tp])
+	}
+	fmt.Fprintln(w, "# HELP stats_consumer_messages_total Messages processed by the consumers.")
+	fmt.Fprintln(w, "# TYPE stats_consumer_messages_total counter")
+	for _, tr := range messages {
+		fmt.Fprintf(w, "stats_consumer_messages_total{topic=%q,result=%q} %d\n", tr.topic, tr.result, m.messages[tr])
+	}
+	m.mu.Unlock()
+}
@@ -0,0 +1,39 @@
+package app
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+type relatedPostsBuilder interface {
+	ComputeRelatedPosts(ctx context.Context, window time.Duration, topN int) error
+}
+
+// runRecommendations recomputes co-like neighbours right away and then every
+// interval until ctx is cancelled.
+func runRecommendations(ctx context.Context, wg *sync.WaitGroup, builder relatedPostsBuilder, interval, window time.Duration, topN int) {
+	defer wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		started := time.Now()
+		if err := builder.ComputeRelatedPosts(ctx, window, topN); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("compute related posts failed: %v", err)
+		} else {
+			log.Printf("related posts computed in %s", time.Since(started).Round(time.Millisecond))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
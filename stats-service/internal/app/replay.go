@@ -0,0 +1,407 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"stats-service/internal/storage"
+)
+
+const replayBatchSize = 1000
+
+// ReplayOptions controls a rebuild of the stats tables from Kafka.
+type ReplayOptions struct {
+	// From is the earliest event time to replay; zero replays every message
+	// still retained by the topics.
+	From time.Time
+	// ProgressInterval is how often progress is logged.
+	ProgressInterval time.Duration
+}
+
+type replayPartition struct {
+	topic       string
+	defaultType string
+	partition   int
+	start       int64
+	end         int64
+	read        atomic.Int64
+}
+
+// Replay re-reads post_views and post_likes into shadow tables, swaps them
+// with the live ones once every partition is caught up to the offsets seen at
+// start, and moves the consumer group to those offsets. With From set, the
+// live history before it is kept. Replay joins the consumer group and holds
+// every partition of the topics until the offsets are committed, so it fails
+// when stats-service is still consuming instead of racing with it.
+func Replay(ctx context.Context, cfg Config, opts ReplayOptions) error {
+	if cfg.KafkaGroupID == "" {
+		cfg.KafkaGroupID = "stats-service"
+	}
+	if cfg.ViewsTopic == "" {
+		cfg.ViewsTopic = "post_views"
+	}
+	if cfg.LikesTopic == "" {
+		cfg.LikesTopic = "post_likes"
+	}
+	if len(cfg.KafkaBrokers) == 0 {
+		return fmt.Errorf("no kafka brokers configured")
+	}
+	if opts.ProgressInterval <= 0 {
+		opts.ProgressInterval = 10 * time.Second
+	}
+
+	repo, err := storage.New(ctx, storage.Config{
+		Addr:           cfg.ClickHouseAddr,
+		DB:             cfg.ClickHouseDB,
+		User:           cfg.ClickHouseUser,
+		Password:       cfg.ClickHousePassword,
+		SkipMigrations: cfg.SkipMigrations,
+	})
+	if err != nil {
+		return fmt.Errorf("storage init failed: %w", err)
+	}
+	defer repo.Close()
+
+	client := &kafka.Client{Addr: kafka.TCP(cfg.KafkaBrokers...)}
+	partitions, err := replayPartitions(ctx, client, map[string]string{
+		cfg.ViewsTopic: "view",
+		cfg.LikesTopic: "like",
+	}, opts.From)
+	if err != nil {
+		return err
+	}
+
+	group, gen, err := holdConsumerGroup(ctx, cfg.KafkaBrokers, cfg.KafkaGroupID, partitions)
+	if err != nil {
+		return err
+	}
+	defer group.Close()
+
+	if err := repo.PrepareRebuild(ctx, opts.From); err != nil {
+		return fmt.Errorf("prepare shadow tables: %w", err)
+	}
+
+	var total int64
+	for _, p := range partitions {
+		total += p.end - p.start
+	}
+	log.Printf("replay: %d messages in %d partitions", total, len(partitions))
+
+	progressCtx, stopProgress := context.WithCancel(ctx)
+	defer stopProgress()
+	go reportReplayProgress(progressCtx, partitions, total, opts.ProgressInterval)
+
+	// Flagged traffic is dropped again with the live rules; moderators'
+	// approvals are restored from flagged_events before the swap.
+	filter := &replayFilter{
+		detector: newFraudDetector(newFraudRules(cfg.FraudWindow, cfg.FraudViewerLimit, cfg.FraudPostLimit, cfg.FraudLikeLimit)),
+	}
+	// Events of deleted posts and users are not replayed, so data already
+	// purged does not come back.
+	if filter.deletedPosts, err = repo.DeletedPostIDs(ctx); err != nil {
+		return fmt.Errorf("load deleted posts: %w", err)
+	}
+	if filter.deletedUsers, err = repo.DeletedUserIDs(ctx); err != nil {
+		return fmt.Errorf("load deleted users: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	errCh := make(chan error, len(partitions))
+	for _, p := range partitions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := replayPartitionInto(ctx, repo, filter, cfg.KafkaBrokers, p); err != nil {
+				errCh <- fmt.Errorf("replay %s/%d: %w", p.topic, p.partition, err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errCh)
+	if err := errors.Join(collect(errCh)...); err != nil {
+		return err
+	}
+	stopProgress()
+
+	since := opts.From
+	if since.IsZero() {
+		since = time.Unix(0, 0)
+	}
+	if err := repo.RestoreApprovedFlags(ctx, "events"+storage.RebuildSuffix, since); err != nil {
+		return fmt.Errorf("restore approved events: %w", err)
+	}
+
+	if err := repo.SwapRebuild(ctx); err != nil {
+		return fmt.Errorf("swap tables: %w", err)
+	}
+	log.Printf("replay: swapped rebuilt tables into place")
+
+	if err := commitReplayOffsets(gen, partitions); err != nil {
+		return fmt.Errorf("reset consumer group (rerun replay before starting stats-service): %w", err)
+	}
+	log.Printf("replay: consumer group %s moved to the replayed offsets", cfg.KafkaGroupID)
+	return nil
+}
+
+// replayPartitions resolves the offset range [start, end) to replay for every
+// partition of the topics.
+func replayPartitions(ctx context.Context, client *kafka.Client, topics map[string]string, from time.Time) ([]*replayPartition, error) {
+	names := make([]string, 0, len(topics))
+	for name := range topics {
+		names = append(names, name)
+	}
+	meta, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: names})
+	if err != nil {
+		return nil, err
+	}
+
+	startReq := map[string][]kafka.OffsetRequest{}
+	endReq := map[string][]kafka.OffsetRequest{}
+	for _, t := range meta.Topics {
+		if t.Error != nil {
+			return nil, fmt.Errorf("topic %s: %w", t.Name, t.Error)
+		}
+		for _, p := range t.Partitions {
+			if from.IsZero() {
+				startReq[t.Name] = append(startReq[t.Name], kafka.FirstOffsetOf(p.ID))
+			} else {
+				startReq[t.Name] = append(startReq[t.Name], kafka.TimeOffsetOf(p.ID, from))
+			}
+			endReq[t.Name] = append(endReq[t.Name], kafka.LastOffsetOf(p.ID))
+		}
+	}
+
+	starts, err := client.ListOffsets(ctx, &kafka.ListOffsetsRequest{Topics: startReq})
+	if err != nil {
+		return nil, err
+	}
+	ends, err := client.ListOffsets(ctx, &kafka.ListOffsetsRequest{Topics: endReq})
+	if err != nil {
+		return nil, err
+	}
+
+	var partitions []*replayPartition
+	for topic, offsets := range ends.Topics {
+		for _, end := range offsets {
+			if end.Error != nil {
+				return nil, fmt.Errorf("topic %s partition %d: %w", topic, end.Partition, end.Error)
+			}
+			p := &replayPartition{topic: topic, defaultType: topics[topic], partition: end.Partition, end: end.LastOffset, start: end.LastOffset}
+			for _, start := range starts.Topics[topic] {
+				if start.Partition != end.Partition {
+					continue
+				}
+				if start.Error != nil {
+					return nil, fmt.Errorf("topic %s partition %d: %w", topic, start.Partition, start.Error)
+				}
+				if from.IsZero() {
+					p.start = start.FirstOffset
+				}
+				// A time lookup answers with the first offset at or after the
+				// timestamp, or -1 when there is none.
+				for offset := range start.Offsets {
+					if offset >= 0 && offset < p.start {
+						p.start = offset
+					}
+				}
+			}
+			partitions = append(partitions, p)
+		}
+	}
+	return partitions, nil
+}
+
+// replayFilter decides which replayed events go back into the tables.
+type replayFilter struct {
+	detector     *fraudDetector
+	deletedPosts map[string]struct{}
+	deletedUsers map[string]struct{}
+}
+
+func (f *replayFilter) keep(e storage.Event, ip string) bool {
+	if _, gone := f.deletedPosts[e.PostID]; gone {
+		return false
+	}
+	if _, gone := f.deletedUsers[e.UserID]; gone && e.UserID != "" {
+		return false
+	}
+	return f.detector.check(e, ip) == ""
+}
+
+// replayReader is the part of *kafka.Reader a replay reads a partition with.
+type replayReader interface {
+	SetOffset(offset int64) error
+	ReadMessage(ctx context.Context) (kafka.Message, error)
+	Close() error
+}
+
+var newReplayReader = func(cfg kafka.ReaderConfig) replayReader {
+	return kafka.NewReader(cfg)
+}
+
+type replayStore interface {
+	SaveEventsTo(ctx context.Context, table string, events []storage.Event) error
+}
+
+func replayPartitionInto(ctx context.Context, store replayStore, filter *replayFilter, brokers []string, p *replayPartition) error {
+	if p.start >= p.end {
+		return nil
+	}
+	reader := newReplayReader(kafka.ReaderConfig{
+		Brokers:   brokers,
+		Topic:     p.topic,
+		Partition: p.partition,
+		MaxWait:   time.Second,
+	})
+	defer reader.Close()
+	if err := reader.SetOffset(p.start); err != nil {
+		return err
+	}
+
+	table := "events" + storage.RebuildSuffix
+	batch := make([]storage.Event, 0, replayBatchSize)
+	for {
+		msg, err := reader.ReadMessage(ctx)
+		if err != nil {
+			return err
+		}
+		if e, ip, ok := decodeEvent(msg, p.defaultType); ok && filter.keep(e, ip) {
+			batch = append(batch, e)
+		}
+		done := msg.Offset+1 >= p.end
+		if len(batch) == replayBatchSize || done {
+			if err := store.SaveEventsTo(ctx, table, batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+			p.read.Store(msg.Offset + 1 - p.start)
+		}
+		if done {
+			return nil
+		}
+	}
+}
+
+func reportReplayProgress(ctx context.Context, partitions []*replayPartition, total int64, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	started := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		var read int64
+		for _, p := range partitions {
+			read += p.read.Load()
+		}
+		rate := float64(read) / time.Since(started).Seconds()
+		percent := 100.0
+		if total > 0 {
+			percent = float64(read) * 100 / float64(total)
+		}
+		log.Printf("replay: %d/%d messages (%.1f%%, %.0f msg/s)", read, total, percent, rate)
+	}
+}
+
+// holdConsumerGroup joins the consumer group for the replayed topics and
+// checks that this member got every partition, i.e. that no stats-service
+// consumer is running. Should one join later, the generation ends and the
+// offset commit fails.
+func holdConsumerGroup(ctx context.Context, brokers []string, groupID string, partitions []*replayPartition) (*kafka.ConsumerGroup, *kafka.Generation, error) {
+	var topics []string
+	seen := map[string]bool{}
+	for _, p := range partitions {
+		if !seen[p.topic] {
+			seen[p.topic] = true
+			topics = append(topics, p.topic)
+		}
+	}
+	group, err := kafka.NewConsumerGroup(kafka.ConsumerGroupConfig{ID: groupID, Brokers: brokers, Topics: topics})
+	if err != nil {
+		return nil, nil, fmt.Errorf("join consumer group: %w", err)
+	}
+	gen, err := group.Next(ctx)
+	if err != nil {
+		group.Close()
+		return nil, nil, fmt.Errorf("join consumer group: %w", err)
+	}
+
+	owned := map[string]map[int]bool{}
+	for topic, assigned := range gen.Assignments {
+		owned[topic] = map[int]bool{}
+		for _, a := range assigned {
+			owned[topic][a.ID] = true
+		}
+	}
+	for _, p := range partitions {
+		if !owned[p.topic][p.partition] {
+			group.Close()
+			return nil, nil, fmt.Errorf("consumer group %s has other members; stop stats-service before replaying", groupID)
+		}
+	}
+	return group, gen, nil
+}
+
+// commitReplayOffsets points the consumer group at the end of the replayed
+// range through the generation the replay holds.
+func commitReplayOffsets(gen *kafka.Generation, partitions []*replayPartition) error {
+	offsets := map[string]map[int]int64{}
+	for _, p := range partitions {
+		if offsets[p.topic] == nil {
+			offsets[p.topic] = map[int]int64{}
+		}
+		offsets[p.topic][p.partition] = p.end
+	}
+	return gen.CommitOffsets(offsets)
+}
+
+// decodeEvent turns a Kafka message into a storage event and the client IP,
+// filling in the topic's event type and the current time when the producer
+// omitted them.
+func decodeEvent(msg kafka.Message, defaultType string) (storage.Event, string, bool) {
+	var e event
+	if err := json.Unmarshal(msg.Value, &e); err != nil {
+		log.Printf("decode message failed: %v", err)
+		return storage.Event{}, "", false
+	}
+	if e.EventType == "" {
+		e.EventType = defaultType
+	}
+	if e.PostID == "" {
+		log.Printf("skip message without post_id")
+		return storage.Event{}, "", false
+	}
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now().UTC()
+	}
+	return storage.Event{
+		EventType: e.EventType,
+		PostID:    e.PostID,
+		UserID:    e.UserID,
+		Timestamp: e.Timestamp,
+	}, e.IP, true
+}
+
+// flagID identifies a flagged event by its position in Kafka, so flagging
+// the same message twice keeps a single row.
+func flagID(msg kafka.Message) string {
+	return fmt.Sprintf("%s-%d-%d", msg.Topic, msg.Partition, msg.Offset)
+}
+
+func collect(errCh <-chan error) []error {
+	var errs []error
+	for err := range errCh {
+		errs = append(errs, err)
+	}
+	return errs
+}
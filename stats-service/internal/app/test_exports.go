@@ -2,9 +2,15 @@ package app
 
 import (
 	"context"
+	"net/http"
 	"sync"
+	"time"
 
 	"github.com/segmentio/kafka-go"
+
+	statspb "stats-service/proto"
+
+	"common/identity"
 )
 
 // StatsRepositoryForTest exposes the internal repository interface for external tests.
@@ -22,5 +28,78 @@ func SetKafkaReaderFactoryForTest(factory func(kafka.ReaderConfig) kafkaMessageR
 
 // ConsumeTopicForTest calls the internal consumeTopic helper for integration tests.
 func ConsumeTopicForTest(ctx context.Context, wg *sync.WaitGroup, repo statsRepository, cfg kafka.ReaderConfig, defaultEvent string) {
-	consumeTopic(ctx, wg, repo, cfg, defaultEvent)
+	(&consumer{repo: repo}).consumeTopic(ctx, wg, cfg, defaultEvent)
+}
+
+// ConsumeTopicWithFraudRulesForTest runs consumeTopic with a fraud detector
+// using the given window and limits.
+func ConsumeTopicWithFraudRulesForTest(ctx context.Context, wg *sync.WaitGroup, repo statsRepository, cfg kafka.ReaderConfig, defaultEvent string, window time.Duration, viewerLimit, postLimit, likeLimit int) {
+	detector := newFraudDetector(newFraudRules(window, viewerLimit, postLimit, likeLimit))
+	(&consumer{repo: repo, detector: detector}).consumeTopic(ctx, wg, cfg, defaultEvent)
+}
+
+// ConsumerMetricsForTest exposes the consumer metrics for external tests.
+type ConsumerMetricsForTest = consumerMetrics
+
+// NewConsumerMetricsForTest returns empty consumer metrics.
+func NewConsumerMetricsForTest() *ConsumerMetricsForTest {
+	return newConsumerMetrics()
+}
+
+// ConsumeTopicWithWorkersForTest runs consumeTopic with the given number of
+// workers, recording into metrics.
+func ConsumeTopicWithWorkersForTest(ctx context.Context, wg *sync.WaitGroup, repo statsRepository, metrics *ConsumerMetricsForTest, cfg kafka.ReaderConfig, defaultEvent string, workers int) {
+	(&consumer{repo: repo, metrics: metrics, workers: workers}).consumeTopic(ctx, wg, cfg, defaultEvent)
+}
+
+// NewWatchableStatsServerForTest returns a stats server together with a
+// function that signals new events for a post, as the consumers do.
+func NewWatchableStatsServerForTest(repo statsRepository, postsClient postsClient, interval, poll time.Duration) (statspb.StatsServiceServer, func(postID string)) {
+	hub := newStatsHub()
+	srv := newStatsServer(repo, postsClient, hub, interval)
+	srv.watchPoll = poll
+	return srv, hub.publish
+}
+
+// ExportHandlerForTest exposes the /export/events HTTP handler, accepting
+// identity tokens signed with secret.
+func ExportHandlerForTest(repo statsRepository, postsClient postsClient, secret string) http.Handler {
+	return exportHandler(newStatsServer(repo, postsClient, newStatsHub(), defaultWatchInterval), identity.NewVerifier(secret, "stats-service"))
+}
+
+// DeletedPostsStoreForTest exposes the deleted posts storage interface.
+type DeletedPostsStoreForTest = deletedPostsStore
+
+// ConsumePostEventsForTest runs the post lifecycle consumer.
+func ConsumePostEventsForTest(ctx context.Context, wg *sync.WaitGroup, store deletedPostsStore, cfg kafka.ReaderConfig) {
+	consumePostEvents(ctx, wg, store, cfg)
+}
+
+// UserDataStoreForTest exposes the user data storage interface.
+type UserDataStoreForTest = userDataStore
+
+// ConsumeUserEventsForTest runs the account event consumer.
+func ConsumeUserEventsForTest(ctx context.Context, wg *sync.WaitGroup, store userDataStore, cfg kafka.ReaderConfig) {
+	consumeUserEvents(ctx, wg, store, cfg)
+}
+
+// ReconcileDeletedPostsForTest runs one reconciliation against posts.
+func ReconcileDeletedPostsForTest(ctx context.Context, store deletedPostsStore, posts postsClient) (int, error) {
+	return reconcileDeletedPosts(ctx, store, posts)
+}
+
+// ReplayReaderForTest exposes the reader a replay reads a partition with.
+type ReplayReaderForTest = replayReader
+
+// ReplayStoreForTest exposes the storage a replay writes events to.
+type ReplayStoreForTest = replayStore
+
+// ReplayPartitionForTest replays offsets [start, end) of one partition, read
+// by reader, into the shadow events table of store. No fraud rules apply.
+func ReplayPartitionForTest(ctx context.Context, store replayStore, reader replayReader, topic, defaultType string, start, end int64, deletedPosts, deletedUsers map[string]struct{}) error {
+	original := newReplayReader
+	newReplayReader = func(kafka.ReaderConfig) replayReader { return reader }
+	defer func() { newReplayReader = original }()
+	filter := &replayFilter{deletedPosts: deletedPosts, deletedUsers: deletedUsers}
+	return replayPartitionInto(ctx, store, filter, nil, &replayPartition{topic: topic, defaultType: defaultType, start: start, end: end})
 }
@@ -0,0 +1,64 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+type userDataStore interface {
+	DeleteUserData(ctx context.Context, userID string) error
+}
+
+type userEvent struct {
+	EventType string    `json:"event_type"`
+	UserID    string    `json:"user_id"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// consumeUserEvents erases the views, likes and flagged events of users
+// who deleted their account. Aggregated counters, which hold no user ids,
+// are kept. Other account events are acknowledged and ignored.
+func consumeUserEvents(ctx context.Context, wg *sync.WaitGroup, store userDataStore, cfg kafka.ReaderConfig) {
+	defer wg.Done()
+
+	reader := newKafkaReader(cfg)
+	defer reader.Close()
+
+	for {
+		msg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("read user event failed: %v", err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		var e userEvent
+		if err := json.Unmarshal(msg.Value, &e); err != nil {
+			log.Printf("decode user event failed: %v", err)
+		} else if e.EventType == "user_deleted" && e.UserID != "" {
+			// The offset is not committed until the data is gone.
+			for {
+				err := store.DeleteUserData(ctx, e.UserID)
+				if err == nil || ctx.Err() != nil {
+					break
+				}
+				log.Printf("delete data of user %s failed: %v", e.UserID, err)
+				select {
+				case <-ctx.Done():
+				case <-time.After(time.Second):
+				}
+			}
+		}
+		if err := reader.CommitMessages(ctx, msg); err != nil && ctx.Err() == nil {
+			log.Printf("commit user event failed: %v", err)
+		}
+	}
+}
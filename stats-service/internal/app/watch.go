@@ -0,0 +1,103 @@
+package app
+
+import (
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	statspb "stats-service/proto"
+)
+
+const (
+	defaultWatchInterval = time.Second
+	defaultWatchPoll     = 5 * time.Second
+)
+
+// statsHub fans out "post changed" signals from the consumers to watchers.
+// Each subscriber channel holds at most one pending signal, so bursts of
+// events for a popular post collapse into a single refresh. The hub only
+// sees events stored by this replica; watchers poll for the rest.
+type statsHub struct {
+	mu   sync.Mutex
+	subs map[string]map[chan struct{}]struct{}
+}
+
+func newStatsHub() *statsHub {
+	return &statsHub{subs: make(map[string]map[chan struct{}]struct{})}
+}
+
+func (h *statsHub) subscribe(postID string) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	h.mu.Lock()
+	if h.subs[postID] == nil {
+		h.subs[postID] = make(map[chan struct{}]struct{})
+	}
+	h.subs[postID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subs[postID], ch)
+		if len(h.subs[postID]) == 0 {
+			delete(h.subs, postID)
+		}
+	}
+}
+
+func (h *statsHub) publish(postID string) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs[postID] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (s *statsServer) WatchPostStats(in *statspb.PostStatsRequest, stream grpc.ServerStreamingServer[statspb.PostStatsResponse]) error {
+	postID := in.GetPostId()
+	if postID == "" {
+		return status.Error(codes.InvalidArgument, "post_id is required")
+	}
+	ctx := stream.Context()
+
+	updates, unsubscribe := s.hub.subscribe(postID)
+	defer unsubscribe()
+
+	var last *statspb.PostStatsResponse
+	for {
+		views, likes, err := s.repo.PostStats(ctx, postID)
+		if err != nil {
+			return err
+		}
+		if last == nil || last.GetViews() != views || last.GetLikes() != likes {
+			last = &statspb.PostStatsResponse{PostId: postID, Views: views, Likes: likes}
+			if err := stream.Send(last); err != nil {
+				return err
+			}
+		}
+
+		// Wait out the interval first so that updates arriving meanwhile are
+		// merged into the next refresh.
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(s.watchInterval):
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-updates:
+		case <-time.After(s.watchPoll):
+		}
+	}
+}
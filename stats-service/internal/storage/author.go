@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+type PostTotals struct {
+	PostID string
+	Views  int64
+	Likes  int64
+}
+
+type DayTotals struct {
+	Day   time.Time
+	Views int64
+	Likes int64
+}
+
+type AuthorStats struct {
+	Posts []PostTotals
+	Daily []DayTotals
+	Reach int64
+}
+
+// AuthorStats aggregates the given posts: all-time totals per post, daily
+// totals since the given day and the number of distinct identified users
+// who viewed or liked any of them.
+func (r *Repository) AuthorStats(ctx context.Context, postIDs []string, since time.Time) (AuthorStats, error) {
+	var stats AuthorStats
+	if len(postIDs) == 0 {
+		return stats, nil
+	}
+
+	query := "SELECT post_id, sumIf(cnt, event_type = 'view'), sumIf(cnt, event_type = 'like') FROM " + r.dbName +
+		".post_stats_daily WHERE has(?, post_id) GROUP BY post_id"
+	rows, err := r.conn.Query(ctx, query, postIDs)
+	if err != nil {
+		return stats, err
+	}
+	for rows.Next() {
+		var (
+			id          string
+			views, like uint64
+		)
+		if err := rows.Scan(&id, &views, &like); err != nil {
+			rows.Close()
+			return stats, err
+		}
+		stats.Posts = append(stats.Posts, PostTotals{PostID: id, Views: int64(views), Likes: int64(like)})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return stats, err
+	}
+
+	query = "SELECT day, sumIf(cnt, event_type = 'view'), sumIf(cnt, event_type = 'like') FROM " + r.dbName +
+		".post_stats_daily WHERE has(?, post_id) AND day >= ? GROUP BY day ORDER BY day"
+	rows, err = r.conn.Query(ctx, query, postIDs, since.UTC().Format("2006-01-02"))
+	if err != nil {
+		return stats, err
+	}
+	for rows.Next() {
+		var (
+			day         time.Time
+			views, like uint64
+		)
+		if err := rows.Scan(&day, &views, &like); err != nil {
+			rows.Close()
+			return stats, err
+		}
+		stats.Daily = append(stats.Daily, DayTotals{Day: day, Views: int64(views), Likes: int64(like)})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return stats, err
+	}
+
+	var reach uint64
+	query = "SELECT uniqExact(user_id) FROM " + r.dbName + ".events WHERE has(?, post_id) AND user_id != ''"
+	if err := r.conn.QueryRow(ctx, query, postIDs).Scan(&reach); err != nil {
+		return stats, err
+	}
+	stats.Reach = int64(reach)
+	return stats, nil
+}
@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// purgeConditions match the rows of deleted posts in every table holding
+// per-post data; each ? is bound to the list of post ids.
+var purgeConditions = []struct{ table, where string }{
+	{"events", "has(?, post_id)"},
+	{"post_stats_hourly", "has(?, post_id)"},
+	{"post_stats_daily", "has(?, post_id)"},
+	{"flagged_events", "has(?, post_id)"},
+	{"related_posts", "has(?, post_id) OR has(?, related_post_id)"},
+}
+
+// MarkPostDeleted excludes the post from rankings. Its rows stay until
+// PurgeDeletedPosts removes them. Marking a post twice keeps the first mark,
+// so a redelivered deletion does not schedule a second purge.
+func (r *Repository) MarkPostDeleted(ctx context.Context, postID string, at time.Time) error {
+	query := "INSERT INTO " + r.dbName + ".deleted_posts (post_id, deleted_at, purged_at, updated_at) " +
+		"SELECT ?, ?, NULL, ? WHERE (SELECT count() FROM " + r.dbName + ".deleted_posts WHERE post_id = ?) = 0"
+	return r.conn.Exec(ctx, query, postID, at.UTC(), time.Now().UTC(), postID)
+}
+
+// PurgeDeletedPosts removes the rows of up to limit posts marked deleted
+// with lightweight DELETEs and returns how many posts it purged.
+func (r *Repository) PurgeDeletedPosts(ctx context.Context, limit int) (int, error) {
+	if limit <= 0 {
+		limit = 1000
+	}
+	query := "SELECT post_id, deleted_at FROM " + r.dbName + ".deleted_posts FINAL WHERE purged_at IS NULL ORDER BY deleted_at LIMIT ?"
+	rows, err := r.conn.Query(ctx, query, uint64(limit))
+	if err != nil {
+		return 0, err
+	}
+	var (
+		ids       []string
+		deletedAt []time.Time
+	)
+	for rows.Next() {
+		var id string
+		var at time.Time
+		if err := rows.Scan(&id, &at); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+		deletedAt = append(deletedAt, at)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	for _, c := range purgeConditions {
+		args := make([]any, strings.Count(c.where, "?"))
+		for i := range args {
+			args[i] = ids
+		}
+		if err := r.conn.Exec(ctx, "DELETE FROM "+r.dbName+"."+c.table+" WHERE "+c.where, args...); err != nil {
+			return 0, fmt.Errorf("purge %s: %w", c.table, err)
+		}
+	}
+
+	batch, err := r.conn.PrepareBatch(ctx, "INSERT INTO "+r.dbName+".deleted_posts (post_id, deleted_at, purged_at, updated_at)")
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now().UTC()
+	for i, id := range ids {
+		if err := batch.Append(id, deletedAt[i], &now, now); err != nil {
+			batch.Abort()
+			return 0, err
+		}
+	}
+	if err := batch.Send(); err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// LivePostIDs returns the posts with stats that are not marked deleted.
+func (r *Repository) LivePostIDs(ctx context.Context) ([]string, error) {
+	query := "SELECT DISTINCT post_id FROM " + r.dbName + ".post_stats_daily WHERE post_id NOT IN (SELECT post_id FROM " + r.dbName + ".deleted_posts)"
+	rows, err := r.conn.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// DeletedPostIDs returns every post marked deleted.
+func (r *Repository) DeletedPostIDs(ctx context.Context) (map[string]struct{}, error) {
+	rows, err := r.conn.Query(ctx, "SELECT DISTINCT post_id FROM "+r.dbName+".deleted_posts")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[string]struct{})
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = struct{}{}
+	}
+	return ids, rows.Err()
+}
+
+// notDeleted is a condition on column that skips deleted posts.
+func (r *Repository) notDeleted(column string) string {
+	return column + " NOT IN (SELECT post_id FROM " + r.dbName + ".deleted_posts)"
+}
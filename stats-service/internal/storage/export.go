@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	GranularityRaw  = "raw"
+	GranularityHour = "hour"
+	GranularityDay  = "day"
+)
+
+type ExportFilter struct {
+	From        time.Time
+	To          time.Time
+	PostIDs     []string // nil exports every post
+	Granularity string
+}
+
+type ExportRow struct {
+	Timestamp time.Time
+	EventType string
+	PostID    string
+	UserID    string
+	Count     int64
+}
+
+// ExportEvents calls fn for every row matching the filter, ordered by time.
+// Rows are read from the server as a stream, so memory use does not depend
+// on the size of the export.
+func (r *Repository) ExportEvents(ctx context.Context, filter ExportFilter, fn func(ExportRow) error) error {
+	var query string
+	switch filter.Granularity {
+	case GranularityRaw, "":
+		query = "SELECT ts, event_type, post_id, user_id, toUInt64(1) FROM " + r.dbName + ".events WHERE ts >= ? AND ts < ?"
+	case GranularityHour:
+		query = "SELECT hour, event_type, post_id, '', sum(cnt) FROM " + r.dbName + ".post_stats_hourly WHERE hour >= ? AND hour < ?"
+	case GranularityDay:
+		query = "SELECT toDateTime(day), event_type, post_id, '', sum(cnt) FROM " + r.dbName + ".post_stats_daily WHERE day >= toDate(?) AND day < toDate(?)"
+	default:
+		return fmt.Errorf("unknown granularity %q", filter.Granularity)
+	}
+
+	args := []any{filter.From.UTC(), filter.To.UTC()}
+	if filter.Granularity == GranularityDay {
+		args[1] = dayEnd(filter.To)
+	}
+	if filter.PostIDs != nil {
+		query += " AND has(?, post_id)"
+		args = append(args, filter.PostIDs)
+	}
+	switch filter.Granularity {
+	case GranularityHour:
+		query += " GROUP BY hour, event_type, post_id ORDER BY hour"
+	case GranularityDay:
+		query += " GROUP BY day, event_type, post_id ORDER BY day"
+	default:
+		query += " ORDER BY ts"
+	}
+
+	rows, err := r.conn.Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			row ExportRow
+			cnt uint64
+		)
+		if err := rows.Scan(&row.Timestamp, &row.EventType, &row.PostID, &row.UserID, &cnt); err != nil {
+			return err
+		}
+		row.Count = int64(cnt)
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// dayEnd returns the start of the day after the one t falls in, or t itself
+// at midnight, so that a daily export includes the day to ends in.
+func dayEnd(t time.Time) time.Time {
+	day := t.UTC().Truncate(24 * time.Hour)
+	if day.Equal(t.UTC()) {
+		return day
+	}
+	return day.Add(24 * time.Hour)
+}
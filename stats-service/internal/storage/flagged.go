@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+)
+
+const (
+	FlagPending  = "pending"
+	FlagApproved = "approved"
+	FlagRejected = "rejected"
+)
+
+var (
+	ErrFlagNotFound = errors.New("flagged event not found")
+	ErrFlagReviewed = errors.New("flagged event already reviewed")
+)
+
+// FlaggedEvent is an event kept out of the counters until a moderator
+// approves it.
+type FlaggedEvent struct {
+	ID string
+	Event
+	IP         string
+	Reason     string
+	Status     string
+	ReviewedBy string
+}
+
+func (r *Repository) FlagEvent(ctx context.Context, f FlaggedEvent) error {
+	if f.Status == "" {
+		f.Status = FlagPending
+	}
+	query := "INSERT INTO " + r.dbName + ".flagged_events (id, event_type, post_id, user_id, ip, ts, reason, status, reviewed_by, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	return r.conn.Exec(ctx, query, f.ID, f.EventType, f.PostID, f.UserID, f.IP, f.Timestamp, f.Reason, f.Status, f.ReviewedBy, time.Now().UTC())
+}
+
+// FlaggedEvents lists flagged events with the given status, newest first.
+// An empty postID matches every post.
+func (r *Repository) FlaggedEvents(ctx context.Context, status, postID string, limit int) ([]FlaggedEvent, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	query := "SELECT id, event_type, post_id, user_id, ip, ts, reason, status, reviewed_by FROM " + r.dbName +
+		".flagged_events FINAL WHERE status = ? AND (? = '' OR post_id = ?) ORDER BY ts DESC LIMIT ?"
+	rows, err := r.conn.Query(ctx, query, status, postID, postID, uint64(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []FlaggedEvent
+	for rows.Next() {
+		var f FlaggedEvent
+		if err := rows.Scan(&f.ID, &f.EventType, &f.PostID, &f.UserID, &f.IP, &f.Timestamp, &f.Reason, &f.Status, &f.ReviewedBy); err != nil {
+			return nil, err
+		}
+		result = append(result, f)
+	}
+	return result, rows.Err()
+}
+
+// ReviewFlaggedEvent records the moderator's decision. Approved events are
+// inserted into the events table and so reach every counter.
+func (r *Repository) ReviewFlaggedEvent(ctx context.Context, id string, approve bool, reviewer string) (FlaggedEvent, error) {
+	var f FlaggedEvent
+	query := "SELECT id, event_type, post_id, user_id, ip, ts, reason, status, reviewed_by FROM " + r.dbName + ".flagged_events FINAL WHERE id = ?"
+	rows, err := r.conn.Query(ctx, query, id)
+	if err != nil {
+		return f, err
+	}
+	found := rows.Next()
+	if found {
+		err = rows.Scan(&f.ID, &f.EventType, &f.PostID, &f.UserID, &f.IP, &f.Timestamp, &f.Reason, &f.Status, &f.ReviewedBy)
+	}
+	rows.Close()
+	if err != nil {
+		return f, err
+	}
+	if !found {
+		return f, ErrFlagNotFound
+	}
+	if f.Status != FlagPending {
+		return f, ErrFlagReviewed
+	}
+	if decided, err := r.finishReview(ctx, f); decided || err != nil {
+		return f, cmp.Or(err, ErrFlagReviewed)
+	}
+	claim, err := r.claimReview(ctx, id)
+	if err != nil {
+		return f, err
+	}
+
+	f.Status, f.ReviewedBy = FlagRejected, reviewer
+	if approve {
+		f.Status = FlagApproved
+	}
+	// From here on the decision stands: once recorded, a failure below is
+	// finished by the next review instead of leaving the event pending.
+	if err := r.conn.Exec(ctx, "INSERT INTO "+r.dbName+".flag_reviews (id, claim, decision, reviewed_by) VALUES (?, ?, ?, ?)",
+		id, claim, f.Status, reviewer); err != nil {
+		r.releaseReview(id)
+		return f, err
+	}
+	if approve {
+		if err := r.SaveEvent(ctx, f.Event); err != nil {
+			r.releaseReview(id)
+			return f, err
+		}
+	}
+	return f, r.storeDecision(f)
+}
+
+// finishReview stores a decision recorded by an earlier review that failed
+// before updating the flagged event, and reports whether there was one.
+func (r *Repository) finishReview(ctx context.Context, f FlaggedEvent) (bool, error) {
+	rows, err := r.conn.Query(ctx,
+		"SELECT decision, reviewed_by FROM "+r.dbName+".flag_reviews WHERE id = ? AND decision != '' ORDER BY claimed_at LIMIT 1", f.ID)
+	if err != nil {
+		return false, err
+	}
+	found := rows.Next()
+	if found {
+		err = rows.Scan(&f.Status, &f.ReviewedBy)
+	}
+	rows.Close()
+	if err != nil || !found {
+		return false, err
+	}
+	return true, r.storeDecision(f)
+}
+
+// storeDecision updates the flagged event, retrying for a while: the
+// approved event may already be in the counters.
+func (r *Repository) storeDecision(f FlaggedEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	backoff := 100 * time.Millisecond
+	for {
+		err := r.FlagEvent(ctx, f)
+		if err == nil {
+			return nil
+		}
+		log.Printf("store review of flagged event %s: %v", f.ID, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, 5*time.Second)
+	}
+}
+
+// claimReview records a claim on the flagged event and checks that it is the
+// earliest one, so two concurrent reviews cannot both insert the event.
+func (r *Repository) claimReview(ctx context.Context, id string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	claim := hex.EncodeToString(b)
+	if err := r.conn.Exec(ctx, "INSERT INTO "+r.dbName+".flag_reviews (id, claim) VALUES (?, ?)", id, claim); err != nil {
+		return "", err
+	}
+
+	var winner string
+	query := "SELECT argMin(claim, (claimed_at, claim)) FROM " + r.dbName + ".flag_reviews WHERE id = ?"
+	if err := r.conn.QueryRow(ctx, query, id).Scan(&winner); err != nil {
+		return "", err
+	}
+	if winner != claim {
+		return "", ErrFlagReviewed
+	}
+	return claim, nil
+}
+
+// releaseReview drops the claims of a review that failed before the approved
+// event was stored, so the event can be reviewed again.
+func (r *Repository) releaseReview(id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := r.conn.Exec(ctx, "DELETE FROM "+r.dbName+".flag_reviews WHERE id = ?", id); err != nil {
+		log.Printf("release review claim %s: %v", id, err)
+	}
+}
+
+// RestoreApprovedFlags copies approved flagged events since the given time
+// into table, so a replay that drops flagged traffic keeps the moderators'
+// decisions.
+func (r *Repository) RestoreApprovedFlags(ctx context.Context, table string, since time.Time) error {
+	query := "INSERT INTO " + r.dbName + "." + table + " (event_type, post_id, user_id, ts) " +
+		"SELECT event_type, post_id, user_id, ts FROM " + r.dbName + ".flagged_events FINAL WHERE status = ? AND ts >= ?"
+	return r.conn.Exec(ctx, query, FlagApproved, since.UTC())
+}
@@ -0,0 +1,615 @@
+package storage
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Memory keeps every event in process memory and answers the same queries
+// as Repository by scanning them. It is meant for local development and CI,
+// not for production volumes. When opened with OpenFile, events are also
+// appended to a JSON lines file and reloaded on the next start.
+type Memory struct {
+	mu      sync.RWMutex
+	events  []Event
+	related map[string][]PostScore
+	flagged map[string]FlaggedEvent
+	// deleted maps deleted posts to whether their events were purged.
+	deleted map[string]bool
+	file    *os.File
+}
+
+func NewMemory() *Memory {
+	return &Memory{related: make(map[string][]PostScore), flagged: make(map[string]FlaggedEvent), deleted: make(map[string]bool)}
+}
+
+// OpenFile loads the events stored at path, creating the file if needed.
+func OpenFile(path string) (*Memory, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	m := NewMemory()
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		var rec fileRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		e := Event{PostID: rec.PostID, EventType: rec.EventType, UserID: rec.UserID, Timestamp: rec.Timestamp}
+		if rec.UserDeleted {
+			m.deleteUserLocked(rec.UserID)
+			continue
+		}
+		if rec.Deleted {
+			// Purges are not logged: the events are dropped again by the
+			// next PurgeDeletedPosts.
+			m.deleted[rec.PostID] = false
+			continue
+		}
+		if rec.Flag == nil {
+			m.events = append(m.events, e)
+			continue
+		}
+		m.flagged[rec.Flag.ID] = FlaggedEvent{
+			ID:         rec.Flag.ID,
+			Event:      e,
+			IP:         rec.Flag.IP,
+			Reason:     rec.Flag.Reason,
+			Status:     rec.Flag.Status,
+			ReviewedBy: rec.Flag.ReviewedBy,
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	m.file = f
+	return m, nil
+}
+
+type fileRecord struct {
+	PostID    string    `json:"post_id"`
+	EventType string    `json:"event_type"`
+	UserID    string    `json:"user_id,omitempty"`
+	Timestamp time.Time `json:"ts"`
+	// Flag is set on lines recording the latest state of a flagged event.
+	Flag *fileFlag `json:"flag,omitempty"`
+	// Deleted marks lines recording that the post was deleted at Timestamp.
+	Deleted bool `json:"deleted,omitempty"`
+	// UserDeleted marks lines recording that the events of UserID up to
+	// that line were deleted.
+	UserDeleted bool `json:"user_deleted,omitempty"`
+}
+
+type fileFlag struct {
+	ID         string `json:"id"`
+	IP         string `json:"ip,omitempty"`
+	Reason     string `json:"reason"`
+	Status     string `json:"status"`
+	ReviewedBy string `json:"reviewed_by,omitempty"`
+}
+
+// appendRecord writes one line to the backing file, if any. Callers hold
+// the write lock.
+func (m *Memory) appendRecord(rec fileRecord) error {
+	if m.file == nil {
+		return nil
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	_, err = m.file.Write(append(line, '\n'))
+	return err
+}
+
+func (m *Memory) Close() error {
+	if m.file == nil {
+		return nil
+	}
+	return m.file.Close()
+}
+
+func (m *Memory) SaveEvent(_ context.Context, e Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.saveEventLocked(e)
+}
+
+func (m *Memory) saveEventLocked(e Event) error {
+	if err := m.appendRecord(fileRecord{PostID: e.PostID, EventType: e.EventType, UserID: e.UserID, Timestamp: e.Timestamp}); err != nil {
+		return err
+	}
+	m.events = append(m.events, e)
+	return nil
+}
+
+func (m *Memory) PostStats(_ context.Context, postID string) (views, likes int64, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, e := range m.events {
+		if e.PostID != postID {
+			continue
+		}
+		switch e.EventType {
+		case "view":
+			views++
+		case "like":
+			likes++
+		}
+	}
+	return views, likes, nil
+}
+
+func (m *Memory) TopPosts(_ context.Context, eventType string, limit int) ([]PostCount, error) {
+	if limit <= 0 {
+		limit = 5
+	}
+	result := m.countByPost(eventType)
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+func (m *Memory) LikesPerPost(_ context.Context) ([]PostCount, error) {
+	return m.countByPost("like"), nil
+}
+
+// countByPost returns the number of events of the type per post, highest
+// first.
+func (m *Memory) countByPost(eventType string) []PostCount {
+	m.mu.RLock()
+	counts := make(map[string]int64)
+	for _, e := range m.events {
+		if _, deleted := m.deleted[e.PostID]; !deleted && e.EventType == eventType {
+			counts[e.PostID]++
+		}
+	}
+	m.mu.RUnlock()
+
+	result := make([]PostCount, 0, len(counts))
+	for id, n := range counts {
+		result = append(result, PostCount{PostID: id, Value: n})
+	}
+	slices.SortFunc(result, func(a, b PostCount) int {
+		return cmp.Or(cmp.Compare(b.Value, a.Value), cmp.Compare(a.PostID, b.PostID))
+	})
+	return result
+}
+
+// TrendingPosts mirrors the ClickHouse query: events are bucketed by hour
+// and each bucket is weighted by its age.
+func (m *Memory) TrendingPosts(_ context.Context, window, halfLife time.Duration, likeWeight float64, limit int) ([]PostScore, error) {
+	if limit <= 0 {
+		limit = 5
+	}
+	now := time.Now()
+	since := now.Add(-window)
+
+	m.mu.RLock()
+	scores := make(map[string]float64)
+	for _, e := range m.events {
+		hour := e.Timestamp.Truncate(time.Hour)
+		if _, deleted := m.deleted[e.PostID]; deleted || hour.Before(since) {
+			continue
+		}
+		weight := 1.0
+		if e.EventType == "like" {
+			weight = likeWeight
+		}
+		scores[e.PostID] += weight * math.Exp2(-now.Sub(hour).Seconds()/halfLife.Seconds())
+	}
+	m.mu.RUnlock()
+
+	result := sortedScores(scores)
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// ComputeRelatedPosts replaces the neighbours of every post liked within the
+// window, using the same cosine similarity as Repository.
+func (m *Memory) ComputeRelatedPosts(_ context.Context, window time.Duration, topN int) error {
+	if topN <= 0 {
+		topN = 20
+	}
+	since := time.Now().Add(-window)
+
+	m.mu.RLock()
+	likers := make(map[string]map[string]struct{})
+	for _, e := range m.events {
+		if e.EventType != "like" || e.UserID == "" || e.Timestamp.Before(since) {
+			continue
+		}
+		if _, deleted := m.deleted[e.PostID]; deleted {
+			continue
+		}
+		if likers[e.PostID] == nil {
+			likers[e.PostID] = make(map[string]struct{})
+		}
+		likers[e.PostID][e.UserID] = struct{}{}
+	}
+	m.mu.RUnlock()
+
+	related := make(map[string][]PostScore)
+	for a, usersA := range likers {
+		scores := make(map[string]float64)
+		for b, usersB := range likers {
+			if a == b {
+				continue
+			}
+			together := 0
+			for u := range usersA {
+				if _, ok := usersB[u]; ok {
+					together++
+				}
+			}
+			if together > 0 {
+				scores[b] = float64(together) / math.Sqrt(float64(len(usersA)*len(usersB)))
+			}
+		}
+		if neighbours := sortedScores(scores); len(neighbours) > 0 {
+			related[a] = neighbours[:min(topN, len(neighbours))]
+		}
+	}
+
+	m.mu.Lock()
+	m.related = related
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *Memory) RelatedPosts(_ context.Context, postID string, limit int) ([]PostScore, error) {
+	if limit <= 0 {
+		limit = 5
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var result []PostScore
+	for _, p := range m.related[postID] {
+		if _, deleted := m.deleted[p.PostID]; !deleted && len(result) < limit {
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+func (m *Memory) AuthorStats(_ context.Context, postIDs []string, since time.Time) (AuthorStats, error) {
+	var stats AuthorStats
+	if len(postIDs) == 0 {
+		return stats, nil
+	}
+	sinceDay := since.UTC().Truncate(24 * time.Hour)
+
+	m.mu.RLock()
+	posts := make(map[string]*PostTotals)
+	days := make(map[time.Time]*DayTotals)
+	users := make(map[string]struct{})
+	for _, e := range m.events {
+		if !slices.Contains(postIDs, e.PostID) {
+			continue
+		}
+		p := posts[e.PostID]
+		if p == nil {
+			p = &PostTotals{PostID: e.PostID}
+			posts[e.PostID] = p
+		}
+		var d *DayTotals
+		if day := e.Timestamp.UTC().Truncate(24 * time.Hour); !day.Before(sinceDay) {
+			if d = days[day]; d == nil {
+				d = &DayTotals{Day: day}
+				days[day] = d
+			}
+		}
+		switch e.EventType {
+		case "view":
+			p.Views++
+			if d != nil {
+				d.Views++
+			}
+		case "like":
+			p.Likes++
+			if d != nil {
+				d.Likes++
+			}
+		}
+		if e.UserID != "" {
+			users[e.UserID] = struct{}{}
+		}
+	}
+	m.mu.RUnlock()
+
+	for _, p := range posts {
+		stats.Posts = append(stats.Posts, *p)
+	}
+	slices.SortFunc(stats.Posts, func(a, b PostTotals) int { return cmp.Compare(a.PostID, b.PostID) })
+	for _, d := range days {
+		stats.Daily = append(stats.Daily, *d)
+	}
+	slices.SortFunc(stats.Daily, func(a, b DayTotals) int { return a.Day.Compare(b.Day) })
+	stats.Reach = int64(len(users))
+	return stats, nil
+}
+
+func (m *Memory) ExportEvents(ctx context.Context, filter ExportFilter, fn func(ExportRow) error) error {
+	var bucket func(time.Time) time.Time
+	switch filter.Granularity {
+	case GranularityRaw, "":
+	case GranularityHour:
+		bucket = func(t time.Time) time.Time { return t.UTC().Truncate(time.Hour) }
+	case GranularityDay:
+		bucket = func(t time.Time) time.Time { return t.UTC().Truncate(24 * time.Hour) }
+	default:
+		return fmt.Errorf("unknown granularity %q", filter.Granularity)
+	}
+
+	// Like the ClickHouse query, hourly buckets are compared with the exact
+	// bounds while daily ones compare dates, including the day to ends in.
+	from, to := filter.From.UTC(), filter.To.UTC()
+	if filter.Granularity == GranularityDay {
+		from, to = bucket(from), dayEnd(to)
+	}
+
+	m.mu.RLock()
+	var rows []ExportRow
+	for _, e := range m.events {
+		ts := e.Timestamp
+		if bucket != nil {
+			ts = bucket(ts)
+		}
+		if ts.Before(from) || !ts.Before(to) {
+			continue
+		}
+		if filter.PostIDs != nil && !slices.Contains(filter.PostIDs, e.PostID) {
+			continue
+		}
+		rows = append(rows, ExportRow{Timestamp: ts, EventType: e.EventType, PostID: e.PostID, UserID: e.UserID, Count: 1})
+	}
+	m.mu.RUnlock()
+
+	slices.SortStableFunc(rows, func(a, b ExportRow) int { return a.Timestamp.Compare(b.Timestamp) })
+	if bucket != nil {
+		rows = mergeExportRows(rows)
+	}
+	for _, row := range rows {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeExportRows sums rows sharing a bucket, event type and post. Rows must
+// be sorted by time.
+func mergeExportRows(rows []ExportRow) []ExportRow {
+	var merged []ExportRow
+	for start := 0; start < len(rows); {
+		end := start
+		for end < len(rows) && rows[end].Timestamp.Equal(rows[start].Timestamp) {
+			end++
+		}
+		group := rows[start:end]
+		slices.SortFunc(group, func(a, b ExportRow) int {
+			return cmp.Or(cmp.Compare(a.EventType, b.EventType), cmp.Compare(a.PostID, b.PostID))
+		})
+		for _, row := range group {
+			row.UserID = ""
+			if n := len(merged); n > 0 && merged[n-1].Timestamp.Equal(row.Timestamp) &&
+				merged[n-1].EventType == row.EventType && merged[n-1].PostID == row.PostID {
+				merged[n-1].Count += row.Count
+				continue
+			}
+			merged = append(merged, row)
+		}
+		start = end
+	}
+	return merged
+}
+
+func sortedScores(scores map[string]float64) []PostScore {
+	result := make([]PostScore, 0, len(scores))
+	for id, score := range scores {
+		result = append(result, PostScore{PostID: id, Score: score})
+	}
+	slices.SortFunc(result, func(a, b PostScore) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), cmp.Compare(a.PostID, b.PostID))
+	})
+	return result
+}
+
+func (m *Memory) FlagEvent(_ context.Context, f FlaggedEvent) error {
+	if f.Status == "" {
+		f.Status = FlagPending
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.flagLocked(f)
+}
+
+func (m *Memory) flagLocked(f FlaggedEvent) error {
+	flag := &fileFlag{ID: f.ID, IP: f.IP, Reason: f.Reason, Status: f.Status, ReviewedBy: f.ReviewedBy}
+	if err := m.appendRecord(fileRecord{PostID: f.PostID, EventType: f.EventType, UserID: f.UserID, Timestamp: f.Timestamp, Flag: flag}); err != nil {
+		return err
+	}
+	m.flagged[f.ID] = f
+	return nil
+}
+
+func (m *Memory) FlaggedEvents(_ context.Context, status, postID string, limit int) ([]FlaggedEvent, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	m.mu.RLock()
+	var result []FlaggedEvent
+	for _, f := range m.flagged {
+		if f.Status == status && (postID == "" || f.PostID == postID) {
+			result = append(result, f)
+		}
+	}
+	m.mu.RUnlock()
+
+	slices.SortFunc(result, func(a, b FlaggedEvent) int {
+		return cmp.Or(b.Timestamp.Compare(a.Timestamp), cmp.Compare(a.ID, b.ID))
+	})
+	return result[:min(limit, len(result))], nil
+}
+
+func (m *Memory) ReviewFlaggedEvent(_ context.Context, id string, approve bool, reviewer string) (FlaggedEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, ok := m.flagged[id]
+	if !ok {
+		return f, ErrFlagNotFound
+	}
+	if f.Status != FlagPending {
+		return f, ErrFlagReviewed
+	}
+
+	f.Status, f.ReviewedBy = FlagRejected, reviewer
+	if approve {
+		f.Status = FlagApproved
+		if err := m.saveEventLocked(f.Event); err != nil {
+			return f, err
+		}
+	}
+	return f, m.flagLocked(f)
+}
+
+func (m *Memory) MarkPostDeleted(_ context.Context, postID string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.deleted[postID]; ok {
+		return nil
+	}
+	if err := m.appendRecord(fileRecord{PostID: postID, Timestamp: at, Deleted: true}); err != nil {
+		return err
+	}
+	m.deleted[postID] = false
+	return nil
+}
+
+func (m *Memory) PurgeDeletedPosts(_ context.Context, limit int) (int, error) {
+	if limit <= 0 {
+		limit = 1000
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	purge := make(map[string]struct{})
+	for id, purged := range m.deleted {
+		if !purged && len(purge) < limit {
+			purge[id] = struct{}{}
+		}
+	}
+	if len(purge) == 0 {
+		return 0, nil
+	}
+	isPurged := func(id string) bool {
+		_, ok := purge[id]
+		return ok
+	}
+
+	m.events = slices.DeleteFunc(m.events, func(e Event) bool { return isPurged(e.PostID) })
+	for id, f := range m.flagged {
+		if isPurged(f.PostID) {
+			delete(m.flagged, id)
+		}
+	}
+	for id, neighbours := range m.related {
+		if isPurged(id) {
+			delete(m.related, id)
+			continue
+		}
+		m.related[id] = slices.DeleteFunc(neighbours, func(p PostScore) bool { return isPurged(p.PostID) })
+	}
+	for id := range purge {
+		m.deleted[id] = true
+	}
+	return len(purge), nil
+}
+
+func (m *Memory) LivePostIDs(_ context.Context) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	seen := make(map[string]struct{})
+	var ids []string
+	for _, e := range m.events {
+		if _, deleted := m.deleted[e.PostID]; deleted {
+			continue
+		}
+		if _, ok := seen[e.PostID]; !ok {
+			seen[e.PostID] = struct{}{}
+			ids = append(ids, e.PostID)
+		}
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+func (m *Memory) DeleteUserData(_ context.Context, userID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.appendRecord(fileRecord{UserID: userID, Timestamp: time.Now().UTC(), UserDeleted: true}); err != nil {
+		return err
+	}
+	m.deleteUserLocked(userID)
+	return nil
+}
+
+func (m *Memory) UserEvents(_ context.Context, userID string, fn func(Event) error) error {
+	m.mu.RLock()
+	var events []Event
+	for _, e := range m.events {
+		if e.UserID == userID {
+			events = append(events, e)
+		}
+	}
+	m.mu.RUnlock()
+
+	slices.SortStableFunc(events, func(a, b Event) int { return a.Timestamp.Compare(b.Timestamp) })
+	for _, e := range events {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Memory) UserFlaggedEvents(_ context.Context, userID string) ([]FlaggedEvent, error) {
+	m.mu.RLock()
+	var result []FlaggedEvent
+	for _, f := range m.flagged {
+		if f.UserID == userID {
+			result = append(result, f)
+		}
+	}
+	m.mu.RUnlock()
+
+	slices.SortFunc(result, func(a, b FlaggedEvent) int {
+		return cmp.Or(a.Timestamp.Compare(b.Timestamp), cmp.Compare(a.ID, b.ID))
+	})
+	return result, nil
+}
+
+func (m *Memory) deleteUserLocked(userID string) {
+	m.events = slices.DeleteFunc(m.events, func(e Event) bool { return e.UserID == userID })
+	for id, f := range m.flagged {
+		if f.UserID == userID {
+			delete(m.flagged, id)
+		}
+	}
+}
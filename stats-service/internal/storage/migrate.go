@@ -0,0 +1,471 @@
+package storage
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	ch "github.com/ClickHouse/clickhouse-go/v2"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+var migrationFileRe = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// destructiveRe matches the comment a down migration starts with when
+// reverting it loses data that cannot be rebuilt.
+var destructiveRe = regexp.MustCompile(`(?m)^--\s*destructive:\s*(.+)$`)
+
+const (
+	migrationLockTable = "schema_migrations_lock"
+	// The holder of the lock renews its heartbeat every
+	// migrationLockHeartbeat; a lock without one for migrationLockStale
+	// belongs to a process that died.
+	migrationLockHeartbeat = 10 * time.Second
+	migrationLockStale     = time.Minute
+	migrationLockPoll      = time.Second
+	// errTableAlreadyExists and errUnknownTable are ClickHouse error codes.
+	errTableAlreadyExists = 57
+	errUnknownTable       = 60
+)
+
+// Migration is a numbered schema change. Statements reference the database
+// through the ${db} placeholder and the moment the migration first started,
+// as a DateTime literal, through ${cutoff}. The cutoff is stored in
+// schema_migrations, so a rerun after a failure splits at the same moment.
+type Migration struct {
+	Version int
+	Name    string
+	Up      []string
+	Down    []string
+	// Destructive explains why reverting the migration loses data; empty
+	// when it does not. MigrateDown refuses such steps unless forced.
+	Destructive string
+}
+
+// Migrations returns the embedded migrations ordered by version.
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		m := migrationFileRe.FindStringSubmatch(entry.Name())
+		if m == nil {
+			return nil, fmt.Errorf("unexpected migration file %q", entry.Name())
+		}
+		version, _ := strconv.Atoi(m[1])
+		content, err := migrationFiles.ReadFile("migrations/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = SplitStatements(string(content))
+		} else {
+			mig.Down = SplitStatements(string(content))
+			if m := destructiveRe.FindStringSubmatch(string(content)); m != nil {
+				mig.Destructive = strings.TrimSpace(m[1])
+			}
+		}
+	}
+
+	result := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if len(mig.Up) == 0 {
+			return nil, fmt.Errorf("migration %d has no up statements", mig.Version)
+		}
+		result = append(result, *mig)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+	return result, nil
+}
+
+// SplitStatements splits a migration file on semicolons, leaving alone
+// those inside quoted literals and identifiers, and drops -- comments.
+func SplitStatements(content string) []string {
+	var (
+		statements []string
+		current    strings.Builder
+		quote      byte
+	)
+	flush := func() {
+		if stmt := strings.TrimSpace(current.String()); stmt != "" {
+			statements = append(statements, stmt)
+		}
+		current.Reset()
+	}
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case quote != 0:
+			current.WriteByte(c)
+			if c == '\\' && i+1 < len(content) {
+				i++
+				current.WriteByte(content[i])
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+			current.WriteByte(c)
+		case strings.HasPrefix(content[i:], "--"):
+			if n := strings.IndexByte(content[i:], '\n'); n >= 0 {
+				i += n - 1
+			} else {
+				i = len(content)
+			}
+		case c == ';':
+			flush()
+		default:
+			current.WriteByte(c)
+		}
+	}
+	flush()
+	return statements
+}
+
+// PlanUp returns the pending migrations up to and including target, or all of
+// them when target is zero.
+func PlanUp(all []Migration, applied []int, target int) []Migration {
+	done := make(map[int]bool, len(applied))
+	for _, v := range applied {
+		done[v] = true
+	}
+	var plan []Migration
+	for _, m := range all {
+		if target > 0 && m.Version > target {
+			break
+		}
+		if !done[m.Version] {
+			plan = append(plan, m)
+		}
+	}
+	return plan
+}
+
+// PlanDown returns the last steps applied migrations in the order they must
+// be reverted.
+func PlanDown(all []Migration, applied []int, steps int) []Migration {
+	done := make(map[int]bool, len(applied))
+	for _, v := range applied {
+		done[v] = true
+	}
+	var plan []Migration
+	for i := len(all) - 1; i >= 0 && len(plan) < steps; i-- {
+		if done[all[i].Version] {
+			plan = append(plan, all[i])
+		}
+	}
+	return plan
+}
+
+// MigrateUp applies pending migrations up to target (all when zero). With
+// dryRun set the plan is returned without touching the database. Replicas
+// starting together take turns through the migration lock.
+func (r *Repository) MigrateUp(ctx context.Context, target int, dryRun bool) ([]Migration, error) {
+	if !dryRun {
+		unlock, err := r.lockMigrations(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+	all, applied, err := r.migrationState(ctx, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	plan := PlanUp(all, applied, target)
+	if dryRun {
+		return plan, nil
+	}
+	for _, m := range plan {
+		if err := r.runMigration(ctx, m, m.Up, true); err != nil {
+			return nil, err
+		}
+	}
+	return plan, nil
+}
+
+// MigrateDown reverts the last steps applied migrations. Steps that lose
+// data are refused unless force is set.
+func (r *Repository) MigrateDown(ctx context.Context, steps int, dryRun, force bool) ([]Migration, error) {
+	if !dryRun {
+		unlock, err := r.lockMigrations(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+	all, applied, err := r.migrationState(ctx, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	plan := PlanDown(all, applied, steps)
+	if dryRun {
+		return plan, nil
+	}
+	if !force {
+		for _, m := range plan {
+			if m.Destructive != "" {
+				return nil, fmt.Errorf("reverting %04d_%s %s; rerun with force to do it anyway", m.Version, m.Name, m.Destructive)
+			}
+		}
+	}
+	for _, m := range plan {
+		if err := r.runMigration(ctx, m, m.Down, false); err != nil {
+			return nil, err
+		}
+	}
+	return plan, nil
+}
+
+// AppliedMigrations returns the versions currently applied, in order.
+func (r *Repository) AppliedMigrations(ctx context.Context) ([]int, error) {
+	if err := r.ensureMigrationsTable(ctx); err != nil {
+		return nil, err
+	}
+	return r.appliedVersions(ctx)
+}
+
+// Statements renders migration statements for this repository's database,
+// taking the current time as the cutoff.
+func (r *Repository) Statements(stmts []string) []string {
+	return RenderStatements(stmts, r.dbName, time.Now())
+}
+
+// RenderStatements substitutes the placeholders of migration statements.
+func RenderStatements(stmts []string, db string, cutoff time.Time) []string {
+	replacer := strings.NewReplacer(
+		"${db}", db,
+		"${cutoff}", "toDateTime('"+cutoff.UTC().Format(time.DateTime)+"', 'UTC')",
+	)
+	out := make([]string, len(stmts))
+	for i, stmt := range stmts {
+		out[i] = replacer.Replace(stmt)
+	}
+	return out
+}
+
+func (r *Repository) migrationState(ctx context.Context, dryRun bool) ([]Migration, []int, error) {
+	all, err := Migrations()
+	if err != nil {
+		return nil, nil, err
+	}
+	if !dryRun {
+		if err := r.ensureMigrationsTable(ctx); err != nil {
+			return nil, nil, err
+		}
+	} else if exists, err := r.migrationsTableExists(ctx); err != nil || !exists {
+		return all, nil, err
+	}
+	applied, err := r.appliedVersions(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return all, applied, nil
+}
+
+func (r *Repository) runMigration(ctx context.Context, m Migration, stmts []string, applied bool) error {
+	// Reverting clears the cutoff, so applying the migration again starts
+	// over from a new one.
+	var cutoff time.Time
+	if applied {
+		var err error
+		if cutoff, err = r.migrationCutoff(ctx, m); err != nil {
+			return fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+	}
+	for _, stmt := range RenderStatements(stmts, r.dbName, cutoff) {
+		if err := r.conn.Exec(ctx, stmt); err != nil {
+			return fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+	}
+	return r.recordMigration(ctx, m, applied, cutoff)
+}
+
+// migrationCutoff returns the cutoff of an earlier, failed run of m, or
+// records the current time as its cutoff.
+func (r *Repository) migrationCutoff(ctx context.Context, m Migration) (time.Time, error) {
+	var cutoff time.Time
+	err := r.conn.QueryRow(ctx,
+		"SELECT argMax(cutoff, changed_at) FROM "+r.dbName+".schema_migrations WHERE version = ?", uint32(m.Version),
+	).Scan(&cutoff)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if cutoff.Unix() > 0 {
+		return cutoff.UTC(), nil
+	}
+	cutoff = time.Now().UTC().Truncate(time.Second)
+	return cutoff, r.recordMigration(ctx, m, false, cutoff)
+}
+
+func (r *Repository) recordMigration(ctx context.Context, m Migration, applied bool, cutoff time.Time) error {
+	var flag uint8
+	if applied {
+		flag = 1
+	}
+	if cutoff.IsZero() {
+		cutoff = time.Unix(0, 0)
+	}
+	query := "INSERT INTO " + r.dbName + ".schema_migrations (version, name, applied, changed_at, cutoff) VALUES (?, ?, ?, ?, ?)"
+	return r.conn.Exec(ctx, query, uint32(m.Version), m.Name, flag, time.Now().UTC(), cutoff.UTC())
+}
+
+// lockMigrations serializes migrations across processes. ClickHouse has no
+// advisory locks, so the lock is a table: creating it fails while another
+// process holds it. The holder keeps inserting heartbeats; a lock without
+// one for migrationLockStale is broken. The table is in memory, so a
+// ClickHouse restart also leaves the lock without heartbeats.
+func (r *Repository) lockMigrations(ctx context.Context) (func(), error) {
+	if err := r.conn.Exec(ctx, "CREATE DATABASE IF NOT EXISTS "+r.dbName); err != nil {
+		return nil, err
+	}
+	table := r.dbName + "." + migrationLockTable
+	for {
+		err := r.conn.Exec(ctx, "CREATE TABLE "+table+" (owner String, heartbeat DateTime) ENGINE = Memory")
+		if err == nil {
+			return r.holdMigrationLock(ctx, table)
+		}
+		var exc *ch.Exception
+		if !errors.As(err, &exc) || exc.Code != errTableAlreadyExists {
+			return nil, err
+		}
+
+		// Until its first heartbeat, a lock is as old as its table.
+		var age int64
+		err = r.conn.QueryRow(ctx,
+			"SELECT toInt64(dateDiff('second', greatest(max(heartbeat), "+
+				"(SELECT any(metadata_modification_time) FROM system.tables WHERE database = ? AND name = ?)), now())) FROM "+table,
+			r.dbName, migrationLockTable).Scan(&age)
+		switch {
+		case errors.As(err, &exc) && exc.Code == errUnknownTable:
+			continue
+		case err != nil:
+			return nil, err
+		case time.Duration(age)*time.Second >= migrationLockStale:
+			log.Printf("breaking migration lock without a heartbeat for %ds", age)
+			if err := r.conn.Exec(ctx, "DROP TABLE IF EXISTS "+table); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("wait for migration lock: %w", ctx.Err())
+		case <-time.After(migrationLockPoll):
+		}
+	}
+}
+
+// holdMigrationLock renews the heartbeat of a lock just taken until the
+// returned function releases it.
+func (r *Repository) holdMigrationLock(ctx context.Context, table string) (func(), error) {
+	host, _ := os.Hostname()
+	owner := fmt.Sprintf("%s/%d", host, os.Getpid())
+	beat := func(ctx context.Context) error {
+		return r.conn.Exec(ctx, "INSERT INTO "+table+" (owner, heartbeat) VALUES (?, now())", owner)
+	}
+	release := func() {
+		if err := r.conn.Exec(context.Background(), "DROP TABLE IF EXISTS "+table); err != nil {
+			log.Printf("release migration lock: %v", err)
+		}
+	}
+	if err := beat(ctx); err != nil {
+		release()
+		return nil, err
+	}
+
+	stop, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(migrationLockHeartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			if err := beat(context.Background()); err != nil {
+				log.Printf("renew migration lock: %v", err)
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-done
+		release()
+	}, nil
+}
+
+// UnlockMigrations removes the migration lock whoever holds it, for an
+// operator who knows the holder is gone.
+func (r *Repository) UnlockMigrations(ctx context.Context) error {
+	return r.conn.Exec(ctx, "DROP TABLE IF EXISTS "+r.dbName+"."+migrationLockTable)
+}
+
+func (r *Repository) ensureMigrationsTable(ctx context.Context) error {
+	if err := r.conn.Exec(ctx, "CREATE DATABASE IF NOT EXISTS "+r.dbName); err != nil {
+		return err
+	}
+	createTable := "CREATE TABLE IF NOT EXISTS " + r.dbName + `.schema_migrations (
+    version UInt32,
+    name String,
+    applied UInt8,
+    changed_at DateTime64(3),
+    cutoff DateTime DEFAULT toDateTime(0)
+) ENGINE = ReplacingMergeTree(changed_at) ORDER BY version
+`
+	if err := r.conn.Exec(ctx, createTable); err != nil {
+		return err
+	}
+	// Tables created before cutoffs were stored.
+	return r.conn.Exec(ctx, "ALTER TABLE "+r.dbName+".schema_migrations ADD COLUMN IF NOT EXISTS cutoff DateTime DEFAULT toDateTime(0)")
+}
+
+func (r *Repository) migrationsTableExists(ctx context.Context) (bool, error) {
+	var exists uint8
+	err := r.conn.QueryRow(ctx, "EXISTS TABLE "+r.dbName+".schema_migrations").Scan(&exists)
+	return exists == 1, err
+}
+
+func (r *Repository) appliedVersions(ctx context.Context) ([]int, error) {
+	query := "SELECT version FROM " + r.dbName + ".schema_migrations GROUP BY version HAVING argMax(applied, changed_at) = 1 ORDER BY version"
+	rows, err := r.conn.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []int
+	for rows.Next() {
+		var v uint32
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		versions = append(versions, int(v))
+	}
+	return versions, rows.Err()
+}
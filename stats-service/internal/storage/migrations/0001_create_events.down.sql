@@ -0,0 +1,2 @@
+-- destructive: drops the raw events
+DROP TABLE IF EXISTS ${db}.events;
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS ${db}.events (
+    event_type String,
+    post_id String,
+    ts DateTime
+) ENGINE = MergeTree() ORDER BY (event_type, post_id, ts);
@@ -0,0 +1,3 @@
+-- destructive: drops hourly counters older than the raw events
+DROP VIEW IF EXISTS ${db}.post_stats_hourly_mv;
+DROP TABLE IF EXISTS ${db}.post_stats_hourly;
@@ -0,0 +1,3 @@
+-- destructive: drops the all-time daily counters
+DROP VIEW IF EXISTS ${db}.post_stats_daily_mv;
+DROP TABLE IF EXISTS ${db}.post_stats_daily;
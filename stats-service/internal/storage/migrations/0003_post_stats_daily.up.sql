@@ -0,0 +1,30 @@
+-- Daily counters are kept indefinitely so totals survive raw event expiry.
+CREATE TABLE IF NOT EXISTS ${db}.post_stats_daily (
+    event_type String,
+    post_id String,
+    day Date,
+    cnt UInt64
+) ENGINE = SummingMergeTree(cnt) ORDER BY (event_type, post_id, day);
+
+-- Split at ${cutoff} like the hourly rollup in 0002.
+CREATE MATERIALIZED VIEW IF NOT EXISTS ${db}.post_stats_daily_mv TO ${db}.post_stats_daily AS
+SELECT event_type, post_id, toDate(ts) AS day, count() AS cnt
+FROM ${db}.events
+WHERE ts >= ${cutoff}
+GROUP BY event_type, post_id, day;
+
+-- Whole hours come from the hourly rollup, which outlives raw events; the
+-- part of the current hour before the cutoff comes from the events table.
+INSERT INTO ${db}.post_stats_daily (event_type, post_id, day, cnt)
+SELECT event_type, post_id, day, sum(cnt)
+FROM (
+    SELECT event_type, post_id, toDate(hour) AS day, cnt
+    FROM ${db}.post_stats_hourly
+    WHERE hour < toStartOfHour(${cutoff})
+    UNION ALL
+    SELECT event_type, post_id, toDate(ts) AS day, toUInt64(1) AS cnt
+    FROM ${db}.events
+    WHERE ts >= toStartOfHour(${cutoff}) AND ts < ${cutoff}
+)
+WHERE (SELECT count() FROM ${db}.post_stats_daily WHERE day < toDate(${cutoff})) = 0
+GROUP BY event_type, post_id, day;
@@ -0,0 +1,2 @@
+-- destructive: drops the viewer of every event
+ALTER TABLE ${db}.events DROP COLUMN IF EXISTS user_id;
@@ -0,0 +1,2 @@
+-- Identity of the viewer or liker; empty for anonymous events.
+ALTER TABLE ${db}.events ADD COLUMN IF NOT EXISTS user_id String DEFAULT '';
@@ -0,0 +1,9 @@
+-- Top-N co-liked neighbours per post. Every recommendation run writes a new
+-- computed_at; readers only look at the latest run of a post.
+CREATE TABLE IF NOT EXISTS ${db}.related_posts (
+    post_id String,
+    related_post_id String,
+    score Float64,
+    computed_at DateTime
+) ENGINE = ReplacingMergeTree(computed_at) ORDER BY (post_id, related_post_id)
+TTL computed_at + INTERVAL 7 DAY;
@@ -0,0 +1,2 @@
+-- destructive: drops events held back for review
+DROP TABLE IF EXISTS ${db}.flagged_events;
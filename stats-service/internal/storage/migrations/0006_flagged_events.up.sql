@@ -0,0 +1,14 @@
+-- Events held back by the fraud detector. Reviews insert a newer version of
+-- the row; readers use FINAL to see the latest status.
+CREATE TABLE IF NOT EXISTS ${db}.flagged_events (
+    id String,
+    event_type String,
+    post_id String,
+    user_id String,
+    ip String,
+    ts DateTime,
+    reason LowCardinality(String),
+    status LowCardinality(String),
+    reviewed_by String,
+    updated_at DateTime64(3)
+) ENGINE = ReplacingMergeTree(updated_at) ORDER BY id;
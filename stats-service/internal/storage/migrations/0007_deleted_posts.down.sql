@@ -0,0 +1,2 @@
+-- destructive: forgets which posts were deleted
+DROP TABLE IF EXISTS ${db}.deleted_posts;
@@ -0,0 +1,9 @@
+-- Posts deleted in posts-service. Rankings skip them right away; purged_at
+-- is set once their rows were removed from the stats tables. Rows are kept
+-- so that late events of a deleted post stay excluded.
+CREATE TABLE IF NOT EXISTS ${db}.deleted_posts (
+    post_id String,
+    deleted_at DateTime,
+    purged_at Nullable(DateTime),
+    updated_at DateTime64(3)
+) ENGINE = ReplacingMergeTree(updated_at) ORDER BY post_id;
@@ -0,0 +1,12 @@
+-- One row per attempt to review a flagged event. The earliest claim wins,
+-- which turns concurrent reviews of the same event into a check-and-set:
+-- only the winner inserts the approved event. The winner then adds a row
+-- with its decision before storing it, so a review that fails halfway can
+-- be finished by the next one.
+CREATE TABLE IF NOT EXISTS ${db}.flag_reviews (
+    id String,
+    claim String,
+    claimed_at DateTime64(9) DEFAULT now64(9),
+    decision String DEFAULT '',
+    reviewed_by String DEFAULT ''
+) ENGINE = MergeTree ORDER BY (id, claimed_at);
@@ -0,0 +1,2 @@
+-- destructive: forgets which users were deleted
+DROP TABLE IF EXISTS ${db}.deleted_users;
@@ -0,0 +1,6 @@
+-- Users who deleted their account. Their rows are removed right away; the
+-- ids are kept so that a replay from Kafka does not bring the events back.
+CREATE TABLE IF NOT EXISTS ${db}.deleted_users (
+    user_id String,
+    deleted_at DateTime
+) ENGINE = ReplacingMergeTree ORDER BY user_id;
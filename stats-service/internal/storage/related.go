@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ComputeRelatedPosts stores, for every post liked in the last window, its
+// topN most similar posts by cosine similarity of the sets of users who
+// liked them.
+func (r *Repository) ComputeRelatedPosts(ctx context.Context, window time.Duration, topN int) error {
+	if topN <= 0 {
+		topN = 20
+	}
+	likes := fmt.Sprintf("SELECT DISTINCT post_id, user_id FROM %s.events WHERE event_type = 'like' AND user_id != '' AND ts >= now() - toIntervalSecond(%d) AND %s",
+		r.dbName, int64(window.Seconds()), r.notDeleted("post_id"))
+	totals := "SELECT post_id, count() AS likes FROM (" + likes + ") GROUP BY post_id"
+
+	query := "INSERT INTO " + r.dbName + ".related_posts (post_id, related_post_id, score, computed_at) " +
+		"SELECT co.post_id, co.related_post_id, co.together / sqrt(ta.likes * tb.likes) AS score, ? FROM (" +
+		"SELECT a.post_id AS post_id, b.post_id AS related_post_id, count() AS together " +
+		"FROM (" + likes + ") AS a INNER JOIN (" + likes + ") AS b ON a.user_id = b.user_id " +
+		"WHERE a.post_id != b.post_id GROUP BY post_id, related_post_id) AS co " +
+		"INNER JOIN (" + totals + ") AS ta ON ta.post_id = co.post_id " +
+		"INNER JOIN (" + totals + ") AS tb ON tb.post_id = co.related_post_id " +
+		"ORDER BY co.post_id, score DESC LIMIT ? BY co.post_id"
+	return r.conn.Exec(ctx, query, time.Now().UTC().Truncate(time.Second), uint64(topN))
+}
+
+// RelatedPosts returns the neighbours of postID from the latest
+// recommendation run, best first.
+func (r *Repository) RelatedPosts(ctx context.Context, postID string, limit int) ([]PostScore, error) {
+	if limit <= 0 {
+		limit = 5
+	}
+	query := "SELECT related_post_id, score FROM " + r.dbName + ".related_posts " +
+		"WHERE post_id = ? AND computed_at = (SELECT max(computed_at) FROM " + r.dbName + ".related_posts WHERE post_id = ?) " +
+		"AND " + r.notDeleted("related_post_id") + " " +
+		"ORDER BY score DESC LIMIT ?"
+	rows, err := r.conn.Query(ctx, query, postID, postID, uint64(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []PostScore
+	for rows.Next() {
+		var p PostScore
+		if err := rows.Scan(&p.PostID, &p.Score); err != nil {
+			return nil, err
+		}
+		result = append(result, p)
+	}
+
+	return result, rows.Err()
+}
@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// RebuildSuffix names the shadow tables a replay writes into. After the swap
+// they hold the previous data until the next replay drops them.
+const RebuildSuffix = "_rebuild"
+
+var rebuildTables = []string{"events", "post_stats_hourly", "post_stats_daily"}
+
+// PrepareRebuild (re)creates shadow copies of the events table and its
+// rollups, wired with the same materialized views as the live tables. When
+// keepBefore is set, the live rows older than it are copied over, so a replay
+// starting there does not lose the history before it.
+func (r *Repository) PrepareRebuild(ctx context.Context, keepBefore time.Time) error {
+	if err := r.dropRebuildViews(ctx); err != nil {
+		return err
+	}
+	for _, table := range rebuildTables {
+		shadow := r.dbName + "." + table + RebuildSuffix
+		if err := r.conn.Exec(ctx, "DROP TABLE IF EXISTS "+shadow); err != nil {
+			return err
+		}
+		if err := r.conn.Exec(ctx, "CREATE TABLE "+shadow+" AS "+r.dbName+"."+table); err != nil {
+			return err
+		}
+	}
+	if !keepBefore.IsZero() {
+		if err := r.copyHistory(ctx, keepBefore); err != nil {
+			return fmt.Errorf("copy history: %w", err)
+		}
+	}
+	return r.createRollupViews(ctx, RebuildSuffix)
+}
+
+// copyHistory runs before the shadow views exist, so the copied events are
+// not rolled up a second time. Rollups outlive raw events and are copied
+// for whole hours and days; the partial hour and day before the cutoff are
+// rolled up from the raw events.
+func (r *Repository) copyHistory(ctx context.Context, before time.Time) error {
+	db := r.dbName
+	stmts := []string{
+		"INSERT INTO " + db + ".events" + RebuildSuffix + " SELECT * FROM " + db + ".events WHERE ts < ?",
+		"INSERT INTO " + db + ".post_stats_hourly" + RebuildSuffix + " (event_type, post_id, hour, cnt) " +
+			"SELECT event_type, post_id, hour, cnt FROM " + db + ".post_stats_hourly WHERE hour < toStartOfHour(?)",
+		"INSERT INTO " + db + ".post_stats_hourly" + RebuildSuffix + " (event_type, post_id, hour, cnt) " +
+			"SELECT event_type, post_id, toStartOfHour(ts) AS hour, count() FROM " + db + ".events " +
+			"WHERE ts >= toStartOfHour(?) AND ts < ? GROUP BY event_type, post_id, hour",
+		"INSERT INTO " + db + ".post_stats_daily" + RebuildSuffix + " (event_type, post_id, day, cnt) " +
+			"SELECT event_type, post_id, day, cnt FROM " + db + ".post_stats_daily WHERE day < toDate(?)",
+		"INSERT INTO " + db + ".post_stats_daily" + RebuildSuffix + " (event_type, post_id, day, cnt) " +
+			"SELECT event_type, post_id, toDate(ts) AS day, count() FROM " + db + ".events " +
+			"WHERE ts >= toStartOfDay(?) AND ts < ? GROUP BY event_type, post_id, day",
+	}
+	before = before.UTC()
+	for _, stmt := range stmts {
+		args := make([]any, strings.Count(stmt, "?"))
+		for i := range args {
+			args[i] = before
+		}
+		if err := r.conn.Exec(ctx, stmt, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveEventsTo bulk-inserts events into the given table of the database,
+// typically "events" + RebuildSuffix.
+func (r *Repository) SaveEventsTo(ctx context.Context, table string, events []Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	batch, err := r.conn.PrepareBatch(ctx, "INSERT INTO "+r.dbName+"."+table+" (event_type, post_id, user_id, ts)")
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		if err := batch.Append(e.EventType, e.PostID, e.UserID, e.Timestamp); err != nil {
+			batch.Abort()
+			return err
+		}
+	}
+	return batch.Send()
+}
+
+// SwapRebuild exchanges all shadow tables with the live ones in a single
+// EXCHANGE statement, so readers never see new events next to old rollups.
+// The live rollup views are recreated around it so they keep pointing at the
+// live tables; nothing may write to the events table meanwhile, which the
+// caller ensures by holding the consumer group.
+func (r *Repository) SwapRebuild(ctx context.Context) error {
+	if err := r.dropRebuildViews(ctx); err != nil {
+		return err
+	}
+	if err := r.dropRollupViews(ctx, ""); err != nil {
+		return err
+	}
+	pairs := make([]string, len(rebuildTables))
+	for i, table := range rebuildTables {
+		live := r.dbName + "." + table
+		pairs[i] = live + RebuildSuffix + " AND " + live
+	}
+	if err := r.conn.Exec(ctx, "EXCHANGE TABLES "+strings.Join(pairs, ", ")); err != nil {
+		if restoreErr := r.createRollupViews(ctx, ""); restoreErr != nil {
+			log.Printf("restore rollup views: %v", restoreErr)
+		}
+		return fmt.Errorf("exchange tables: %w", err)
+	}
+	return r.createRollupViews(ctx, "")
+}
+
+func (r *Repository) dropRebuildViews(ctx context.Context) error {
+	return r.dropRollupViews(ctx, RebuildSuffix)
+}
+
+func (r *Repository) dropRollupViews(ctx context.Context, suffix string) error {
+	for _, view := range []string{"post_stats_hourly_mv", "post_stats_daily_mv"} {
+		if err := r.conn.Exec(ctx, "DROP VIEW IF EXISTS "+r.dbName+"."+view+suffix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createRollupViews mirrors the views of migrations 0002 and 0003 for the
+// table set identified by suffix.
+func (r *Repository) createRollupViews(ctx context.Context, suffix string) error {
+	db := r.dbName
+	hourly := "CREATE MATERIALIZED VIEW IF NOT EXISTS " + db + ".post_stats_hourly_mv" + suffix + " TO " + db + ".post_stats_hourly" + suffix + " AS " +
+		"SELECT event_type, post_id, toStartOfHour(ts) AS hour, count() AS cnt FROM " + db + ".events" + suffix + " GROUP BY event_type, post_id, hour"
+	if err := r.conn.Exec(ctx, hourly); err != nil {
+		return err
+	}
+	daily := "CREATE MATERIALIZED VIEW IF NOT EXISTS " + db + ".post_stats_daily_mv" + suffix + " TO " + db + ".post_stats_daily" + suffix + " AS " +
+		"SELECT event_type, post_id, toDate(ts) AS day, count() AS cnt FROM " + db + ".events" + suffix + " GROUP BY event_type, post_id, day"
+	return r.conn.Exec(ctx, daily)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	ch "github.com/ClickHouse/clickhouse-go/v2"
@@ -15,11 +16,20 @@ type Config struct {
 	DB       string
 	User     string
 	Password string
+	// SkipMigrations leaves the schema untouched on connect; used by the
+	// migrate subcommand, which drives migrations itself.
+	SkipMigrations bool
+	// RawEventsTTLDays and HourlyStatsTTLDays expire raw events and hourly
+	// rollups after the given number of days; zero keeps them forever.
+	// Daily rollups are never expired.
+	RawEventsTTLDays   int
+	HourlyStatsTTLDays int
 }
 
 type Event struct {
 	PostID    string
 	EventType string
+	UserID    string
 	Timestamp time.Time
 }
 
@@ -28,6 +38,11 @@ type PostCount struct {
 	Value  int64
 }
 
+type PostScore struct {
+	PostID string
+	Score  float64
+}
+
 type Repository struct {
 	conn   driver.Conn
 	dbName string
@@ -87,9 +102,20 @@ func New(ctx context.Context, cfg Config) (*Repository, error) {
 		conn:   conn,
 		dbName: cfg.DB,
 	}
-	if err := repo.ensureSchema(ctx); err != nil {
+	if cfg.SkipMigrations {
+		return repo, nil
+	}
+	if _, err := repo.MigrateUp(ctx, 0, false); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("migrate schema failed: %w", err)
+	}
+	if err := repo.applyRetention(ctx, "events", "ts", cfg.RawEventsTTLDays); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("apply events retention failed: %w", err)
+	}
+	if err := repo.applyRetention(ctx, "post_stats_hourly", "hour", cfg.HourlyStatsTTLDays); err != nil {
 		conn.Close()
-		return nil, fmt.Errorf("ensure schema failed: %w", err)
+		return nil, fmt.Errorf("apply hourly rollup retention failed: %w", err)
 	}
 	return repo, nil
 }
@@ -101,26 +127,85 @@ func (r *Repository) Close() error {
 	return r.conn.Close()
 }
 
-func (r *Repository) ensureSchema(ctx context.Context) error {
-	if err := r.conn.Exec(ctx, "CREATE DATABASE IF NOT EXISTS "+r.dbName); err != nil {
+// applyRetention sets or removes the table TTL, skipping the ALTER when the
+// table already has the requested TTL.
+func (r *Repository) applyRetention(ctx context.Context, table, column string, days int) error {
+	var engineFull string
+	query := "SELECT engine_full FROM system.tables WHERE database = ? AND name = ?"
+	if err := r.conn.QueryRow(ctx, query, r.dbName, table).Scan(&engineFull); err != nil {
 		return err
 	}
-	createTable := "CREATE TABLE IF NOT EXISTS " + r.dbName + `.events (
-    event_type String,
-    post_id String,
-    ts DateTime
-) ENGINE = MergeTree() ORDER BY (event_type, post_id, ts)
-`
-	return r.conn.Exec(ctx, createTable)
+
+	current := TableTTL(engineFull)
+	if days <= 0 {
+		if current == "" {
+			return nil
+		}
+		return r.conn.Exec(ctx, "ALTER TABLE "+r.dbName+"."+table+" REMOVE TTL")
+	}
+
+	ttl := fmt.Sprintf("%s + toIntervalDay(%d)", column, days)
+	if current == ttl {
+		return nil
+	}
+	return r.conn.Exec(ctx, "ALTER TABLE "+r.dbName+"."+table+" MODIFY TTL "+ttl)
+}
+
+// TableTTL returns the table TTL expression of an engine_full value from
+// system.tables, or "" when there is none. Only the top-level TTL clause
+// counts; quoted names and parenthesized expressions are skipped.
+func TableTTL(engineFull string) string {
+	keywordAt := func(i int, kw string) bool {
+		end := i + len(kw)
+		return strings.HasPrefix(engineFull[i:], kw) && (end == len(engineFull) || engineFull[end] == ' ')
+	}
+	start := -1
+	depth := 0
+	var quote byte
+	for i := 0; i < len(engineFull); i++ {
+		c := engineFull[i]
+		if quote != 0 {
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '\'', '"', '`':
+			quote = c
+			continue
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+		if depth != 0 || (i > 0 && engineFull[i-1] != ' ') {
+			continue
+		}
+		switch {
+		case start < 0 && keywordAt(i, "TTL"):
+			start = i + len("TTL")
+		case start >= 0 && keywordAt(i, "SETTINGS"):
+			return strings.TrimSpace(engineFull[start:i])
+		}
+	}
+	if start < 0 {
+		return ""
+	}
+	return strings.TrimSpace(engineFull[start:])
 }
 
 func (r *Repository) SaveEvent(ctx context.Context, e Event) error {
-	query := "INSERT INTO " + r.dbName + ".events (event_type, post_id, ts) VALUES (?, ?, ?)"
-	return r.conn.Exec(ctx, query, e.EventType, e.PostID, e.Timestamp)
+	query := "INSERT INTO " + r.dbName + ".events (event_type, post_id, user_id, ts) VALUES (?, ?, ?, ?)"
+	return r.conn.Exec(ctx, query, e.EventType, e.PostID, e.UserID, e.Timestamp)
 }
 
+// PostStats returns all-time totals from the daily rollup, which the
+// materialized view keeps complete even after raw events expire.
 func (r *Repository) PostStats(ctx context.Context, postID string) (views, likes int64, err error) {
-	query := "SELECT sum(event_type = 'view') AS views, sum(event_type = 'like') AS likes FROM " + r.dbName + ".events WHERE post_id = ?"
+	query := "SELECT sumIf(cnt, event_type = 'view') AS views, sumIf(cnt, event_type = 'like') AS likes FROM " + r.dbName + ".post_stats_daily WHERE post_id = ?"
 	rows, err := r.conn.Query(ctx, query, postID)
 	if err != nil {
 		return 0, 0, err
@@ -142,7 +227,7 @@ func (r *Repository) TopPosts(ctx context.Context, eventType string, limit int)
 	if limit <= 0 {
 		limit = 5
 	}
-	query := "SELECT post_id, count() AS cnt FROM " + r.dbName + ".events WHERE event_type = ? GROUP BY post_id ORDER BY cnt DESC LIMIT ?"
+	query := "SELECT post_id, sum(cnt) AS total FROM " + r.dbName + ".post_stats_daily WHERE event_type = ? AND " + r.notDeleted("post_id") + " GROUP BY post_id ORDER BY total DESC LIMIT ?"
 	rows, err := r.conn.Query(ctx, query, eventType, uint64(limit))
 	if err != nil {
 		return nil, err
@@ -162,8 +247,35 @@ func (r *Repository) TopPosts(ctx context.Context, eventType string, limit int)
 	return result, rows.Err()
 }
 
+// TrendingPosts ranks posts by views and likes from the last window, each
+// hourly bucket weighted by exp2(-age/halfLife) and likes counted likeWeight
+// times.
+func (r *Repository) TrendingPosts(ctx context.Context, window, halfLife time.Duration, likeWeight float64, limit int) ([]PostScore, error) {
+	if limit <= 0 {
+		limit = 5
+	}
+	query := "SELECT post_id, sum(cnt * if(event_type = 'like', ?, 1) * exp2(-dateDiff('second', hour, now()) / ?)) AS score FROM " + r.dbName +
+		".post_stats_hourly WHERE hour >= now() - toIntervalSecond(?) AND " + r.notDeleted("post_id") + " GROUP BY post_id ORDER BY score DESC LIMIT ?"
+	rows, err := r.conn.Query(ctx, query, likeWeight, halfLife.Seconds(), uint64(window.Seconds()), uint64(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []PostScore
+	for rows.Next() {
+		var p PostScore
+		if err := rows.Scan(&p.PostID, &p.Score); err != nil {
+			return nil, err
+		}
+		result = append(result, p)
+	}
+
+	return result, rows.Err()
+}
+
 func (r *Repository) LikesPerPost(ctx context.Context) ([]PostCount, error) {
-	query := "SELECT post_id, count() AS cnt FROM " + r.dbName + ".events WHERE event_type = 'like' GROUP BY post_id"
+	query := "SELECT post_id, sum(cnt) AS total FROM " + r.dbName + ".post_stats_daily WHERE event_type = 'like' AND " + r.notDeleted("post_id") + " GROUP BY post_id"
 	rows, err := r.conn.Query(ctx, query)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// userTables hold rows with a user_id. Rollups count events without
+// recording who made them and are left alone.
+var userTables = []string{"events", "flagged_events"}
+
+// DeleteUserData removes the events made by userID with lightweight DELETEs.
+// The user is recorded as deleted first, so a replay skips their events even
+// when the deletion is retried.
+func (r *Repository) DeleteUserData(ctx context.Context, userID string) error {
+	if err := r.conn.Exec(ctx, "INSERT INTO "+r.dbName+".deleted_users (user_id, deleted_at) VALUES (?, ?)",
+		userID, time.Now().UTC()); err != nil {
+		return fmt.Errorf("record deleted user: %w", err)
+	}
+	for _, table := range userTables {
+		if err := r.conn.Exec(ctx, "DELETE FROM "+r.dbName+"."+table+" WHERE user_id = ?", userID); err != nil {
+			return fmt.Errorf("delete user data from %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// DeletedUserIDs returns every user whose data was deleted.
+func (r *Repository) DeletedUserIDs(ctx context.Context) (map[string]struct{}, error) {
+	rows, err := r.conn.Query(ctx, "SELECT DISTINCT user_id FROM "+r.dbName+".deleted_users")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[string]struct{})
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = struct{}{}
+	}
+	return ids, rows.Err()
+}
+
+// UserEvents calls fn for every raw event made by userID, ordered by time.
+// Events past the raw retention only live on in the rollups and are gone.
+func (r *Repository) UserEvents(ctx context.Context, userID string, fn func(Event) error) error {
+	rows, err := r.conn.Query(ctx,
+		"SELECT post_id, event_type, user_id, ts FROM "+r.dbName+".events WHERE user_id = ? ORDER BY ts", userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e Event
+		if err := rows.Scan(&e.PostID, &e.EventType, &e.UserID, &e.Timestamp); err != nil {
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// UserFlaggedEvents returns the flagged events of userID in any status,
+// oldest first.
+func (r *Repository) UserFlaggedEvents(ctx context.Context, userID string) ([]FlaggedEvent, error) {
+	query := "SELECT id, event_type, post_id, user_id, ip, ts, reason, status, reviewed_by FROM " + r.dbName +
+		".flagged_events FINAL WHERE user_id = ? ORDER BY ts"
+	rows, err := r.conn.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []FlaggedEvent
+	for rows.Next() {
+		var f FlaggedEvent
+		if err := rows.Scan(&f.ID, &f.EventType, &f.PostID, &f.UserID, &f.IP, &f.Timestamp, &f.Reason, &f.Status, &f.ReviewedBy); err != nil {
+			return nil, err
+		}
+		result = append(result, f)
+	}
+	return result, rows.Err()
+}
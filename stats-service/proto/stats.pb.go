@@ -9,6 +9,7 @@ package proto
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -126,9 +127,12 @@ func (x *PostStatsResponse) GetLikes() int64 {
 }
 
 type TopPostsRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Metric        string                 `protobuf:"bytes,1,opt,name=metric,proto3" json:"metric,omitempty"` // "views" or "likes"
-	Limit         int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Metric string                 `protobuf:"bytes,1,opt,name=metric,proto3" json:"metric,omitempty"` // "views", "likes" or "trending"
+	Limit  int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	// Trending only: overrides the server's half-life and look-back window.
+	HalfLifeHours float64 `protobuf:"fixed64,3,opt,name=half_life_hours,json=halfLifeHours,proto3" json:"half_life_hours,omitempty"`
+	WindowHours   int32   `protobuf:"varint,4,opt,name=window_hours,json=windowHours,proto3" json:"window_hours,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -177,10 +181,25 @@ func (x *TopPostsRequest) GetLimit() int32 {
 	return 0
 }
 
+func (x *TopPostsRequest) GetHalfLifeHours() float64 {
+	if x != nil {
+		return x.HalfLifeHours
+	}
+	return 0
+}
+
+func (x *TopPostsRequest) GetWindowHours() int32 {
+	if x != nil {
+		return x.WindowHours
+	}
+	return 0
+}
+
 type PostItem struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	PostId        string                 `protobuf:"bytes,1,opt,name=post_id,json=postId,proto3" json:"post_id,omitempty"`
 	Value         int64                  `protobuf:"varint,2,opt,name=value,proto3" json:"value,omitempty"`
+	Score         float64                `protobuf:"fixed64,3,opt,name=score,proto3" json:"score,omitempty"` // time-decayed score for the trending metric
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -229,6 +248,13 @@ func (x *PostItem) GetValue() int64 {
 	return 0
 }
 
+func (x *PostItem) GetScore() float64 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
 type TopPostsResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Items         []*PostItem            `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
@@ -413,36 +439,1156 @@ func (x *TopUsersResponse) GetUsers() []*UserItem {
 	return nil
 }
 
-var File_proto_stats_proto protoreflect.FileDescriptor
+type RelatedPostsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PostId        string                 `protobuf:"bytes,1,opt,name=post_id,json=postId,proto3" json:"post_id,omitempty"`
+	Limit         int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-const file_proto_stats_proto_rawDesc = "" +
-	"\n" +
-	"\x11proto/stats.proto\x12\bstats.v1\"+\n" +
-	"\x10PostStatsRequest\x12\x17\n" +
-	"\apost_id\x18\x01 \x01(\tR\x06postId\"X\n" +
-	"\x11PostStatsResponse\x12\x17\n" +
-	"\apost_id\x18\x01 \x01(\tR\x06postId\x12\x14\n" +
-	"\x05views\x18\x02 \x01(\x03R\x05views\x12\x14\n" +
-	"\x05likes\x18\x03 \x01(\x03R\x05likes\"?\n" +
-	"\x0fTopPostsRequest\x12\x16\n" +
-	"\x06metric\x18\x01 \x01(\tR\x06metric\x12\x14\n" +
-	"\x05limit\x18\x02 \x01(\x05R\x05limit\"9\n" +
-	"\bPostItem\x12\x17\n" +
-	"\apost_id\x18\x01 \x01(\tR\x06postId\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\x03R\x05value\"<\n" +
-	"\x10TopPostsResponse\x12(\n" +
-	"\x05items\x18\x01 \x03(\v2\x12.stats.v1.PostItemR\x05items\"'\n" +
-	"\x0fTopUsersRequest\x12\x14\n" +
-	"\x05limit\x18\x01 \x01(\x05R\x05limit\"9\n" +
-	"\bUserItem\x12\x17\n" +
-	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x14\n" +
-	"\x05likes\x18\x02 \x01(\x03R\x05likes\"<\n" +
-	"\x10TopUsersResponse\x12(\n" +
-	"\x05users\x18\x01 \x03(\v2\x12.stats.v1.UserItemR\x05users2\xea\x01\n" +
+func (x *RelatedPostsRequest) Reset() {
+	*x = RelatedPostsRequest{}
+	mi := &file_proto_stats_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RelatedPostsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RelatedPostsRequest) ProtoMessage() {}
+
+func (x *RelatedPostsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_stats_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RelatedPostsRequest.ProtoReflect.Descriptor instead.
+func (*RelatedPostsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_stats_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *RelatedPostsRequest) GetPostId() string {
+	if x != nil {
+		return x.PostId
+	}
+	return ""
+}
+
+func (x *RelatedPostsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type RelatedPostsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*PostItem            `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"` // score is the co-like cosine similarity
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RelatedPostsResponse) Reset() {
+	*x = RelatedPostsResponse{}
+	mi := &file_proto_stats_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RelatedPostsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RelatedPostsResponse) ProtoMessage() {}
+
+func (x *RelatedPostsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_stats_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RelatedPostsResponse.ProtoReflect.Descriptor instead.
+func (*RelatedPostsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_stats_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *RelatedPostsResponse) GetItems() []*PostItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type AuthorStatsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Days          int32                  `protobuf:"varint,2,opt,name=days,proto3" json:"days,omitempty"` // length of the daily series, 30 by default
+	Top           int32                  `protobuf:"varint,3,opt,name=top,proto3" json:"top,omitempty"`   // number of best posts, 3 by default
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuthorStatsRequest) Reset() {
+	*x = AuthorStatsRequest{}
+	mi := &file_proto_stats_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuthorStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuthorStatsRequest) ProtoMessage() {}
+
+func (x *AuthorStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_stats_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuthorStatsRequest.ProtoReflect.Descriptor instead.
+func (*AuthorStatsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_stats_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *AuthorStatsRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *AuthorStatsRequest) GetDays() int32 {
+	if x != nil {
+		return x.Days
+	}
+	return 0
+}
+
+func (x *AuthorStatsRequest) GetTop() int32 {
+	if x != nil {
+		return x.Top
+	}
+	return 0
+}
+
+type AuthorPostStats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PostId        string                 `protobuf:"bytes,1,opt,name=post_id,json=postId,proto3" json:"post_id,omitempty"`
+	Views         int64                  `protobuf:"varint,2,opt,name=views,proto3" json:"views,omitempty"`
+	Likes         int64                  `protobuf:"varint,3,opt,name=likes,proto3" json:"likes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuthorPostStats) Reset() {
+	*x = AuthorPostStats{}
+	mi := &file_proto_stats_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuthorPostStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuthorPostStats) ProtoMessage() {}
+
+func (x *AuthorPostStats) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_stats_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuthorPostStats.ProtoReflect.Descriptor instead.
+func (*AuthorPostStats) Descriptor() ([]byte, []int) {
+	return file_proto_stats_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *AuthorPostStats) GetPostId() string {
+	if x != nil {
+		return x.PostId
+	}
+	return ""
+}
+
+func (x *AuthorPostStats) GetViews() int64 {
+	if x != nil {
+		return x.Views
+	}
+	return 0
+}
+
+func (x *AuthorPostStats) GetLikes() int64 {
+	if x != nil {
+		return x.Likes
+	}
+	return 0
+}
+
+type DailyStats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Date          string                 `protobuf:"bytes,1,opt,name=date,proto3" json:"date,omitempty"` // YYYY-MM-DD, UTC
+	Views         int64                  `protobuf:"varint,2,opt,name=views,proto3" json:"views,omitempty"`
+	Likes         int64                  `protobuf:"varint,3,opt,name=likes,proto3" json:"likes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DailyStats) Reset() {
+	*x = DailyStats{}
+	mi := &file_proto_stats_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DailyStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DailyStats) ProtoMessage() {}
+
+func (x *DailyStats) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_stats_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DailyStats.ProtoReflect.Descriptor instead.
+func (*DailyStats) Descriptor() ([]byte, []int) {
+	return file_proto_stats_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *DailyStats) GetDate() string {
+	if x != nil {
+		return x.Date
+	}
+	return ""
+}
+
+func (x *DailyStats) GetViews() int64 {
+	if x != nil {
+		return x.Views
+	}
+	return 0
+}
+
+func (x *DailyStats) GetLikes() int64 {
+	if x != nil {
+		return x.Likes
+	}
+	return 0
+}
+
+type AuthorStatsResponse struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	UserId     string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	PostCount  int64                  `protobuf:"varint,2,opt,name=post_count,json=postCount,proto3" json:"post_count,omitempty"`
+	TotalViews int64                  `protobuf:"varint,3,opt,name=total_views,json=totalViews,proto3" json:"total_views,omitempty"`
+	TotalLikes int64                  `protobuf:"varint,4,opt,name=total_likes,json=totalLikes,proto3" json:"total_likes,omitempty"`
+	// Distinct signed-in users who viewed or liked any of the author's posts
+	// while raw events are retained.
+	Reach         int64              `protobuf:"varint,5,opt,name=reach,proto3" json:"reach,omitempty"`
+	Posts         []*AuthorPostStats `protobuf:"bytes,6,rep,name=posts,proto3" json:"posts,omitempty"`
+	BestPosts     []*AuthorPostStats `protobuf:"bytes,7,rep,name=best_posts,json=bestPosts,proto3" json:"best_posts,omitempty"`
+	Daily         []*DailyStats      `protobuf:"bytes,8,rep,name=daily,proto3" json:"daily,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuthorStatsResponse) Reset() {
+	*x = AuthorStatsResponse{}
+	mi := &file_proto_stats_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuthorStatsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuthorStatsResponse) ProtoMessage() {}
+
+func (x *AuthorStatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_stats_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuthorStatsResponse.ProtoReflect.Descriptor instead.
+func (*AuthorStatsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_stats_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *AuthorStatsResponse) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *AuthorStatsResponse) GetPostCount() int64 {
+	if x != nil {
+		return x.PostCount
+	}
+	return 0
+}
+
+func (x *AuthorStatsResponse) GetTotalViews() int64 {
+	if x != nil {
+		return x.TotalViews
+	}
+	return 0
+}
+
+func (x *AuthorStatsResponse) GetTotalLikes() int64 {
+	if x != nil {
+		return x.TotalLikes
+	}
+	return 0
+}
+
+func (x *AuthorStatsResponse) GetReach() int64 {
+	if x != nil {
+		return x.Reach
+	}
+	return 0
+}
+
+func (x *AuthorStatsResponse) GetPosts() []*AuthorPostStats {
+	if x != nil {
+		return x.Posts
+	}
+	return nil
+}
+
+func (x *AuthorStatsResponse) GetBestPosts() []*AuthorPostStats {
+	if x != nil {
+		return x.BestPosts
+	}
+	return nil
+}
+
+func (x *AuthorStatsResponse) GetDaily() []*DailyStats {
+	if x != nil {
+		return x.Daily
+	}
+	return nil
+}
+
+type ExportEventsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	From          *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	To            *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
+	PostId        string                 `protobuf:"bytes,3,opt,name=post_id,json=postId,proto3" json:"post_id,omitempty"`    // optional filter
+	OwnerId       string                 `protobuf:"bytes,4,opt,name=owner_id,json=ownerId,proto3" json:"owner_id,omitempty"` // optional filter, resolved through posts-service
+	Granularity   string                 `protobuf:"bytes,5,opt,name=granularity,proto3" json:"granularity,omitempty"`        // "raw" (default), "hour" or "day"
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExportEventsRequest) Reset() {
+	*x = ExportEventsRequest{}
+	mi := &file_proto_stats_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportEventsRequest) ProtoMessage() {}
+
+func (x *ExportEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_stats_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportEventsRequest.ProtoReflect.Descriptor instead.
+func (*ExportEventsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_stats_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ExportEventsRequest) GetFrom() *timestamppb.Timestamp {
+	if x != nil {
+		return x.From
+	}
+	return nil
+}
+
+func (x *ExportEventsRequest) GetTo() *timestamppb.Timestamp {
+	if x != nil {
+		return x.To
+	}
+	return nil
+}
+
+func (x *ExportEventsRequest) GetPostId() string {
+	if x != nil {
+		return x.PostId
+	}
+	return ""
+}
+
+func (x *ExportEventsRequest) GetOwnerId() string {
+	if x != nil {
+		return x.OwnerId
+	}
+	return ""
+}
+
+func (x *ExportEventsRequest) GetGranularity() string {
+	if x != nil {
+		return x.Granularity
+	}
+	return ""
+}
+
+type ExportRow struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ts            *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=ts,proto3" json:"ts,omitempty"` // event time or bucket start
+	EventType     string                 `protobuf:"bytes,2,opt,name=event_type,json=eventType,proto3" json:"event_type,omitempty"`
+	PostId        string                 `protobuf:"bytes,3,opt,name=post_id,json=postId,proto3" json:"post_id,omitempty"`
+	UserId        string                 `protobuf:"bytes,4,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"` // raw rows only
+	Count         int64                  `protobuf:"varint,5,opt,name=count,proto3" json:"count,omitempty"`                // 1 for raw rows
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExportRow) Reset() {
+	*x = ExportRow{}
+	mi := &file_proto_stats_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportRow) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportRow) ProtoMessage() {}
+
+func (x *ExportRow) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_stats_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportRow.ProtoReflect.Descriptor instead.
+func (*ExportRow) Descriptor() ([]byte, []int) {
+	return file_proto_stats_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ExportRow) GetTs() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Ts
+	}
+	return nil
+}
+
+func (x *ExportRow) GetEventType() string {
+	if x != nil {
+		return x.EventType
+	}
+	return ""
+}
+
+func (x *ExportRow) GetPostId() string {
+	if x != nil {
+		return x.PostId
+	}
+	return ""
+}
+
+func (x *ExportRow) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *ExportRow) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+type ExportEventsChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Rows          []*ExportRow           `protobuf:"bytes,1,rep,name=rows,proto3" json:"rows,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExportEventsChunk) Reset() {
+	*x = ExportEventsChunk{}
+	mi := &file_proto_stats_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportEventsChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportEventsChunk) ProtoMessage() {}
+
+func (x *ExportEventsChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_stats_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportEventsChunk.ProtoReflect.Descriptor instead.
+func (*ExportEventsChunk) Descriptor() ([]byte, []int) {
+	return file_proto_stats_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ExportEventsChunk) GetRows() []*ExportRow {
+	if x != nil {
+		return x.Rows
+	}
+	return nil
+}
+
+// An event held back from the public counters by the fraud detector.
+type FlaggedEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Ts            *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=ts,proto3" json:"ts,omitempty"`
+	EventType     string                 `protobuf:"bytes,3,opt,name=event_type,json=eventType,proto3" json:"event_type,omitempty"`
+	PostId        string                 `protobuf:"bytes,4,opt,name=post_id,json=postId,proto3" json:"post_id,omitempty"`
+	UserId        string                 `protobuf:"bytes,5,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Ip            string                 `protobuf:"bytes,6,opt,name=ip,proto3" json:"ip,omitempty"`
+	Reason        string                 `protobuf:"bytes,7,opt,name=reason,proto3" json:"reason,omitempty"` // rule that matched, e.g. "viewer_burst"
+	Status        string                 `protobuf:"bytes,8,opt,name=status,proto3" json:"status,omitempty"` // "pending", "approved" or "rejected"
+	ReviewedBy    string                 `protobuf:"bytes,9,opt,name=reviewed_by,json=reviewedBy,proto3" json:"reviewed_by,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FlaggedEvent) Reset() {
+	*x = FlaggedEvent{}
+	mi := &file_proto_stats_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FlaggedEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FlaggedEvent) ProtoMessage() {}
+
+func (x *FlaggedEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_stats_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FlaggedEvent.ProtoReflect.Descriptor instead.
+func (*FlaggedEvent) Descriptor() ([]byte, []int) {
+	return file_proto_stats_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *FlaggedEvent) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *FlaggedEvent) GetTs() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Ts
+	}
+	return nil
+}
+
+func (x *FlaggedEvent) GetEventType() string {
+	if x != nil {
+		return x.EventType
+	}
+	return ""
+}
+
+func (x *FlaggedEvent) GetPostId() string {
+	if x != nil {
+		return x.PostId
+	}
+	return ""
+}
+
+func (x *FlaggedEvent) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *FlaggedEvent) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *FlaggedEvent) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *FlaggedEvent) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *FlaggedEvent) GetReviewedBy() string {
+	if x != nil {
+		return x.ReviewedBy
+	}
+	return ""
+}
+
+type ListFlaggedEventsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`               // default "pending"
+	PostId        string                 `protobuf:"bytes,2,opt,name=post_id,json=postId,proto3" json:"post_id,omitempty"` // optional filter
+	Limit         int32                  `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListFlaggedEventsRequest) Reset() {
+	*x = ListFlaggedEventsRequest{}
+	mi := &file_proto_stats_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListFlaggedEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFlaggedEventsRequest) ProtoMessage() {}
+
+func (x *ListFlaggedEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_stats_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFlaggedEventsRequest.ProtoReflect.Descriptor instead.
+func (*ListFlaggedEventsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_stats_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ListFlaggedEventsRequest) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *ListFlaggedEventsRequest) GetPostId() string {
+	if x != nil {
+		return x.PostId
+	}
+	return ""
+}
+
+func (x *ListFlaggedEventsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type ListFlaggedEventsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Events        []*FlaggedEvent        `protobuf:"bytes,1,rep,name=events,proto3" json:"events,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListFlaggedEventsResponse) Reset() {
+	*x = ListFlaggedEventsResponse{}
+	mi := &file_proto_stats_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListFlaggedEventsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFlaggedEventsResponse) ProtoMessage() {}
+
+func (x *ListFlaggedEventsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_stats_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFlaggedEventsResponse.ProtoReflect.Descriptor instead.
+func (*ListFlaggedEventsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_stats_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *ListFlaggedEventsResponse) GetEvents() []*FlaggedEvent {
+	if x != nil {
+		return x.Events
+	}
+	return nil
+}
+
+type ReviewFlaggedEventRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Approve       bool                   `protobuf:"varint,2,opt,name=approve,proto3" json:"approve,omitempty"` // approved events are added to the counters
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReviewFlaggedEventRequest) Reset() {
+	*x = ReviewFlaggedEventRequest{}
+	mi := &file_proto_stats_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReviewFlaggedEventRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReviewFlaggedEventRequest) ProtoMessage() {}
+
+func (x *ReviewFlaggedEventRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_stats_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReviewFlaggedEventRequest.ProtoReflect.Descriptor instead.
+func (*ReviewFlaggedEventRequest) Descriptor() ([]byte, []int) {
+	return file_proto_stats_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *ReviewFlaggedEventRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ReviewFlaggedEventRequest) GetApprove() bool {
+	if x != nil {
+		return x.Approve
+	}
+	return false
+}
+
+type ReviewFlaggedEventResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Event         *FlaggedEvent          `protobuf:"bytes,1,opt,name=event,proto3" json:"event,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReviewFlaggedEventResponse) Reset() {
+	*x = ReviewFlaggedEventResponse{}
+	mi := &file_proto_stats_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReviewFlaggedEventResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReviewFlaggedEventResponse) ProtoMessage() {}
+
+func (x *ReviewFlaggedEventResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_stats_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReviewFlaggedEventResponse.ProtoReflect.Descriptor instead.
+func (*ReviewFlaggedEventResponse) Descriptor() ([]byte, []int) {
+	return file_proto_stats_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *ReviewFlaggedEventResponse) GetEvent() *FlaggedEvent {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+// The user is the one of the internal identity token.
+type UserEventsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserEventsRequest) Reset() {
+	*x = UserEventsRequest{}
+	mi := &file_proto_stats_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserEventsRequest) ProtoMessage() {}
+
+func (x *UserEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_stats_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserEventsRequest.ProtoReflect.Descriptor instead.
+func (*UserEventsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_stats_proto_rawDescGZIP(), []int{22}
+}
+
+type UserEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ts            *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=ts,proto3" json:"ts,omitempty"`
+	EventType     string                 `protobuf:"bytes,2,opt,name=event_type,json=eventType,proto3" json:"event_type,omitempty"`
+	PostId        string                 `protobuf:"bytes,3,opt,name=post_id,json=postId,proto3" json:"post_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserEvent) Reset() {
+	*x = UserEvent{}
+	mi := &file_proto_stats_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserEvent) ProtoMessage() {}
+
+func (x *UserEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_stats_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserEvent.ProtoReflect.Descriptor instead.
+func (*UserEvent) Descriptor() ([]byte, []int) {
+	return file_proto_stats_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *UserEvent) GetTs() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Ts
+	}
+	return nil
+}
+
+func (x *UserEvent) GetEventType() string {
+	if x != nil {
+		return x.EventType
+	}
+	return ""
+}
+
+func (x *UserEvent) GetPostId() string {
+	if x != nil {
+		return x.PostId
+	}
+	return ""
+}
+
+type UserEventsChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Events        []*UserEvent           `protobuf:"bytes,1,rep,name=events,proto3" json:"events,omitempty"`
+	Flagged       []*FlaggedEvent        `protobuf:"bytes,2,rep,name=flagged,proto3" json:"flagged,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserEventsChunk) Reset() {
+	*x = UserEventsChunk{}
+	mi := &file_proto_stats_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserEventsChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserEventsChunk) ProtoMessage() {}
+
+func (x *UserEventsChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_stats_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserEventsChunk.ProtoReflect.Descriptor instead.
+func (*UserEventsChunk) Descriptor() ([]byte, []int) {
+	return file_proto_stats_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *UserEventsChunk) GetEvents() []*UserEvent {
+	if x != nil {
+		return x.Events
+	}
+	return nil
+}
+
+func (x *UserEventsChunk) GetFlagged() []*FlaggedEvent {
+	if x != nil {
+		return x.Flagged
+	}
+	return nil
+}
+
+var File_proto_stats_proto protoreflect.FileDescriptor
+
+const file_proto_stats_proto_rawDesc = "" +
+	"\n" +
+	"\x11proto/stats.proto\x12\bstats.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"+\n" +
+	"\x10PostStatsRequest\x12\x17\n" +
+	"\apost_id\x18\x01 \x01(\tR\x06postId\"X\n" +
+	"\x11PostStatsResponse\x12\x17\n" +
+	"\apost_id\x18\x01 \x01(\tR\x06postId\x12\x14\n" +
+	"\x05views\x18\x02 \x01(\x03R\x05views\x12\x14\n" +
+	"\x05likes\x18\x03 \x01(\x03R\x05likes\"\x8a\x01\n" +
+	"\x0fTopPostsRequest\x12\x16\n" +
+	"\x06metric\x18\x01 \x01(\tR\x06metric\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\x12&\n" +
+	"\x0fhalf_life_hours\x18\x03 \x01(\x01R\rhalfLifeHours\x12!\n" +
+	"\fwindow_hours\x18\x04 \x01(\x05R\vwindowHours\"O\n" +
+	"\bPostItem\x12\x17\n" +
+	"\apost_id\x18\x01 \x01(\tR\x06postId\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value\x12\x14\n" +
+	"\x05score\x18\x03 \x01(\x01R\x05score\"<\n" +
+	"\x10TopPostsResponse\x12(\n" +
+	"\x05items\x18\x01 \x03(\v2\x12.stats.v1.PostItemR\x05items\"'\n" +
+	"\x0fTopUsersRequest\x12\x14\n" +
+	"\x05limit\x18\x01 \x01(\x05R\x05limit\"9\n" +
+	"\bUserItem\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x14\n" +
+	"\x05likes\x18\x02 \x01(\x03R\x05likes\"<\n" +
+	"\x10TopUsersResponse\x12(\n" +
+	"\x05users\x18\x01 \x03(\v2\x12.stats.v1.UserItemR\x05users\"D\n" +
+	"\x13RelatedPostsRequest\x12\x17\n" +
+	"\apost_id\x18\x01 \x01(\tR\x06postId\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\"@\n" +
+	"\x14RelatedPostsResponse\x12(\n" +
+	"\x05items\x18\x01 \x03(\v2\x12.stats.v1.PostItemR\x05items\"S\n" +
+	"\x12AuthorStatsRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x12\n" +
+	"\x04days\x18\x02 \x01(\x05R\x04days\x12\x10\n" +
+	"\x03top\x18\x03 \x01(\x05R\x03top\"V\n" +
+	"\x0fAuthorPostStats\x12\x17\n" +
+	"\apost_id\x18\x01 \x01(\tR\x06postId\x12\x14\n" +
+	"\x05views\x18\x02 \x01(\x03R\x05views\x12\x14\n" +
+	"\x05likes\x18\x03 \x01(\x03R\x05likes\"L\n" +
+	"\n" +
+	"DailyStats\x12\x12\n" +
+	"\x04date\x18\x01 \x01(\tR\x04date\x12\x14\n" +
+	"\x05views\x18\x02 \x01(\x03R\x05views\x12\x14\n" +
+	"\x05likes\x18\x03 \x01(\x03R\x05likes\"\xbc\x02\n" +
+	"\x13AuthorStatsResponse\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x1d\n" +
+	"\n" +
+	"post_count\x18\x02 \x01(\x03R\tpostCount\x12\x1f\n" +
+	"\vtotal_views\x18\x03 \x01(\x03R\n" +
+	"totalViews\x12\x1f\n" +
+	"\vtotal_likes\x18\x04 \x01(\x03R\n" +
+	"totalLikes\x12\x14\n" +
+	"\x05reach\x18\x05 \x01(\x03R\x05reach\x12/\n" +
+	"\x05posts\x18\x06 \x03(\v2\x19.stats.v1.AuthorPostStatsR\x05posts\x128\n" +
+	"\n" +
+	"best_posts\x18\a \x03(\v2\x19.stats.v1.AuthorPostStatsR\tbestPosts\x12*\n" +
+	"\x05daily\x18\b \x03(\v2\x14.stats.v1.DailyStatsR\x05daily\"\xc7\x01\n" +
+	"\x13ExportEventsRequest\x12.\n" +
+	"\x04from\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x04from\x12*\n" +
+	"\x02to\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x02to\x12\x17\n" +
+	"\apost_id\x18\x03 \x01(\tR\x06postId\x12\x19\n" +
+	"\bowner_id\x18\x04 \x01(\tR\aownerId\x12 \n" +
+	"\vgranularity\x18\x05 \x01(\tR\vgranularity\"\x9e\x01\n" +
+	"\tExportRow\x12*\n" +
+	"\x02ts\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x02ts\x12\x1d\n" +
+	"\n" +
+	"event_type\x18\x02 \x01(\tR\teventType\x12\x17\n" +
+	"\apost_id\x18\x03 \x01(\tR\x06postId\x12\x17\n" +
+	"\auser_id\x18\x04 \x01(\tR\x06userId\x12\x14\n" +
+	"\x05count\x18\x05 \x01(\x03R\x05count\"<\n" +
+	"\x11ExportEventsChunk\x12'\n" +
+	"\x04rows\x18\x01 \x03(\v2\x13.stats.v1.ExportRowR\x04rows\"\xfc\x01\n" +
+	"\fFlaggedEvent\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12*\n" +
+	"\x02ts\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x02ts\x12\x1d\n" +
+	"\n" +
+	"event_type\x18\x03 \x01(\tR\teventType\x12\x17\n" +
+	"\apost_id\x18\x04 \x01(\tR\x06postId\x12\x17\n" +
+	"\auser_id\x18\x05 \x01(\tR\x06userId\x12\x0e\n" +
+	"\x02ip\x18\x06 \x01(\tR\x02ip\x12\x16\n" +
+	"\x06reason\x18\a \x01(\tR\x06reason\x12\x16\n" +
+	"\x06status\x18\b \x01(\tR\x06status\x12\x1f\n" +
+	"\vreviewed_by\x18\t \x01(\tR\n" +
+	"reviewedBy\"a\n" +
+	"\x18ListFlaggedEventsRequest\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status\x12\x17\n" +
+	"\apost_id\x18\x02 \x01(\tR\x06postId\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\x05R\x05limit\"K\n" +
+	"\x19ListFlaggedEventsResponse\x12.\n" +
+	"\x06events\x18\x01 \x03(\v2\x16.stats.v1.FlaggedEventR\x06events\"X\n" +
+	"\x19ReviewFlaggedEventRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x18\n" +
+	"\aapprove\x18\x02 \x01(\bR\aapproveJ\x04\b\x03\x10\x04R\vreviewer_id\"J\n" +
+	"\x1aReviewFlaggedEventResponse\x12,\n" +
+	"\x05event\x18\x01 \x01(\v2\x16.stats.v1.FlaggedEventR\x05event\"\x13\n" +
+	"\x11UserEventsRequest\"o\n" +
+	"\tUserEvent\x12*\n" +
+	"\x02ts\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x02ts\x12\x1d\n" +
+	"\n" +
+	"event_type\x18\x02 \x01(\tR\teventType\x12\x17\n" +
+	"\apost_id\x18\x03 \x01(\tR\x06postId\"p\n" +
+	"\x0fUserEventsChunk\x12+\n" +
+	"\x06events\x18\x01 \x03(\v2\x13.stats.v1.UserEventR\x06events\x120\n" +
+	"\aflagged\x18\x02 \x03(\v2\x16.stats.v1.FlaggedEventR\aflagged2\xb3\x06\n" +
 	"\fStatsService\x12G\n" +
 	"\fGetPostStats\x12\x1a.stats.v1.PostStatsRequest\x1a\x1b.stats.v1.PostStatsResponse\x12D\n" +
 	"\vGetTopPosts\x12\x19.stats.v1.TopPostsRequest\x1a\x1a.stats.v1.TopPostsResponse\x12K\n" +
-	"\x12GetTopUsersByLikes\x12\x19.stats.v1.TopUsersRequest\x1a\x1a.stats.v1.TopUsersResponseB\x1bZ\x19stats-service/proto;protob\x06proto3"
+	"\x12GetTopUsersByLikes\x12\x19.stats.v1.TopUsersRequest\x1a\x1a.stats.v1.TopUsersResponse\x12K\n" +
+	"\x0eWatchPostStats\x12\x1a.stats.v1.PostStatsRequest\x1a\x1b.stats.v1.PostStatsResponse0\x01\x12P\n" +
+	"\x0fGetRelatedPosts\x12\x1d.stats.v1.RelatedPostsRequest\x1a\x1e.stats.v1.RelatedPostsResponse\x12M\n" +
+	"\x0eGetAuthorStats\x12\x1c.stats.v1.AuthorStatsRequest\x1a\x1d.stats.v1.AuthorStatsResponse\x12L\n" +
+	"\fExportEvents\x12\x1d.stats.v1.ExportEventsRequest\x1a\x1b.stats.v1.ExportEventsChunk0\x01\x12\\\n" +
+	"\x11ListFlaggedEvents\x12\".stats.v1.ListFlaggedEventsRequest\x1a#.stats.v1.ListFlaggedEventsResponse\x12_\n" +
+	"\x12ReviewFlaggedEvent\x12#.stats.v1.ReviewFlaggedEventRequest\x1a$.stats.v1.ReviewFlaggedEventResponse\x12L\n" +
+	"\x10ExportUserEvents\x12\x1b.stats.v1.UserEventsRequest\x1a\x19.stats.v1.UserEventsChunk0\x01B\x1bZ\x19stats-service/proto;protob\x06proto3"
 
 var (
 	file_proto_stats_proto_rawDescOnce sync.Once
@@ -456,31 +1602,77 @@ func file_proto_stats_proto_rawDescGZIP() []byte {
 	return file_proto_stats_proto_rawDescData
 }
 
-var file_proto_stats_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_proto_stats_proto_msgTypes = make([]protoimpl.MessageInfo, 25)
 var file_proto_stats_proto_goTypes = []any{
-	(*PostStatsRequest)(nil),  // 0: stats.v1.PostStatsRequest
-	(*PostStatsResponse)(nil), // 1: stats.v1.PostStatsResponse
-	(*TopPostsRequest)(nil),   // 2: stats.v1.TopPostsRequest
-	(*PostItem)(nil),          // 3: stats.v1.PostItem
-	(*TopPostsResponse)(nil),  // 4: stats.v1.TopPostsResponse
-	(*TopUsersRequest)(nil),   // 5: stats.v1.TopUsersRequest
-	(*UserItem)(nil),          // 6: stats.v1.UserItem
-	(*TopUsersResponse)(nil),  // 7: stats.v1.TopUsersResponse
+	(*PostStatsRequest)(nil),           // 0: stats.v1.PostStatsRequest
+	(*PostStatsResponse)(nil),          // 1: stats.v1.PostStatsResponse
+	(*TopPostsRequest)(nil),            // 2: stats.v1.TopPostsRequest
+	(*PostItem)(nil),                   // 3: stats.v1.PostItem
+	(*TopPostsResponse)(nil),           // 4: stats.v1.TopPostsResponse
+	(*TopUsersRequest)(nil),            // 5: stats.v1.TopUsersRequest
+	(*UserItem)(nil),                   // 6: stats.v1.UserItem
+	(*TopUsersResponse)(nil),           // 7: stats.v1.TopUsersResponse
+	(*RelatedPostsRequest)(nil),        // 8: stats.v1.RelatedPostsRequest
+	(*RelatedPostsResponse)(nil),       // 9: stats.v1.RelatedPostsResponse
+	(*AuthorStatsRequest)(nil),         // 10: stats.v1.AuthorStatsRequest
+	(*AuthorPostStats)(nil),            // 11: stats.v1.AuthorPostStats
+	(*DailyStats)(nil),                 // 12: stats.v1.DailyStats
+	(*AuthorStatsResponse)(nil),        // 13: stats.v1.AuthorStatsResponse
+	(*ExportEventsRequest)(nil),        // 14: stats.v1.ExportEventsRequest
+	(*ExportRow)(nil),                  // 15: stats.v1.ExportRow
+	(*ExportEventsChunk)(nil),          // 16: stats.v1.ExportEventsChunk
+	(*FlaggedEvent)(nil),               // 17: stats.v1.FlaggedEvent
+	(*ListFlaggedEventsRequest)(nil),   // 18: stats.v1.ListFlaggedEventsRequest
+	(*ListFlaggedEventsResponse)(nil),  // 19: stats.v1.ListFlaggedEventsResponse
+	(*ReviewFlaggedEventRequest)(nil),  // 20: stats.v1.ReviewFlaggedEventRequest
+	(*ReviewFlaggedEventResponse)(nil), // 21: stats.v1.ReviewFlaggedEventResponse
+	(*UserEventsRequest)(nil),          // 22: stats.v1.UserEventsRequest
+	(*UserEvent)(nil),                  // 23: stats.v1.UserEvent
+	(*UserEventsChunk)(nil),            // 24: stats.v1.UserEventsChunk
+	(*timestamppb.Timestamp)(nil),      // 25: google.protobuf.Timestamp
 }
 var file_proto_stats_proto_depIdxs = []int32{
-	3, // 0: stats.v1.TopPostsResponse.items:type_name -> stats.v1.PostItem
-	6, // 1: stats.v1.TopUsersResponse.users:type_name -> stats.v1.UserItem
-	0, // 2: stats.v1.StatsService.GetPostStats:input_type -> stats.v1.PostStatsRequest
-	2, // 3: stats.v1.StatsService.GetTopPosts:input_type -> stats.v1.TopPostsRequest
-	5, // 4: stats.v1.StatsService.GetTopUsersByLikes:input_type -> stats.v1.TopUsersRequest
-	1, // 5: stats.v1.StatsService.GetPostStats:output_type -> stats.v1.PostStatsResponse
-	4, // 6: stats.v1.StatsService.GetTopPosts:output_type -> stats.v1.TopPostsResponse
-	7, // 7: stats.v1.StatsService.GetTopUsersByLikes:output_type -> stats.v1.TopUsersResponse
-	5, // [5:8] is the sub-list for method output_type
-	2, // [2:5] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	3,  // 0: stats.v1.TopPostsResponse.items:type_name -> stats.v1.PostItem
+	6,  // 1: stats.v1.TopUsersResponse.users:type_name -> stats.v1.UserItem
+	3,  // 2: stats.v1.RelatedPostsResponse.items:type_name -> stats.v1.PostItem
+	11, // 3: stats.v1.AuthorStatsResponse.posts:type_name -> stats.v1.AuthorPostStats
+	11, // 4: stats.v1.AuthorStatsResponse.best_posts:type_name -> stats.v1.AuthorPostStats
+	12, // 5: stats.v1.AuthorStatsResponse.daily:type_name -> stats.v1.DailyStats
+	25, // 6: stats.v1.ExportEventsRequest.from:type_name -> google.protobuf.Timestamp
+	25, // 7: stats.v1.ExportEventsRequest.to:type_name -> google.protobuf.Timestamp
+	25, // 8: stats.v1.ExportRow.ts:type_name -> google.protobuf.Timestamp
+	15, // 9: stats.v1.ExportEventsChunk.rows:type_name -> stats.v1.ExportRow
+	25, // 10: stats.v1.FlaggedEvent.ts:type_name -> google.protobuf.Timestamp
+	17, // 11: stats.v1.ListFlaggedEventsResponse.events:type_name -> stats.v1.FlaggedEvent
+	17, // 12: stats.v1.ReviewFlaggedEventResponse.event:type_name -> stats.v1.FlaggedEvent
+	25, // 13: stats.v1.UserEvent.ts:type_name -> google.protobuf.Timestamp
+	23, // 14: stats.v1.UserEventsChunk.events:type_name -> stats.v1.UserEvent
+	17, // 15: stats.v1.UserEventsChunk.flagged:type_name -> stats.v1.FlaggedEvent
+	0,  // 16: stats.v1.StatsService.GetPostStats:input_type -> stats.v1.PostStatsRequest
+	2,  // 17: stats.v1.StatsService.GetTopPosts:input_type -> stats.v1.TopPostsRequest
+	5,  // 18: stats.v1.StatsService.GetTopUsersByLikes:input_type -> stats.v1.TopUsersRequest
+	0,  // 19: stats.v1.StatsService.WatchPostStats:input_type -> stats.v1.PostStatsRequest
+	8,  // 20: stats.v1.StatsService.GetRelatedPosts:input_type -> stats.v1.RelatedPostsRequest
+	10, // 21: stats.v1.StatsService.GetAuthorStats:input_type -> stats.v1.AuthorStatsRequest
+	14, // 22: stats.v1.StatsService.ExportEvents:input_type -> stats.v1.ExportEventsRequest
+	18, // 23: stats.v1.StatsService.ListFlaggedEvents:input_type -> stats.v1.ListFlaggedEventsRequest
+	20, // 24: stats.v1.StatsService.ReviewFlaggedEvent:input_type -> stats.v1.ReviewFlaggedEventRequest
+	22, // 25: stats.v1.StatsService.ExportUserEvents:input_type -> stats.v1.UserEventsRequest
+	1,  // 26: stats.v1.StatsService.GetPostStats:output_type -> stats.v1.PostStatsResponse
+	4,  // 27: stats.v1.StatsService.GetTopPosts:output_type -> stats.v1.TopPostsResponse
+	7,  // 28: stats.v1.StatsService.GetTopUsersByLikes:output_type -> stats.v1.TopUsersResponse
+	1,  // 29: stats.v1.StatsService.WatchPostStats:output_type -> stats.v1.PostStatsResponse
+	9,  // 30: stats.v1.StatsService.GetRelatedPosts:output_type -> stats.v1.RelatedPostsResponse
+	13, // 31: stats.v1.StatsService.GetAuthorStats:output_type -> stats.v1.AuthorStatsResponse
+	16, // 32: stats.v1.StatsService.ExportEvents:output_type -> stats.v1.ExportEventsChunk
+	19, // 33: stats.v1.StatsService.ListFlaggedEvents:output_type -> stats.v1.ListFlaggedEventsResponse
+	21, // 34: stats.v1.StatsService.ReviewFlaggedEvent:output_type -> stats.v1.ReviewFlaggedEventResponse
+	24, // 35: stats.v1.StatsService.ExportUserEvents:output_type -> stats.v1.UserEventsChunk
+	26, // [26:36] is the sub-list for method output_type
+	16, // [16:26] is the sub-list for method input_type
+	16, // [16:16] is the sub-list for extension type_name
+	16, // [16:16] is the sub-list for extension extendee
+	0,  // [0:16] is the sub-list for field type_name
 }
 
 func init() { file_proto_stats_proto_init() }
@@ -494,7 +1686,7 @@ func file_proto_stats_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_stats_proto_rawDesc), len(file_proto_stats_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   8,
+			NumMessages:   25,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -22,6 +22,13 @@ const (
 	StatsService_GetPostStats_FullMethodName       = "/stats.v1.StatsService/GetPostStats"
 	StatsService_GetTopPosts_FullMethodName        = "/stats.v1.StatsService/GetTopPosts"
 	StatsService_GetTopUsersByLikes_FullMethodName = "/stats.v1.StatsService/GetTopUsersByLikes"
+	StatsService_WatchPostStats_FullMethodName     = "/stats.v1.StatsService/WatchPostStats"
+	StatsService_GetRelatedPosts_FullMethodName    = "/stats.v1.StatsService/GetRelatedPosts"
+	StatsService_GetAuthorStats_FullMethodName     = "/stats.v1.StatsService/GetAuthorStats"
+	StatsService_ExportEvents_FullMethodName       = "/stats.v1.StatsService/ExportEvents"
+	StatsService_ListFlaggedEvents_FullMethodName  = "/stats.v1.StatsService/ListFlaggedEvents"
+	StatsService_ReviewFlaggedEvent_FullMethodName = "/stats.v1.StatsService/ReviewFlaggedEvent"
+	StatsService_ExportUserEvents_FullMethodName   = "/stats.v1.StatsService/ExportUserEvents"
 )
 
 // StatsServiceClient is the client API for StatsService service.
@@ -31,6 +38,23 @@ type StatsServiceClient interface {
 	GetPostStats(ctx context.Context, in *PostStatsRequest, opts ...grpc.CallOption) (*PostStatsResponse, error)
 	GetTopPosts(ctx context.Context, in *TopPostsRequest, opts ...grpc.CallOption) (*TopPostsResponse, error)
 	GetTopUsersByLikes(ctx context.Context, in *TopUsersRequest, opts ...grpc.CallOption) (*TopUsersResponse, error)
+	// Streams the post counters: the current values first, then an update
+	// whenever new events for the post are consumed (coalesced server-side).
+	WatchPostStats(ctx context.Context, in *PostStatsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PostStatsResponse], error)
+	// "People who liked this also liked": neighbours from the latest
+	// recommendation run.
+	GetRelatedPosts(ctx context.Context, in *RelatedPostsRequest, opts ...grpc.CallOption) (*RelatedPostsResponse, error)
+	// Totals, per-post breakdown, reach and a daily series across all posts
+	// of one author.
+	GetAuthorStats(ctx context.Context, in *AuthorStatsRequest, opts ...grpc.CallOption) (*AuthorStatsResponse, error)
+	// Streams raw events or hourly/daily aggregates for [from, to) in chunks.
+	ExportEvents(ctx context.Context, in *ExportEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ExportEventsChunk], error)
+	// Moderation queue of events flagged as bot or fraud traffic.
+	ListFlaggedEvents(ctx context.Context, in *ListFlaggedEventsRequest, opts ...grpc.CallOption) (*ListFlaggedEventsResponse, error)
+	ReviewFlaggedEvent(ctx context.Context, in *ReviewFlaggedEventRequest, opts ...grpc.CallOption) (*ReviewFlaggedEventResponse, error)
+	// Streams the raw events the calling user made and the flagged events
+	// about them, IPs included, for personal data exports.
+	ExportUserEvents(ctx context.Context, in *UserEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[UserEventsChunk], error)
 }
 
 type statsServiceClient struct {
@@ -71,6 +95,103 @@ func (c *statsServiceClient) GetTopUsersByLikes(ctx context.Context, in *TopUser
 	return out, nil
 }
 
+func (c *statsServiceClient) WatchPostStats(ctx context.Context, in *PostStatsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PostStatsResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &StatsService_ServiceDesc.Streams[0], StatsService_WatchPostStats_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[PostStatsRequest, PostStatsResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type StatsService_WatchPostStatsClient = grpc.ServerStreamingClient[PostStatsResponse]
+
+func (c *statsServiceClient) GetRelatedPosts(ctx context.Context, in *RelatedPostsRequest, opts ...grpc.CallOption) (*RelatedPostsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RelatedPostsResponse)
+	err := c.cc.Invoke(ctx, StatsService_GetRelatedPosts_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *statsServiceClient) GetAuthorStats(ctx context.Context, in *AuthorStatsRequest, opts ...grpc.CallOption) (*AuthorStatsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AuthorStatsResponse)
+	err := c.cc.Invoke(ctx, StatsService_GetAuthorStats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *statsServiceClient) ExportEvents(ctx context.Context, in *ExportEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ExportEventsChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &StatsService_ServiceDesc.Streams[1], StatsService_ExportEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ExportEventsRequest, ExportEventsChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type StatsService_ExportEventsClient = grpc.ServerStreamingClient[ExportEventsChunk]
+
+func (c *statsServiceClient) ListFlaggedEvents(ctx context.Context, in *ListFlaggedEventsRequest, opts ...grpc.CallOption) (*ListFlaggedEventsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListFlaggedEventsResponse)
+	err := c.cc.Invoke(ctx, StatsService_ListFlaggedEvents_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *statsServiceClient) ReviewFlaggedEvent(ctx context.Context, in *ReviewFlaggedEventRequest, opts ...grpc.CallOption) (*ReviewFlaggedEventResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReviewFlaggedEventResponse)
+	err := c.cc.Invoke(ctx, StatsService_ReviewFlaggedEvent_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *statsServiceClient) ExportUserEvents(ctx context.Context, in *UserEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[UserEventsChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &StatsService_ServiceDesc.Streams[2], StatsService_ExportUserEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[UserEventsRequest, UserEventsChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type StatsService_ExportUserEventsClient = grpc.ServerStreamingClient[UserEventsChunk]
+
 // StatsServiceServer is the server API for StatsService service.
 // All implementations must embed UnimplementedStatsServiceServer
 // for forward compatibility.
@@ -78,6 +199,23 @@ type StatsServiceServer interface {
 	GetPostStats(context.Context, *PostStatsRequest) (*PostStatsResponse, error)
 	GetTopPosts(context.Context, *TopPostsRequest) (*TopPostsResponse, error)
 	GetTopUsersByLikes(context.Context, *TopUsersRequest) (*TopUsersResponse, error)
+	// Streams the post counters: the current values first, then an update
+	// whenever new events for the post are consumed (coalesced server-side).
+	WatchPostStats(*PostStatsRequest, grpc.ServerStreamingServer[PostStatsResponse]) error
+	// "People who liked this also liked": neighbours from the latest
+	// recommendation run.
+	GetRelatedPosts(context.Context, *RelatedPostsRequest) (*RelatedPostsResponse, error)
+	// Totals, per-post breakdown, reach and a daily series across all posts
+	// of one author.
+	GetAuthorStats(context.Context, *AuthorStatsRequest) (*AuthorStatsResponse, error)
+	// Streams raw events or hourly/daily aggregates for [from, to) in chunks.
+	ExportEvents(*ExportEventsRequest, grpc.ServerStreamingServer[ExportEventsChunk]) error
+	// Moderation queue of events flagged as bot or fraud traffic.
+	ListFlaggedEvents(context.Context, *ListFlaggedEventsRequest) (*ListFlaggedEventsResponse, error)
+	ReviewFlaggedEvent(context.Context, *ReviewFlaggedEventRequest) (*ReviewFlaggedEventResponse, error)
+	// Streams the raw events the calling user made and the flagged events
+	// about them, IPs included, for personal data exports.
+	ExportUserEvents(*UserEventsRequest, grpc.ServerStreamingServer[UserEventsChunk]) error
 	mustEmbedUnimplementedStatsServiceServer()
 }
 
@@ -97,6 +235,27 @@ func (UnimplementedStatsServiceServer) GetTopPosts(context.Context, *TopPostsReq
 func (UnimplementedStatsServiceServer) GetTopUsersByLikes(context.Context, *TopUsersRequest) (*TopUsersResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetTopUsersByLikes not implemented")
 }
+func (UnimplementedStatsServiceServer) WatchPostStats(*PostStatsRequest, grpc.ServerStreamingServer[PostStatsResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchPostStats not implemented")
+}
+func (UnimplementedStatsServiceServer) GetRelatedPosts(context.Context, *RelatedPostsRequest) (*RelatedPostsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRelatedPosts not implemented")
+}
+func (UnimplementedStatsServiceServer) GetAuthorStats(context.Context, *AuthorStatsRequest) (*AuthorStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAuthorStats not implemented")
+}
+func (UnimplementedStatsServiceServer) ExportEvents(*ExportEventsRequest, grpc.ServerStreamingServer[ExportEventsChunk]) error {
+	return status.Errorf(codes.Unimplemented, "method ExportEvents not implemented")
+}
+func (UnimplementedStatsServiceServer) ListFlaggedEvents(context.Context, *ListFlaggedEventsRequest) (*ListFlaggedEventsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListFlaggedEvents not implemented")
+}
+func (UnimplementedStatsServiceServer) ReviewFlaggedEvent(context.Context, *ReviewFlaggedEventRequest) (*ReviewFlaggedEventResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReviewFlaggedEvent not implemented")
+}
+func (UnimplementedStatsServiceServer) ExportUserEvents(*UserEventsRequest, grpc.ServerStreamingServer[UserEventsChunk]) error {
+	return status.Errorf(codes.Unimplemented, "method ExportUserEvents not implemented")
+}
 func (UnimplementedStatsServiceServer) mustEmbedUnimplementedStatsServiceServer() {}
 func (UnimplementedStatsServiceServer) testEmbeddedByValue()                      {}
 
@@ -172,6 +331,111 @@ func _StatsService_GetTopUsersByLikes_Handler(srv interface{}, ctx context.Conte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _StatsService_WatchPostStats_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(PostStatsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(StatsServiceServer).WatchPostStats(m, &grpc.GenericServerStream[PostStatsRequest, PostStatsResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type StatsService_WatchPostStatsServer = grpc.ServerStreamingServer[PostStatsResponse]
+
+func _StatsService_GetRelatedPosts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RelatedPostsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StatsServiceServer).GetRelatedPosts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StatsService_GetRelatedPosts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StatsServiceServer).GetRelatedPosts(ctx, req.(*RelatedPostsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StatsService_GetAuthorStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuthorStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StatsServiceServer).GetAuthorStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StatsService_GetAuthorStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StatsServiceServer).GetAuthorStats(ctx, req.(*AuthorStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StatsService_ExportEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExportEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(StatsServiceServer).ExportEvents(m, &grpc.GenericServerStream[ExportEventsRequest, ExportEventsChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type StatsService_ExportEventsServer = grpc.ServerStreamingServer[ExportEventsChunk]
+
+func _StatsService_ListFlaggedEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFlaggedEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StatsServiceServer).ListFlaggedEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StatsService_ListFlaggedEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StatsServiceServer).ListFlaggedEvents(ctx, req.(*ListFlaggedEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StatsService_ReviewFlaggedEvent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReviewFlaggedEventRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StatsServiceServer).ReviewFlaggedEvent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StatsService_ReviewFlaggedEvent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StatsServiceServer).ReviewFlaggedEvent(ctx, req.(*ReviewFlaggedEventRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StatsService_ExportUserEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(UserEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(StatsServiceServer).ExportUserEvents(m, &grpc.GenericServerStream[UserEventsRequest, UserEventsChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type StatsService_ExportUserEventsServer = grpc.ServerStreamingServer[UserEventsChunk]
+
 // StatsService_ServiceDesc is the grpc.ServiceDesc for StatsService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -191,7 +455,39 @@ var StatsService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetTopUsersByLikes",
 			Handler:    _StatsService_GetTopUsersByLikes_Handler,
 		},
+		{
+			MethodName: "GetRelatedPosts",
+			Handler:    _StatsService_GetRelatedPosts_Handler,
+		},
+		{
+			MethodName: "GetAuthorStats",
+			Handler:    _StatsService_GetAuthorStats_Handler,
+		},
+		{
+			MethodName: "ListFlaggedEvents",
+			Handler:    _StatsService_ListFlaggedEvents_Handler,
+		},
+		{
+			MethodName: "ReviewFlaggedEvent",
+			Handler:    _StatsService_ReviewFlaggedEvent_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchPostStats",
+			Handler:       _StatsService_WatchPostStats_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ExportEvents",
+			Handler:       _StatsService_ExportEvents_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ExportUserEvents",
+			Handler:       _StatsService_ExportUserEvents_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/stats.proto",
 }
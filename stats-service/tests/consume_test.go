@@ -3,6 +3,11 @@ package tests
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -13,47 +18,15 @@ import (
 	"stats-service/internal/storage"
 )
 
-type memoryRepo struct {
-	mu     sync.Mutex
-	events []storage.Event
-}
-
-func (m *memoryRepo) SaveEvent(_ context.Context, e storage.Event) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	m.events = append(m.events, e)
-	return nil
-}
-
-func (m *memoryRepo) PostStats(_ context.Context, postID string) (int64, int64, error) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	var views, likes int64
-	for _, e := range m.events {
-		if e.PostID != postID {
-			continue
-		}
-		if e.EventType == "view" {
-			views++
-		}
-		if e.EventType == "like" {
-			likes++
-		}
-	}
-	return views, likes, nil
-}
-
-func (m *memoryRepo) TopPosts(context.Context, string, int) ([]storage.PostCount, error) {
-	return nil, nil
-}
-func (m *memoryRepo) LikesPerPost(context.Context) ([]storage.PostCount, error) { return nil, nil }
-
 type stubReader struct {
 	messages []kafka.Message
 	cancel   context.CancelFunc
+
+	mu        sync.Mutex
+	committed []kafka.Message
 }
 
-func (s *stubReader) ReadMessage(ctx context.Context) (kafka.Message, error) {
+func (s *stubReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
 	if len(s.messages) == 0 {
 		if s.cancel != nil {
 			s.cancel()
@@ -65,10 +38,17 @@ func (s *stubReader) ReadMessage(ctx context.Context) (kafka.Message, error) {
 	return msg, nil
 }
 
+func (s *stubReader) CommitMessages(_ context.Context, msgs ...kafka.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.committed = append(s.committed, msgs...)
+	return nil
+}
+
 func (s *stubReader) Close() error { return nil }
 
 func TestConsumeTopicSavesEvents(t *testing.T) {
-	repo := &memoryRepo{}
+	repo := storage.NewMemory()
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -97,3 +77,114 @@ func TestConsumeTopicSavesEvents(t *testing.T) {
 		t.Fatalf("expected 1 view and 1 like, got %d and %d", views, likes)
 	}
 }
+
+func TestConsumeTopicKeepsPartitionOrderAcrossWorkers(t *testing.T) {
+	repo := storage.NewMemory()
+	metrics := app.NewConsumerMetricsForTest()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var messages []kafka.Message
+	for offset := 0; offset < 5; offset++ {
+		for partition := 0; partition < 3; partition++ {
+			value, _ := json.Marshal(map[string]any{"post_id": fmt.Sprintf("p%d", partition), "event_type": "view"})
+			messages = append(messages, kafka.Message{
+				Topic:         "post_views",
+				Partition:     partition,
+				Offset:        int64(offset),
+				HighWaterMark: 7,
+				Value:         value,
+			})
+		}
+	}
+	reader := &stubReader{messages: messages, cancel: cancel}
+	restore := app.SetKafkaReaderFactoryForTest(func(kafka.ReaderConfig) app.KafkaMessageReaderForTest { return reader })
+	defer restore()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go app.ConsumeTopicWithWorkersForTest(ctx, &wg, repo, metrics, kafka.ReaderConfig{}, "view", 2)
+	wg.Wait()
+
+	for partition := 0; partition < 3; partition++ {
+		if views, _, _ := repo.PostStats(context.Background(), fmt.Sprintf("p%d", partition)); views != 5 {
+			t.Fatalf("expected 5 views for partition %d, got %d", partition, views)
+		}
+	}
+
+	last := map[int]int64{}
+	for _, msg := range reader.committed {
+		if prev, ok := last[msg.Partition]; ok && msg.Offset <= prev {
+			t.Fatalf("partition %d committed offset %d after %d", msg.Partition, msg.Offset, prev)
+		}
+		last[msg.Partition] = msg.Offset
+	}
+	if len(reader.committed) != len(messages) {
+		t.Fatalf("expected %d commits, got %d", len(messages), len(reader.committed))
+	}
+
+	rr := httptest.NewRecorder()
+	metrics.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rr.Body.String()
+	for _, want := range []string{
+		`stats_consumer_lag{topic="post_views",partition="1"} 2`,
+		`stats_consumer_messages_total{topic="post_views",result="saved"} 15`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("metrics missing %q:\n%s", want, body)
+		}
+	}
+}
+
+// flakyRepo fails the first saves and any save with a cancelled context.
+type flakyRepo struct {
+	*storage.Memory
+
+	mu       sync.Mutex
+	failures int
+}
+
+func (f *flakyRepo) SaveEvent(ctx context.Context, e storage.Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	fail := f.failures > 0
+	f.failures--
+	f.mu.Unlock()
+	if fail {
+		return errors.New("clickhouse unavailable")
+	}
+	return f.Memory.SaveEvent(ctx, e)
+}
+
+func TestConsumeTopicRetriesAndDrainsOnShutdown(t *testing.T) {
+	repo := &flakyRepo{Memory: storage.NewMemory(), failures: 2}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var messages []kafka.Message
+	for offset := 0; offset < 3; offset++ {
+		value, _ := json.Marshal(map[string]any{"post_id": "p1", "event_type": "view"})
+		messages = append(messages, kafka.Message{Offset: int64(offset), Value: value})
+	}
+	// The reader cancels ctx once everything is fetched, while the first
+	// message is still being retried.
+	reader := &stubReader{messages: messages, cancel: cancel}
+	restore := app.SetKafkaReaderFactoryForTest(func(kafka.ReaderConfig) app.KafkaMessageReaderForTest { return reader })
+	defer restore()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go app.ConsumeTopicForTest(ctx, &wg, repo, kafka.ReaderConfig{}, "view")
+	wg.Wait()
+
+	if views, _, _ := repo.PostStats(context.Background(), "p1"); views != 3 {
+		t.Fatalf("expected all queued views to be stored, got %d", views)
+	}
+	if len(reader.committed) != 3 {
+		t.Fatalf("expected 3 commits, got %d", len(reader.committed))
+	}
+}
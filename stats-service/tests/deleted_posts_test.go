@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	postspb "posts-service/proto"
+	"stats-service/internal/app"
+	"stats-service/internal/storage"
+	statspb "stats-service/proto"
+)
+
+// livePostsStub knows only the listed posts.
+type livePostsStub map[string]bool
+
+func (s livePostsStub) GetPost(_ context.Context, in *postspb.GetPostRequest, _ ...grpc.CallOption) (*postspb.GetPostResponse, error) {
+	if !s[in.GetId()] {
+		return nil, status.Error(codes.NotFound, "not found")
+	}
+	return &postspb.GetPostResponse{Post: &postspb.Post{Id: in.GetId()}}, nil
+}
+
+func (livePostsStub) ListPosts(context.Context, *postspb.ListPostsRequest, ...grpc.CallOption) (*postspb.ListPostsResponse, error) {
+	return &postspb.ListPostsResponse{}, nil
+}
+
+func seedViews(t *testing.T, repo *storage.Memory, views map[string]int) {
+	t.Helper()
+	now := time.Now().UTC()
+	for postID, n := range views {
+		for i := 0; i < n; i++ {
+			if err := repo.SaveEvent(context.Background(), storage.Event{PostID: postID, EventType: "view", Timestamp: now}); err != nil {
+				t.Fatalf("save event: %v", err)
+			}
+		}
+	}
+}
+
+func TestDeletedPostsLeaveRankingsAndArePurged(t *testing.T) {
+	ctx := context.Background()
+	repo := storage.NewMemory()
+	seedViews(t, repo, map[string]int{"p1": 5, "p2": 3, "p3": 2, "p4": 1})
+
+	consumeCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var messages []kafka.Message
+	for _, e := range []map[string]any{
+		{"event_type": "post_created", "post_id": "p5"},
+		{"event_type": "post_deleted", "post_id": "p1", "timestamp": time.Now()},
+		{"event_type": "post_deleted", "post_id": "p1", "timestamp": time.Now()},
+	} {
+		value, _ := json.Marshal(e)
+		messages = append(messages, kafka.Message{Value: value})
+	}
+	reader := &stubReader{messages: messages, cancel: cancel}
+	restore := app.SetKafkaReaderFactoryForTest(func(kafka.ReaderConfig) app.KafkaMessageReaderForTest { return reader })
+	defer restore()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go app.ConsumePostEventsForTest(consumeCtx, &wg, repo, kafka.ReaderConfig{})
+	wg.Wait()
+	if len(reader.committed) != 3 {
+		t.Fatalf("expected every post event to be committed, got %d", len(reader.committed))
+	}
+
+	srv := app.NewStatsServerForTest(repo, nil)
+	resp, err := srv.GetTopPosts(ctx, &statspb.TopPostsRequest{Limit: 3})
+	if err != nil {
+		t.Fatalf("top posts: %v", err)
+	}
+	if items := resp.GetItems(); len(items) != 3 || items[0].GetPostId() != "p2" || items[2].GetPostId() != "p4" {
+		t.Fatalf("expected three live posts, got %+v", items)
+	}
+
+	if n, err := repo.PurgeDeletedPosts(ctx, 0); err != nil || n != 1 {
+		t.Fatalf("expected one purged post, got %d (%v)", n, err)
+	}
+	if views, _, _ := repo.PostStats(ctx, "p1"); views != 0 {
+		t.Fatalf("expected purged post to have no stats, got %d views", views)
+	}
+	if n, _ := repo.PurgeDeletedPosts(ctx, 0); n != 0 {
+		t.Fatalf("expected nothing left to purge, got %d", n)
+	}
+}
+
+func TestReconcileMarksPostsMissingInPostsService(t *testing.T) {
+	ctx := context.Background()
+	repo := storage.NewMemory()
+	seedViews(t, repo, map[string]int{"p1": 2, "p2": 1})
+
+	n, err := app.ReconcileDeletedPostsForTest(ctx, repo, livePostsStub{"p2": true})
+	if err != nil || n != 1 {
+		t.Fatalf("expected one post marked deleted, got %d (%v)", n, err)
+	}
+	ids, _ := repo.LivePostIDs(ctx)
+	if len(ids) != 1 || ids[0] != "p2" {
+		t.Fatalf("unexpected live posts: %v", ids)
+	}
+	top, _ := repo.TopPosts(ctx, "view", 5)
+	if len(top) != 1 || top[0].PostID != "p2" {
+		t.Fatalf("expected deleted post to leave the ranking, got %+v", top)
+	}
+}
@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"stats-service/internal/app"
+	"stats-service/internal/storage"
+)
+
+func TestDeletedUsersLoseTheirEvents(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	repo, err := storage.OpenFile(path)
+	if err != nil {
+		t.Fatalf("open file storage: %v", err)
+	}
+	now := time.Now().UTC()
+	for _, e := range []storage.Event{
+		{PostID: "p1", EventType: "view", UserID: "u1", Timestamp: now},
+		{PostID: "p1", EventType: "like", UserID: "u1", Timestamp: now},
+		{PostID: "p1", EventType: "view", UserID: "u2", Timestamp: now},
+	} {
+		if err := repo.SaveEvent(ctx, e); err != nil {
+			t.Fatalf("save event: %v", err)
+		}
+	}
+	repo.FlagEvent(ctx, storage.FlaggedEvent{ID: "f1", Event: storage.Event{PostID: "p1", EventType: "view", UserID: "u1", Timestamp: now}, Reason: "viewer_rate"})
+
+	consumeCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var messages []kafka.Message
+	for _, e := range []map[string]any{
+		{"event_type": "user_created", "user_id": "u2"},
+		{"event_type": "user_deleted", "user_id": "u1", "timestamp": now},
+	} {
+		value, _ := json.Marshal(e)
+		messages = append(messages, kafka.Message{Value: value})
+	}
+	reader := &stubReader{messages: messages, cancel: cancel}
+	restore := app.SetKafkaReaderFactoryForTest(func(kafka.ReaderConfig) app.KafkaMessageReaderForTest { return reader })
+	defer restore()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go app.ConsumeUserEventsForTest(consumeCtx, &wg, repo, kafka.ReaderConfig{})
+	wg.Wait()
+	if len(reader.committed) != 2 {
+		t.Fatalf("expected every user event to be committed, got %d", len(reader.committed))
+	}
+
+	check := func(repo *storage.Memory) {
+		t.Helper()
+		if views, likes, _ := repo.PostStats(ctx, "p1"); views != 1 || likes != 0 {
+			t.Fatalf("expected only the other user's view to remain, got %d views %d likes", views, likes)
+		}
+		if flagged, _ := repo.FlaggedEvents(ctx, storage.FlagPending, "", 10); len(flagged) != 0 {
+			t.Fatalf("expected the deleted user's flagged events to go, got %+v", flagged)
+		}
+	}
+	check(repo)
+
+	repo.Close()
+	reopened, err := storage.OpenFile(path)
+	if err != nil {
+		t.Fatalf("reopen file storage: %v", err)
+	}
+	defer reopened.Close()
+	check(reopened)
+}
@@ -0,0 +1,211 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"common/identity"
+	"stats-service/internal/app"
+	"stats-service/internal/storage"
+	statspb "stats-service/proto"
+)
+
+func exportFixture(t *testing.T, n int) *storage.Memory {
+	t.Helper()
+	repo := storage.NewMemory()
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		postID := "p1"
+		if i%2 == 1 {
+			postID = "p2"
+		}
+		_ = repo.SaveEvent(context.Background(), storage.Event{
+			PostID:    postID,
+			EventType: "view",
+			UserID:    "u1",
+			Timestamp: start.Add(time.Duration(i) * time.Minute),
+		})
+	}
+	return repo
+}
+
+func signExportRequest(t *testing.T, req *http.Request) {
+	t.Helper()
+	token, err := identity.NewSigner("secret", "main-service").Sign("stats-service", "", "")
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	req.Header.Set(identity.MetadataKey, token)
+}
+
+func TestExportEventsRequiresIdentity(t *testing.T) {
+	handler := app.ExportHandlerForTest(exportFixture(t, 1), nil, "secret")
+
+	for _, signer := range []*identity.Signer{nil, identity.NewSigner("other", "main-service")} {
+		req := httptest.NewRequest(http.MethodGet, "/export/events?from=2026-01-01T00:00:00Z", nil)
+		if signer != nil {
+			token, _ := signer.Sign("stats-service", "", "")
+			req.Header.Set(identity.MetadataKey, token)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusUnauthorized {
+			t.Fatalf("expected 401, got %d", rr.Code)
+		}
+	}
+}
+
+func TestExportEventsCSV(t *testing.T) {
+	handler := app.ExportHandlerForTest(exportFixture(t, 4), nil, "secret")
+
+	req := httptest.NewRequest(http.MethodGet, "/export/events?from=2026-01-01T00:00:00Z&to=2026-01-02T00:00:00Z&post_id=p1", nil)
+	signExportRequest(t, req)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "text/csv" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	want := "ts,event_type,post_id,user_id,count\n" +
+		"2026-01-01T00:00:00Z,view,p1,u1,1\n" +
+		"2026-01-01T00:02:00Z,view,p1,u1,1\n"
+	if rr.Body.String() != want {
+		t.Fatalf("unexpected csv:\n%s", rr.Body.String())
+	}
+}
+
+func TestExportEventsParquet(t *testing.T) {
+	handler := app.ExportHandlerForTest(exportFixture(t, 3), nil, "secret")
+
+	req := httptest.NewRequest(http.MethodGet, "/export/events?from=2026-01-01T00:00:00Z&to=2026-01-02T00:00:00Z&format=parquet", nil)
+	signExportRequest(t, req)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	type row struct {
+		PostID string `parquet:"post_id"`
+		Count  int64  `parquet:"count"`
+	}
+	data := rr.Body.Bytes()
+	rows, err := parquet.Read[row](bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("read parquet: %v", err)
+	}
+	if len(rows) != 3 || rows[1].PostID != "p2" || rows[1].Count != 1 {
+		t.Fatalf("unexpected rows: %+v", rows)
+	}
+}
+
+func TestExportEventsRejectsInvalidRange(t *testing.T) {
+	handler := app.ExportHandlerForTest(storage.NewMemory(), nil, "secret")
+
+	req := httptest.NewRequest(http.MethodGet, "/export/events?from=2026-01-02T00:00:00Z&to=2026-01-01T00:00:00Z", nil)
+	signExportRequest(t, req)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "from must be before to") {
+		t.Fatalf("expected 400, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+type exportStream struct {
+	grpc.ServerStream
+	chunks []*statspb.ExportEventsChunk
+}
+
+func (s *exportStream) Context() context.Context { return context.Background() }
+
+func (s *exportStream) Send(chunk *statspb.ExportEventsChunk) error {
+	s.chunks = append(s.chunks, chunk)
+	return nil
+}
+
+func TestExportEventsStreamsChunks(t *testing.T) {
+	srv := app.NewStatsServerForTest(exportFixture(t, 1200), nil)
+
+	stream := &exportStream{}
+	err := srv.ExportEvents(&statspb.ExportEventsRequest{
+		From: timestamppb.New(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)),
+		To:   timestamppb.New(time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)),
+	}, stream)
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if len(stream.chunks) != 3 || len(stream.chunks[0].GetRows()) != 500 || len(stream.chunks[2].GetRows()) != 200 {
+		t.Fatalf("unexpected chunking: %d chunks", len(stream.chunks))
+	}
+}
+
+type userEventsStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	chunks []*statspb.UserEventsChunk
+}
+
+func (s *userEventsStream) Context() context.Context { return s.ctx }
+
+func (s *userEventsStream) Send(chunk *statspb.UserEventsChunk) error {
+	s.chunks = append(s.chunks, chunk)
+	return nil
+}
+
+func TestExportUserEventsReturnsOnlyTheCaller(t *testing.T) {
+	ctx := context.Background()
+	repo := storage.NewMemory()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, e := range []storage.Event{
+		{PostID: "p1", EventType: "like", UserID: "u1", Timestamp: now.Add(time.Minute)},
+		{PostID: "p1", EventType: "view", UserID: "u1", Timestamp: now},
+		{PostID: "p2", EventType: "view", UserID: "u2", Timestamp: now},
+	} {
+		if err := repo.SaveEvent(ctx, e); err != nil {
+			t.Fatalf("save: %v", err)
+		}
+	}
+	for _, f := range []storage.FlaggedEvent{
+		{ID: "f1", Event: storage.Event{PostID: "p2", EventType: "view", UserID: "u1", Timestamp: now}, IP: "192.0.2.1", Reason: "viewer_burst"},
+		{ID: "f2", Event: storage.Event{PostID: "p2", EventType: "view", UserID: "u2", Timestamp: now}, IP: "192.0.2.2", Reason: "viewer_burst"},
+	} {
+		if err := repo.FlagEvent(ctx, f); err != nil {
+			t.Fatalf("flag: %v", err)
+		}
+	}
+	srv := app.NewStatsServerForTest(repo, nil)
+
+	if err := srv.ExportUserEvents(&statspb.UserEventsRequest{}, &userEventsStream{ctx: ctx}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated without an identity, got %v", err)
+	}
+
+	stream := &userEventsStream{ctx: identity.WithCaller(ctx, identity.Caller{Service: "main-service", UserID: "u1"})}
+	if err := srv.ExportUserEvents(&statspb.UserEventsRequest{}, stream); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	if len(stream.chunks) != 1 {
+		t.Fatalf("expected one chunk, got %d", len(stream.chunks))
+	}
+	events, flagged := stream.chunks[0].GetEvents(), stream.chunks[0].GetFlagged()
+	if len(events) != 2 || events[0].GetEventType() != "view" || events[1].GetEventType() != "like" {
+		t.Fatalf("unexpected events %v", events)
+	}
+	if len(flagged) != 1 || flagged[0].GetId() != "f1" || flagged[0].GetIp() != "192.0.2.1" {
+		t.Fatalf("unexpected flagged events %v", flagged)
+	}
+}
@@ -0,0 +1,172 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"common/identity"
+	"stats-service/internal/app"
+	"stats-service/internal/storage"
+	statspb "stats-service/proto"
+)
+
+func consumeWithFraudRules(t *testing.T, repo *storage.Memory, topic string, events []map[string]any) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var messages []kafka.Message
+	for i, e := range events {
+		value, _ := json.Marshal(e)
+		messages = append(messages, kafka.Message{Topic: topic, Offset: int64(i), Value: value})
+	}
+	reader := &stubReader{messages: messages, cancel: cancel}
+	restore := app.SetKafkaReaderFactoryForTest(func(kafka.ReaderConfig) app.KafkaMessageReaderForTest { return reader })
+	defer restore()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go app.ConsumeTopicWithFraudRulesForTest(ctx, &wg, repo, kafka.ReaderConfig{}, "view", time.Minute, 3, -1, -1)
+	wg.Wait()
+}
+
+func TestFraudDetectionHoldsBackBurstsUntilReviewed(t *testing.T) {
+	repo := storage.NewMemory()
+	now := time.Now().UTC()
+
+	var events []map[string]any
+	for i := 0; i < 5; i++ {
+		events = append(events, map[string]any{"post_id": "p1", "ip": "10.0.0.1", "timestamp": now.Add(time.Duration(i) * time.Second)})
+	}
+	events = append(events, map[string]any{"post_id": "p1", "user_id": "u9", "timestamp": now})
+	consumeWithFraudRules(t, repo, "post_views", events)
+
+	views, _, _ := repo.PostStats(context.Background(), "p1")
+	if views != 4 {
+		t.Fatalf("expected flagged views to be excluded, got %d views", views)
+	}
+
+	srv := app.NewStatsServerForTest(repo, nil)
+	user := identity.WithCaller(context.Background(), identity.Caller{Service: "main-service", UserID: "u9", Role: "user"})
+	if _, err := srv.ListFlaggedEvents(user, &statspb.ListFlaggedEventsRequest{PostId: "p1"}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied for a plain user, got %v", err)
+	}
+	moderator := identity.WithCaller(context.Background(), identity.Caller{Service: "main-service", UserID: "mod", Role: "moderator"})
+	list, err := srv.ListFlaggedEvents(moderator, &statspb.ListFlaggedEventsRequest{PostId: "p1"})
+	if err != nil {
+		t.Fatalf("list flagged: %v", err)
+	}
+	if len(list.GetEvents()) != 2 || list.GetEvents()[0].GetReason() != "viewer_burst" || list.GetEvents()[0].GetIp() != "10.0.0.1" {
+		t.Fatalf("unexpected flagged events: %+v", list.GetEvents())
+	}
+
+	id := list.GetEvents()[0].GetId()
+	_, err = srv.ReviewFlaggedEvent(context.Background(), &statspb.ReviewFlaggedEventRequest{Id: id, Approve: true})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated without a reviewer, got %v", err)
+	}
+	_, err = srv.ReviewFlaggedEvent(user, &statspb.ReviewFlaggedEventRequest{Id: id, Approve: true})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied for a plain user, got %v", err)
+	}
+
+	resp, err := srv.ReviewFlaggedEvent(moderator, &statspb.ReviewFlaggedEventRequest{Id: id, Approve: true})
+	if err != nil {
+		t.Fatalf("review: %v", err)
+	}
+	if resp.GetEvent().GetStatus() != storage.FlagApproved || resp.GetEvent().GetReviewedBy() != "mod" {
+		t.Fatalf("unexpected review result: %+v", resp.GetEvent())
+	}
+	if views, _, _ := repo.PostStats(context.Background(), "p1"); views != 5 {
+		t.Fatalf("expected approved view to be counted, got %d views", views)
+	}
+
+	_, err = srv.ReviewFlaggedEvent(moderator, &statspb.ReviewFlaggedEventRequest{Id: id})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("expected FailedPrecondition for a second review, got %v", err)
+	}
+}
+
+func TestFraudDetectionFlagsRepeatedLikes(t *testing.T) {
+	repo := storage.NewMemory()
+	now := time.Now().UTC()
+	consumeWithFraudRules(t, repo, "post_likes", []map[string]any{
+		{"post_id": "p2", "event_type": "like", "user_id": "u1", "timestamp": now},
+		{"post_id": "p2", "event_type": "like", "user_id": "u1", "timestamp": now.Add(time.Second)},
+		{"post_id": "p3", "event_type": "like", "user_id": "u1", "timestamp": now.Add(2 * time.Second)},
+	})
+
+	if _, likes, _ := repo.PostStats(context.Background(), "p2"); likes != 1 {
+		t.Fatalf("expected one like for p2, got %d", likes)
+	}
+	flagged, _ := repo.FlaggedEvents(context.Background(), storage.FlagPending, "", 0)
+	if len(flagged) != 1 || flagged[0].Reason != "repeated_like" {
+		t.Fatalf("unexpected flagged events: %+v", flagged)
+	}
+}
+
+func TestFraudDetectionToleratesLateEvents(t *testing.T) {
+	repo := storage.NewMemory()
+	now := time.Now().UTC()
+
+	var events []map[string]any
+	for i := 100; i < 103; i++ {
+		events = append(events, map[string]any{"post_id": "p1", "ip": "10.0.0.1", "timestamp": now.Add(time.Duration(i) * time.Second)})
+	}
+	// A late event from another partition is outside the window of the
+	// newer ones and must not count against them.
+	events = append(events, map[string]any{"post_id": "p1", "ip": "10.0.0.1", "timestamp": now})
+	consumeWithFraudRules(t, repo, "post_views", events)
+
+	if views, _, _ := repo.PostStats(context.Background(), "p1"); views != 4 {
+		t.Fatalf("expected every view to be counted, got %d", views)
+	}
+	if flagged, _ := repo.FlaggedEvents(context.Background(), storage.FlagPending, "", 0); len(flagged) != 0 {
+		t.Fatalf("unexpected flagged events: %+v", flagged)
+	}
+}
+
+func TestReviewFlaggedEventApprovesOnce(t *testing.T) {
+	repo := storage.NewMemory()
+	flag := storage.FlaggedEvent{ID: "f1", Event: storage.Event{PostID: "p1", EventType: "view", Timestamp: time.Now().UTC()}}
+	if err := repo.FlagEvent(context.Background(), flag); err != nil {
+		t.Fatalf("flag: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.ReviewFlaggedEvent(context.Background(), "f1", true, "mod")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	approved := 0
+	for err := range errs {
+		switch err {
+		case nil:
+			approved++
+		case storage.ErrFlagReviewed:
+		default:
+			t.Fatalf("review: %v", err)
+		}
+	}
+	if approved != 1 {
+		t.Fatalf("expected one approval to win, got %d", approved)
+	}
+	if views, _, _ := repo.PostStats(context.Background(), "p1"); views != 1 {
+		t.Fatalf("expected the approved view once, got %d", views)
+	}
+}
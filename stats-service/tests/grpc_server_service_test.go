@@ -3,6 +3,7 @@ package tests
 import (
 	"context"
 	"testing"
+	"time"
 
 	"google.golang.org/grpc"
 	postspb "posts-service/proto"
@@ -18,6 +19,33 @@ func (likesRepoStub) PostStats(context.Context, string) (int64, int64, error) {
 func (likesRepoStub) TopPosts(context.Context, string, int) ([]storage.PostCount, error) {
 	return nil, nil
 }
+func (likesRepoStub) TrendingPosts(context.Context, time.Duration, time.Duration, float64, int) ([]storage.PostScore, error) {
+	return nil, nil
+}
+func (likesRepoStub) RelatedPosts(context.Context, string, int) ([]storage.PostScore, error) {
+	return nil, nil
+}
+func (likesRepoStub) AuthorStats(context.Context, []string, time.Time) (storage.AuthorStats, error) {
+	return storage.AuthorStats{}, nil
+}
+func (likesRepoStub) ExportEvents(context.Context, storage.ExportFilter, func(storage.ExportRow) error) error {
+	return nil
+}
+func (likesRepoStub) FlagEvent(context.Context, storage.FlaggedEvent) error { return nil }
+func (likesRepoStub) FlaggedEvents(context.Context, string, string, int) ([]storage.FlaggedEvent, error) {
+	return nil, nil
+}
+
+func (likesRepoStub) UserEvents(context.Context, string, func(storage.Event) error) error {
+	return nil
+}
+
+func (likesRepoStub) UserFlaggedEvents(context.Context, string) ([]storage.FlaggedEvent, error) {
+	return nil, nil
+}
+func (likesRepoStub) ReviewFlaggedEvent(context.Context, string, bool, string) (storage.FlaggedEvent, error) {
+	return storage.FlaggedEvent{}, storage.ErrFlagNotFound
+}
 func (likesRepoStub) LikesPerPost(context.Context) ([]storage.PostCount, error) {
 	return []storage.PostCount{{PostID: "p1", Value: 7}, {PostID: "p2", Value: 3}}, nil
 }
@@ -28,6 +56,10 @@ func (postsStub) GetPost(ctx context.Context, in *postspb.GetPostRequest, opts .
 	return &postspb.GetPostResponse{Post: &postspb.Post{OwnerId: "user" + in.GetId(), Id: in.GetId()}}, nil
 }
 
+func (postsStub) ListPosts(context.Context, *postspb.ListPostsRequest, ...grpc.CallOption) (*postspb.ListPostsResponse, error) {
+	return &postspb.ListPostsResponse{}, nil
+}
+
 func TestGetTopUsersByLikes(t *testing.T) {
 	srv := app.NewStatsServerForTest(likesRepoStub{}, postsStub{})
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"google.golang.org/grpc"
 	postspb "posts-service/proto"
@@ -15,6 +16,8 @@ import (
 type repoStub struct {
 	lastEventType string
 	topResp       []storage.PostCount
+	lastHalfLife  time.Duration
+	lastWindow    time.Duration
 }
 
 func (r *repoStub) SaveEvent(context.Context, storage.Event) error { return nil }
@@ -32,14 +35,66 @@ func (r *repoStub) TopPosts(ctx context.Context, eventType string, limit int) ([
 	return r.topResp, nil
 }
 
+func (r *repoStub) TrendingPosts(_ context.Context, window, halfLife time.Duration, _ float64, _ int) ([]storage.PostScore, error) {
+	r.lastWindow = window
+	r.lastHalfLife = halfLife
+	return []storage.PostScore{{PostID: "fresh", Score: 12.6}}, nil
+}
+
 func (r *repoStub) LikesPerPost(context.Context) ([]storage.PostCount, error) { return nil, nil }
 
+func (r *repoStub) RelatedPosts(_ context.Context, postID string, limit int) ([]storage.PostScore, error) {
+	if postID != "p1" {
+		return nil, nil
+	}
+	related := []storage.PostScore{{PostID: "p2", Score: 0.8}, {PostID: "p3", Score: 0.5}}
+	return related[:min(limit, len(related))], nil
+}
+
+func (r *repoStub) AuthorStats(_ context.Context, postIDs []string, since time.Time) (storage.AuthorStats, error) {
+	return storage.AuthorStats{
+		Posts: []storage.PostTotals{{PostID: "p1", Views: 10, Likes: 1}, {PostID: "p2", Views: 4, Likes: 3}},
+		Daily: []storage.DayTotals{{Day: since, Views: 2, Likes: 1}},
+		Reach: 6,
+	}, nil
+}
+
+func (r *repoStub) ExportEvents(context.Context, storage.ExportFilter, func(storage.ExportRow) error) error {
+	return nil
+}
+
+func (r *repoStub) FlagEvent(context.Context, storage.FlaggedEvent) error { return nil }
+
+func (r *repoStub) FlaggedEvents(context.Context, string, string, int) ([]storage.FlaggedEvent, error) {
+	return nil, nil
+}
+
+func (r *repoStub) UserEvents(context.Context, string, func(storage.Event) error) error {
+	return nil
+}
+
+func (r *repoStub) UserFlaggedEvents(context.Context, string) ([]storage.FlaggedEvent, error) {
+	return nil, nil
+}
+
+func (r *repoStub) ReviewFlaggedEvent(context.Context, string, bool, string) (storage.FlaggedEvent, error) {
+	return storage.FlaggedEvent{}, storage.ErrFlagNotFound
+}
+
 type postClientStub struct{}
 
 func (postClientStub) GetPost(context.Context, *postspb.GetPostRequest, ...grpc.CallOption) (*postspb.GetPostResponse, error) {
 	return &postspb.GetPostResponse{Post: &postspb.Post{OwnerId: "owner"}}, nil
 }
 
+func (postClientStub) ListPosts(_ context.Context, in *postspb.ListPostsRequest, _ ...grpc.CallOption) (*postspb.ListPostsResponse, error) {
+	if in.GetUserId() != "owner" {
+		return &postspb.ListPostsResponse{}, nil
+	}
+	posts := []*postspb.Post{{Id: "p1"}, {Id: "p2"}, {Id: "p3"}}
+	return &postspb.ListPostsResponse{Posts: posts, Total: int32(len(posts))}, nil
+}
+
 func TestGetPostStatsNilRequest(t *testing.T) {
 	srv := app.NewStatsServerForTest(&repoStub{}, nil)
 
@@ -67,3 +122,67 @@ func TestGetTopPostsDefaultsToViews(t *testing.T) {
 		t.Fatalf("unexpected response: %+v", resp)
 	}
 }
+
+func TestGetTopPostsTrending(t *testing.T) {
+	repo := &repoStub{}
+	srv := app.NewStatsServerForTest(repo, postClientStub{})
+
+	resp, err := srv.GetTopPosts(context.Background(), &statspb.TopPostsRequest{Metric: "trending", HalfLifeHours: 1.5})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.lastEventType != "" {
+		t.Fatalf("trending must not use all-time counters, got event type %q", repo.lastEventType)
+	}
+	if repo.lastHalfLife != 90*time.Minute {
+		t.Fatalf("expected half-life override of 90m, got %v", repo.lastHalfLife)
+	}
+	if repo.lastWindow <= 0 {
+		t.Fatalf("expected default window, got %v", repo.lastWindow)
+	}
+	items := resp.GetItems()
+	if len(items) != 1 || items[0].GetPostId() != "fresh" || items[0].GetValue() != 13 || items[0].GetScore() != 12.6 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestGetRelatedPosts(t *testing.T) {
+	srv := app.NewStatsServerForTest(&repoStub{}, nil)
+
+	resp, err := srv.GetRelatedPosts(context.Background(), &statspb.RelatedPostsRequest{PostId: "p1", Limit: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	items := resp.GetItems()
+	if len(items) != 2 || items[0].GetPostId() != "p2" || items[0].GetScore() != 0.8 {
+		t.Fatalf("unexpected related posts: %+v", items)
+	}
+
+	resp, err = srv.GetRelatedPosts(context.Background(), &statspb.RelatedPostsRequest{PostId: "p1", Limit: 10})
+	if err != nil || len(resp.GetItems()) != 2 {
+		t.Fatalf("expected every related post below the limit, got %+v, %v", resp.GetItems(), err)
+	}
+}
+
+func TestGetAuthorStats(t *testing.T) {
+	srv := app.NewStatsServerForTest(&repoStub{}, postClientStub{})
+
+	resp, err := srv.GetAuthorStats(context.Background(), &statspb.AuthorStatsRequest{UserId: "owner", Days: 7, Top: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.GetPostCount() != 3 || resp.GetTotalViews() != 14 || resp.GetTotalLikes() != 4 || resp.GetReach() != 6 {
+		t.Fatalf("unexpected totals: %+v", resp)
+	}
+	if len(resp.GetPosts()) != 3 || resp.GetPosts()[2].GetPostId() != "p3" || resp.GetPosts()[2].GetViews() != 0 {
+		t.Fatalf("expected every post in the breakdown, got %+v", resp.GetPosts())
+	}
+	best := resp.GetBestPosts()
+	if len(best) != 2 || best[0].GetPostId() != "p2" || best[1].GetPostId() != "p1" {
+		t.Fatalf("unexpected best posts: %+v", best)
+	}
+	daily := resp.GetDaily()
+	if len(daily) != 7 || daily[0].GetViews() != 2 || daily[6].GetViews() != 0 {
+		t.Fatalf("expected a zero-filled 7 day series, got %+v", daily)
+	}
+}
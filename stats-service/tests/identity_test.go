@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"common/identity"
+	"stats-service/internal/app"
+	statspb "stats-service/proto"
+)
+
+func dialStats(t *testing.T, opts ...grpc.DialOption) statspb.StatsServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	verifier := identity.NewVerifier("secret", "stats-service")
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(verifier.UnaryServerInterceptor()))
+	statspb.RegisterStatsServiceServer(srv, app.NewStatsServerForTest(&repoStub{}, postClientStub{}))
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	opts = append(opts,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+	)
+	conn, err := grpc.NewClient("passthrough:///bufnet", opts...)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return statspb.NewStatsServiceClient(conn)
+}
+
+func TestInternalIdentityRequired(t *testing.T) {
+	req := &statspb.PostStatsRequest{PostId: "p1"}
+
+	if _, err := dialStats(t).GetPostStats(context.Background(), req); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated without a token, got %v", err)
+	}
+
+	forged := identity.NewSigner("other", "main-service")
+	client := dialStats(t, grpc.WithUnaryInterceptor(forged.UnaryClientInterceptor("stats-service")))
+	if _, err := client.GetPostStats(context.Background(), req); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated with a wrong secret, got %v", err)
+	}
+
+	misrouted := identity.NewSigner("secret", "main-service")
+	client = dialStats(t, grpc.WithUnaryInterceptor(misrouted.UnaryClientInterceptor("posts-service")))
+	if _, err := client.GetPostStats(context.Background(), req); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated for another audience, got %v", err)
+	}
+}
+
+func TestInternalIdentityForwardsUser(t *testing.T) {
+	signer := identity.NewSigner("secret", "main-service")
+	client := dialStats(t, grpc.WithUnaryInterceptor(signer.UnaryClientInterceptor("stats-service")))
+
+	// The user_id field must not let a caller read another author's stats.
+	ctx := identity.WithCaller(context.Background(), identity.Caller{UserID: "owner"})
+	resp, err := client.GetAuthorStats(ctx, &statspb.AuthorStatsRequest{UserId: "someone-else"})
+	if err != nil {
+		t.Fatalf("author stats: %v", err)
+	}
+	if resp.GetPostCount() != 3 {
+		t.Fatalf("expected the stats of the authenticated user, got %d posts", resp.GetPostCount())
+	}
+}
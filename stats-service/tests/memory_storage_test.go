@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"stats-service/internal/storage"
+)
+
+func TestMemoryStorageQueries(t *testing.T) {
+	ctx := context.Background()
+	repo := storage.NewMemory()
+	now := time.Now().UTC()
+	for _, e := range []storage.Event{
+		{PostID: "p1", EventType: "view", UserID: "u1", Timestamp: now},
+		{PostID: "p1", EventType: "view", UserID: "u2", Timestamp: now},
+		{PostID: "p2", EventType: "view", UserID: "u1", Timestamp: now},
+		{PostID: "p1", EventType: "like", UserID: "u1", Timestamp: now},
+		{PostID: "p2", EventType: "like", UserID: "u1", Timestamp: now},
+		{PostID: "p2", EventType: "like", UserID: "u2", Timestamp: now},
+		{PostID: "p3", EventType: "like", UserID: "u2", Timestamp: now.Add(-48 * time.Hour)},
+	} {
+		if err := repo.SaveEvent(ctx, e); err != nil {
+			t.Fatalf("save event: %v", err)
+		}
+	}
+
+	views, likes, _ := repo.PostStats(ctx, "p1")
+	if views != 2 || likes != 1 {
+		t.Fatalf("unexpected post stats: %d views, %d likes", views, likes)
+	}
+
+	top, _ := repo.TopPosts(ctx, "view", 1)
+	if len(top) != 1 || top[0].PostID != "p1" || top[0].Value != 2 {
+		t.Fatalf("unexpected top posts: %+v", top)
+	}
+
+	perPost, _ := repo.LikesPerPost(ctx)
+	if len(perPost) != 3 || perPost[0].PostID != "p2" || perPost[0].Value != 2 {
+		t.Fatalf("unexpected likes per post: %+v", perPost)
+	}
+
+	trending, _ := repo.TrendingPosts(ctx, 24*time.Hour, 6*time.Hour, 3, 5)
+	if len(trending) != 2 || trending[0].PostID != "p2" {
+		t.Fatalf("unexpected trending posts: %+v", trending)
+	}
+
+	if err := repo.ComputeRelatedPosts(ctx, 72*time.Hour, 5); err != nil {
+		t.Fatalf("compute related: %v", err)
+	}
+	related, _ := repo.RelatedPosts(ctx, "p1", 5)
+	if len(related) != 1 || related[0].PostID != "p2" {
+		t.Fatalf("unexpected related posts: %+v", related)
+	}
+
+	author, _ := repo.AuthorStats(ctx, []string{"p1", "p3"}, now.Add(-24*time.Hour))
+	if len(author.Posts) != 2 || author.Reach != 2 || len(author.Daily) != 1 || author.Daily[0].Views != 2 {
+		t.Fatalf("unexpected author stats: %+v", author)
+	}
+
+	var rows []storage.ExportRow
+	err := repo.ExportEvents(ctx, storage.ExportFilter{
+		From:        now.Add(-time.Hour),
+		To:          now.Add(24 * time.Hour),
+		PostIDs:     []string{"p1"},
+		Granularity: storage.GranularityDay,
+	}, func(row storage.ExportRow) error {
+		rows = append(rows, row)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	if len(rows) != 2 || rows[0].EventType != "like" || rows[1].Count != 2 {
+		t.Fatalf("unexpected daily export: %+v", rows)
+	}
+
+	// A range ending mid-day still covers that day.
+	rows = nil
+	err = repo.ExportEvents(ctx, storage.ExportFilter{
+		From:        now.Add(-48 * time.Hour),
+		To:          now.Add(time.Nanosecond),
+		PostIDs:     []string{"p1"},
+		Granularity: storage.GranularityDay,
+	}, func(row storage.ExportRow) error {
+		rows = append(rows, row)
+		return nil
+	})
+	if err != nil || len(rows) != 2 {
+		t.Fatalf("expected the last day to be exported, got %+v (%v)", rows, err)
+	}
+}
+
+func TestFileStorageReloadsEvents(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+
+	repo, err := storage.OpenFile(path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	_ = repo.SaveEvent(ctx, storage.Event{PostID: "p1", EventType: "view", Timestamp: time.Now()})
+	_ = repo.SaveEvent(ctx, storage.Event{PostID: "p1", EventType: "like", UserID: "u1", Timestamp: time.Now()})
+	_ = repo.SaveEvent(ctx, storage.Event{PostID: "p2", EventType: "view", Timestamp: time.Now()})
+	_ = repo.MarkPostDeleted(ctx, "p2", time.Now())
+	if err := repo.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	repo, err = storage.OpenFile(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer repo.Close()
+	views, likes, _ := repo.PostStats(ctx, "p1")
+	if views != 1 || likes != 1 {
+		t.Fatalf("expected persisted events, got %d views and %d likes", views, likes)
+	}
+	if ids, _ := repo.LivePostIDs(ctx); len(ids) != 1 || ids[0] != "p1" {
+		t.Fatalf("expected the deletion of p2 to persist, got live posts %v", ids)
+	}
+}
@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"stats-service/internal/storage"
+)
+
+func TestMigrationsAreSequential(t *testing.T) {
+	all, err := storage.Migrations()
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if len(all) == 0 {
+		t.Fatal("expected embedded migrations")
+	}
+	for i, m := range all {
+		if m.Version != i+1 {
+			t.Fatalf("expected version %d, got %d (%s)", i+1, m.Version, m.Name)
+		}
+		if len(m.Up) == 0 || len(m.Down) == 0 {
+			t.Fatalf("migration %d must have up and down statements", m.Version)
+		}
+	}
+}
+
+func TestPlanUpAndDown(t *testing.T) {
+	all := []storage.Migration{{Version: 1}, {Version: 2}, {Version: 3}}
+
+	up := storage.PlanUp(all, []int{1}, 0)
+	if len(up) != 2 || up[0].Version != 2 || up[1].Version != 3 {
+		t.Fatalf("unexpected up plan: %+v", up)
+	}
+
+	up = storage.PlanUp(all, []int{1}, 2)
+	if len(up) != 1 || up[0].Version != 2 {
+		t.Fatalf("unexpected targeted up plan: %+v", up)
+	}
+
+	down := storage.PlanDown(all, []int{1, 2, 3}, 2)
+	if len(down) != 2 || down[0].Version != 3 || down[1].Version != 2 {
+		t.Fatalf("unexpected down plan: %+v", down)
+	}
+}
+
+func TestRollupBackfillSplitsAtCutoff(t *testing.T) {
+	all, err := storage.Migrations()
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	cutoff := time.Date(2026, 3, 1, 12, 30, 15, 0, time.UTC)
+	literal := "toDateTime('2026-03-01 12:30:15', 'UTC')"
+
+	for _, m := range all[1:3] {
+		var view, backfill string
+		for _, stmt := range storage.RenderStatements(m.Up, "stats", cutoff) {
+			if strings.Contains(stmt, "${") {
+				t.Fatalf("%s: unrendered placeholder in %q", m.Name, stmt)
+			}
+			switch {
+			case strings.HasPrefix(stmt, "CREATE MATERIALIZED VIEW"):
+				view = stmt
+			case strings.HasPrefix(stmt, "INSERT INTO"):
+				backfill = stmt
+			}
+		}
+		if !strings.Contains(view, "ts >= "+literal) {
+			t.Fatalf("%s: expected the view to count events from the cutoff on:\n%s", m.Name, view)
+		}
+		if !strings.Contains(backfill, "ts < "+literal) {
+			t.Fatalf("%s: expected the backfill to stop at the cutoff:\n%s", m.Name, backfill)
+		}
+	}
+}
+
+func TestSplitStatementsKeepsLiterals(t *testing.T) {
+	stmts := storage.SplitStatements(`-- leading comment
+INSERT INTO t VALUES ('a;b', 'it''s; fine', 'esc\'; still'); -- trailing
+SELECT "col;name", ` + "`x;y`" + ` FROM t;
+`)
+	if len(stmts) != 2 {
+		t.Fatalf("expected 2 statements, got %d: %q", len(stmts), stmts)
+	}
+	if stmts[0] != `INSERT INTO t VALUES ('a;b', 'it''s; fine', 'esc\'; still')` {
+		t.Fatalf("unexpected first statement %q", stmts[0])
+	}
+	if !strings.HasPrefix(stmts[1], `SELECT "col;name"`) {
+		t.Fatalf("unexpected second statement %q", stmts[1])
+	}
+}
+
+func TestDestructiveDownMigrations(t *testing.T) {
+	all, err := storage.Migrations()
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	byName := make(map[string]storage.Migration)
+	for _, m := range all {
+		byName[m.Name] = m
+	}
+	if byName["create_events"].Destructive == "" {
+		t.Fatal("expected dropping the raw events to be marked destructive")
+	}
+	if byName["related_posts"].Destructive != "" {
+		t.Fatal("expected recomputed recommendations to be safe to drop")
+	}
+}
+
+func TestTableTTL(t *testing.T) {
+	cases := map[string]string{
+		"MergeTree ORDER BY (event_type, post_id, ts) TTL ts + toIntervalDay(90) SETTINGS index_granularity = 8192": "ts + toIntervalDay(90)",
+		"SummingMergeTree(cnt) ORDER BY (event_type, post_id, hour) TTL hour + toIntervalDay(365)":                  "hour + toIntervalDay(365)",
+		"MergeTree ORDER BY (event_type, post_id, ts) SETTINGS index_granularity = 8192":                            "",
+		"MergeTree ORDER BY (`TTL`, ts) SETTINGS index_granularity = 8192":                                          "",
+		"MergeTree ORDER BY ts TTL ts + toIntervalDay(7), ts + toIntervalDay(1) TO VOLUME 'cold SETTINGS x'":        "ts + toIntervalDay(7), ts + toIntervalDay(1) TO VOLUME 'cold SETTINGS x'",
+	}
+	for engineFull, want := range cases {
+		if got := storage.TableTTL(engineFull); got != want {
+			t.Fatalf("TableTTL(%q) = %q, want %q", engineFull, got, want)
+		}
+	}
+}
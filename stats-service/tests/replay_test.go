@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"stats-service/internal/app"
+	"stats-service/internal/storage"
+)
+
+// replayReader serves messages by offset, as a partition reader does.
+type replayReader struct {
+	messages []kafka.Message
+	next     int64
+}
+
+func (r *replayReader) SetOffset(offset int64) error {
+	r.next = offset
+	return nil
+}
+
+func (r *replayReader) ReadMessage(context.Context) (kafka.Message, error) {
+	if r.next >= int64(len(r.messages)) {
+		return kafka.Message{}, io.EOF
+	}
+	msg := r.messages[r.next]
+	r.next++
+	return msg, nil
+}
+
+func (r *replayReader) Close() error { return nil }
+
+type replayStore struct {
+	tables map[string][]storage.Event
+}
+
+func (s *replayStore) SaveEventsTo(_ context.Context, table string, events []storage.Event) error {
+	s.tables[table] = append(s.tables[table], events...)
+	return nil
+}
+
+func TestReplaySkipsDeletedPostsAndUsers(t *testing.T) {
+	now := time.Now().UTC()
+	reader := &replayReader{}
+	for i, e := range []map[string]any{
+		{"post_id": "p1", "user_id": "u1", "timestamp": now},
+		{"post_id": "p1", "user_id": "gone", "timestamp": now},
+		{"post_id": "deleted", "user_id": "u1", "timestamp": now},
+		{"post_id": "p2", "timestamp": now},
+	} {
+		value, _ := json.Marshal(e)
+		reader.messages = append(reader.messages, kafka.Message{Offset: int64(i), Value: value})
+	}
+	store := &replayStore{tables: make(map[string][]storage.Event)}
+
+	err := app.ReplayPartitionForTest(context.Background(), store, reader, "post_views", "view", 0, int64(len(reader.messages)),
+		map[string]struct{}{"deleted": {}}, map[string]struct{}{"gone": {}})
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+
+	got := store.tables["events"+storage.RebuildSuffix]
+	if len(got) != 2 || got[0].PostID != "p1" || got[0].UserID != "u1" || got[1].PostID != "p2" || got[1].EventType != "view" {
+		t.Fatalf("expected only the events of live posts and users, got %+v", got)
+	}
+}
@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"stats-service/internal/app"
+	"stats-service/internal/storage"
+	statspb "stats-service/proto"
+)
+
+type watchStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent chan *statspb.PostStatsResponse
+}
+
+func (s *watchStream) Context() context.Context { return s.ctx }
+
+func (s *watchStream) Send(resp *statspb.PostStatsResponse) error {
+	s.sent <- resp
+	return nil
+}
+
+func TestWatchPostStatsCoalescesUpdates(t *testing.T) {
+	repo := storage.NewMemory()
+	_ = repo.SaveEvent(context.Background(), storage.Event{PostID: "p1", EventType: "view"})
+
+	srv, publish := app.NewWatchableStatsServerForTest(repo, nil, 20*time.Millisecond, time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &watchStream{ctx: ctx, sent: make(chan *statspb.PostStatsResponse, 10)}
+	done := make(chan error, 1)
+	go func() { done <- srv.WatchPostStats(&statspb.PostStatsRequest{PostId: "p1"}, stream) }()
+
+	first := <-stream.sent
+	if first.GetViews() != 1 || first.GetLikes() != 0 {
+		t.Fatalf("unexpected initial snapshot: %+v", first)
+	}
+
+	for i := 0; i < 3; i++ {
+		_ = repo.SaveEvent(context.Background(), storage.Event{PostID: "p1", EventType: "like"})
+		publish("p1")
+	}
+
+	select {
+	case update := <-stream.sent:
+		if update.GetLikes() != 3 {
+			t.Fatalf("expected coalesced update with 3 likes, got %+v", update)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no update received")
+	}
+
+	select {
+	case extra := <-stream.sent:
+		t.Fatalf("expected a single coalesced update, got extra %+v", extra)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("watch returned error: %v", err)
+	}
+}
+
+func TestWatchPostStatsPollsForOtherReplicas(t *testing.T) {
+	repo := storage.NewMemory()
+	srv, _ := app.NewWatchableStatsServerForTest(repo, nil, 10*time.Millisecond, 30*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream := &watchStream{ctx: ctx, sent: make(chan *statspb.PostStatsResponse, 10)}
+	go func() { _ = srv.WatchPostStats(&statspb.PostStatsRequest{PostId: "p1"}, stream) }()
+	<-stream.sent
+
+	// Stored by another replica: no signal reaches this hub.
+	_ = repo.SaveEvent(context.Background(), storage.Event{PostID: "p1", EventType: "view"})
+
+	select {
+	case update := <-stream.sent:
+		if update.GetViews() != 1 {
+			t.Fatalf("unexpected update: %+v", update)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the watcher to poll for the new view")
+	}
+}